@@ -43,6 +43,8 @@ Allowed values are 'group' and 'kind'. Dictates the packaging of go kinds, where
 	generateCmd.Flags().Lookup("postprocess").NoOptDefVal = "true"
 	generateCmd.Flags().Bool("noschemasinmanifest", false, "Whether to exclude kind schemas from the generated app manifest. This flag exists to allow for codegen with recursive types in CUE until github.com/grafana/grafana-app-sdk/issues/460 is resolved.")
 	generateCmd.Flags().Lookup("noschemasinmanifest").NoOptDefVal = "true"
+	generateCmd.Flags().Bool("genclients", false, "Whether to generate a typed client (<Kind>Client) for each kind version. Generated client types may conflict with existing types of the same name in the generated packages.")
+	generateCmd.Flags().Lookup("genclients").NoOptDefVal = "true"
 
 	// Don't show "usage" information when an error is returned form the command,
 	// because our errors are not command-usage-based
@@ -105,6 +107,10 @@ func generateCmdFunc(cmd *cobra.Command, _ []string) error {
 	if err != nil {
 		return err
 	}
+	genClients, err := cmd.Flags().GetBool("genclients")
+	if err != nil {
+		return err
+	}
 
 	var files codejen.Files
 	switch format {
@@ -117,6 +123,7 @@ func generateCmdFunc(cmd *cobra.Command, _ []string) error {
 			GroupKinds:             grouping == kindGroupingGroup,
 			ManifestIncludeSchemas: !noSchemasInManifest,
 			GenOperatorState:       genOperatorState,
+			GenClients:             genClients,
 		}, selector)
 		if err != nil {
 			return err
@@ -165,6 +172,7 @@ type kindGenConfig struct {
 	GroupKinds             bool
 	ManifestIncludeSchemas bool
 	GenOperatorState       bool
+	GenClients             bool
 }
 
 //nolint:funlen,goconst
@@ -184,7 +192,7 @@ func generateKindsCue(modFS fs.FS, cfg kindGenConfig, selectors ...string) (code
 		return nil, err
 	}
 	// Resource
-	resourceFiles, err := generatorForKinds.Generate(cuekind.ResourceGenerator(cfg.GroupKinds), selectors...)
+	resourceFiles, err := generatorForKinds.Generate(cuekind.ResourceGenerator(cfg.GroupKinds, cfg.GenClients), selectors...)
 	if err != nil {
 		return nil, err
 	}
@@ -23,7 +23,9 @@ func CRDGenerator(outputEncoder jennies.CRDOutputEncoder, outputExtension string
 // If `groupKinds` is true, kinds within the same group will exist in the same package.
 // When combined with `versioned`, each version package will contain all kinds in the group
 // which have a schema for that version.
-func ResourceGenerator(groupKinds bool) *codejen.JennyList[codegen.Kind] {
+// If `generateClients` is true, a typed client is also generated for each version of each kind (see jennies.ClientGenerator).
+// Clients are opt-in, as their `<Kind>Client` types may conflict with existing types in the generated packages.
+func ResourceGenerator(groupKinds bool, generateClients bool) *codejen.JennyList[codegen.Kind] {
 	g := codejen.JennyListWithNamer(namerFunc)
 	g.Append(
 		&jennies.GoTypes{
@@ -46,6 +48,11 @@ func ResourceGenerator(groupKinds bool) *codejen.JennyList[codegen.Kind] {
 			GroupByKind: !groupKinds,
 		},
 	)
+	if generateClients {
+		g.Append(&jennies.ClientGenerator{
+			GroupByKind: !groupKinds,
+		})
+	}
 	return g
 }
 
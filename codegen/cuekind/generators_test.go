@@ -61,7 +61,7 @@ func TestResourceGenerator(t *testing.T) {
 	require.Nil(t, err)
 
 	t.Run("group by kind", func(t *testing.T) {
-		files, err := ResourceGenerator(false).Generate(kinds...)
+		files, err := ResourceGenerator(false, false).Generate(kinds...)
 		require.Nil(t, err)
 		// Check number of files generated
 		// 14 (7 -> object, spec, metadata, status, schema, codec, constants) * 2 versions
@@ -71,7 +71,7 @@ func TestResourceGenerator(t *testing.T) {
 	})
 
 	t.Run("group by group", func(t *testing.T) {
-		files, err := ResourceGenerator(true).Generate(kinds...)
+		files, err := ResourceGenerator(true, false).Generate(kinds...)
 		require.Nil(t, err)
 		// Check number of files generated
 		// 14 (7 -> object, spec, metadata, status, schema, codec, constants) * 2 versions
@@ -81,13 +81,32 @@ func TestResourceGenerator(t *testing.T) {
 	})
 
 	t.Run("group by group, multiple kinds", func(t *testing.T) {
-		files, err := ResourceGenerator(true).Generate(sameGroupKinds...)
+		files, err := ResourceGenerator(true, false).Generate(sameGroupKinds...)
 		require.Nil(t, err)
 		// Check number of files generated
 		assert.Len(t, files, 20, "should be 20 files generated, got %d", len(files))
 		// Check content against the golden files
 		compareToGolden(t, files, "go/groupbygroup")
 	})
+
+	t.Run("group by kind, with clients", func(t *testing.T) {
+		files, err := ResourceGenerator(false, true).Generate(kinds...)
+		require.Nil(t, err)
+		// Check number of files generated
+		// 16 (8 -> object, spec, metadata, status, schema, codec, constants, client) * 2 versions
+		assert.Len(t, files, 16, "should be 16 files generated, got %d", len(files))
+		// Check content against the golden files
+		compareToGolden(t, files, "go/groupbykind")
+	})
+
+	t.Run("group by group, multiple kinds, with clients", func(t *testing.T) {
+		files, err := ResourceGenerator(true, true).Generate(sameGroupKinds...)
+		require.Nil(t, err)
+		// Check number of files generated
+		assert.Len(t, files, 23, "should be 23 files generated, got %d", len(files))
+		// Check content against the golden files
+		compareToGolden(t, files, "go/groupbygroup")
+	})
 }
 
 func TestTypeScriptResourceGenerator(t *testing.T) {
@@ -0,0 +1,74 @@
+package jennies
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path/filepath"
+
+	"cuelang.org/go/cue"
+	"github.com/grafana/codejen"
+
+	"github.com/grafana/grafana-app-sdk/codegen"
+	"github.com/grafana/grafana-app-sdk/codegen/templates"
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+// ClientGenerator is a Jenny which creates a typed client for each version of a kind,
+// built on resource.TypedClient
+type ClientGenerator struct {
+	// GroupByKind determines whether kinds are grouped by GroupVersionKind or just GroupVersion.
+	// If GroupByKind is true, generated paths are <kind>/<version>/<file>, instead of the default <version>/<file>.
+	// When GroupByKind is false, the Kind() function and subresource types (such as status) are assumed to be
+	// prefixed with the kind name, as they are by SchemaGenerator and ResourceObjectGenerator.
+	GroupByKind bool
+}
+
+func (*ClientGenerator) JennyName() string {
+	return "ClientGenerator"
+}
+
+// Generate creates one client go file for each version of the provided Kind
+func (c *ClientGenerator) Generate(kind codegen.Kind) (codejen.Files, error) {
+	meta := kind.Properties()
+
+	if meta.Scope != string(resource.NamespacedScope) && meta.Scope != string(resource.ClusterScope) {
+		return nil, fmt.Errorf("scope '%s' is invalid, must be one of: '%s', '%s'",
+			meta.Scope, resource.ClusterScope, resource.NamespacedScope)
+	}
+
+	prefix := ""
+	if !c.GroupByKind {
+		prefix = exportField(kind.Name())
+	}
+
+	files := make(codejen.Files, 0)
+	for _, ver := range kind.Versions() {
+		statusTypeName := ""
+		if ver.Schema.LookupPath(cue.MakePath(cue.Str("status"))).Exists() {
+			statusTypeName = prefix + "Status"
+		}
+		b := bytes.Buffer{}
+		err := templates.WriteClient(templates.ClientMetadata{
+			Package:        ToPackageName(ver.Version),
+			Kind:           meta.Kind,
+			Scope:          meta.Scope,
+			StatusTypeName: statusTypeName,
+			FuncPrefix:     prefix,
+		}, &b)
+		if err != nil {
+			return nil, err
+		}
+		formatted, err := format.Source(b.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, codejen.File{
+			Data:         formatted,
+			RelativePath: filepath.Join(GetGeneratedPath(c.GroupByKind, kind, ver.Version), fmt.Sprintf("%s_client_gen.go", meta.MachineName)),
+			From:         []codejen.NamedJenny{c},
+		})
+	}
+
+	return files, nil
+}
@@ -27,6 +27,7 @@ var (
 	templateWrappedType, _    = template.ParseFS(templates, "wrappedtype.tmpl")
 	templateTSType, _         = template.ParseFS(templates, "tstype.tmpl")
 	templateConstants, _      = template.ParseFS(templates, "constants.tmpl")
+	templateClient, _         = template.ParseFS(templates, "client.tmpl")
 
 	templateBackendPluginRouter, _          = template.ParseFS(templates, "plugin/plugin.tmpl")
 	templateBackendPluginResourceHandler, _ = template.ParseFS(templates, "plugin/handler_resource.tmpl")
@@ -158,6 +159,21 @@ func WriteCodec(metadata SchemaMetadata, out io.Writer) error {
 	return templateCodec.Execute(out, metadata)
 }
 
+// ClientMetadata is the metadata required by the Resource Client template
+type ClientMetadata struct {
+	Package string
+	Kind    string
+	Scope   string
+	// StatusTypeName is the go type of the kind's status subresource. If empty, no UpdateStatus method is generated.
+	StatusTypeName string
+	FuncPrefix     string
+}
+
+// WriteClient executes the Resource Client template, and writes out the generated go code to out
+func WriteClient(metadata ClientMetadata, out io.Writer) error {
+	return templateClient.Execute(out, metadata)
+}
+
 // WriteThemaCodec executes the Thema-specific Codec template, and writes out the generated go code to out
 func WriteThemaCodec(metadata ResourceObjectTemplateMetadata, out io.Writer) error {
 	return templateThemaCodec.Execute(out, metadata)
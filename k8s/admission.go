@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	"github.com/grafana/grafana-app-sdk/resource"
 )
 
@@ -16,11 +18,14 @@ const (
 	errStringNoAdmissionControllerDefined = "no %s admission controller defined for group '%s' and kind '%s'"
 )
 
-// SimpleAdmissionError implements resource.AdmissionError
+// SimpleAdmissionError implements resource.AdmissionError and resource.DetailedAdmissionError
 type SimpleAdmissionError struct {
 	error
-	statusCode int
-	reason     string
+	statusCode       int
+	reason           string
+	causes           []metav1.StatusCause
+	warnings         []string
+	auditAnnotations map[string]string
 }
 
 // StatusCode returns the error's HTTP status code
@@ -33,6 +38,52 @@ func (s *SimpleAdmissionError) Reason() string {
 	return s.reason
 }
 
+// Causes returns the field-level causes of the error
+func (s *SimpleAdmissionError) Causes() []metav1.StatusCause {
+	return s.causes
+}
+
+// Warnings returns the warnings to return to the user alongside the error
+func (s *SimpleAdmissionError) Warnings() []string {
+	return s.warnings
+}
+
+// AuditAnnotations returns the annotations to add to the audit event for the request
+func (s *SimpleAdmissionError) AuditAnnotations() map[string]string {
+	return s.auditAnnotations
+}
+
+// WithCauses appends the provided field-level causes to the error, and returns the error
+func (s *SimpleAdmissionError) WithCauses(causes ...metav1.StatusCause) *SimpleAdmissionError {
+	s.causes = append(s.causes, causes...)
+	return s
+}
+
+// WithWarnings appends the provided warnings to the error, and returns the error
+func (s *SimpleAdmissionError) WithWarnings(warnings ...string) *SimpleAdmissionError {
+	s.warnings = append(s.warnings, warnings...)
+	return s
+}
+
+// WithAuditAnnotation adds an audit annotation to the error, and returns the error
+func (s *SimpleAdmissionError) WithAuditAnnotation(key, value string) *SimpleAdmissionError {
+	if s.auditAnnotations == nil {
+		s.auditAnnotations = make(map[string]string)
+	}
+	s.auditAnnotations[key] = value
+	return s
+}
+
+// NewFieldCause returns a metav1.StatusCause for the provided field path, cause type, and message,
+// for use with SimpleAdmissionError.WithCauses
+func NewFieldCause(field string, causeType metav1.CauseType, message string) metav1.StatusCause {
+	return metav1.StatusCause{
+		Type:    causeType,
+		Message: message,
+		Field:   field,
+	}
+}
+
 // NewAdmissionError returns a new SimpleAdmissionError, which implements resource.AdmissionError
 func NewAdmissionError(err error, statusCode int, reason string) *SimpleAdmissionError {
 	return &SimpleAdmissionError{
@@ -64,10 +115,11 @@ func (o *OpinionatedMutatingAdmissionController) Mutate(ctx context.Context, req
 			return resp, err
 		}
 	}
-	if resp == nil || resp.UpdatedObject == nil {
-		resp = &resource.MutatingResponse{
-			UpdatedObject: request.Object,
-		}
+	if resp == nil {
+		resp = &resource.MutatingResponse{}
+	}
+	if resp.UpdatedObject == nil {
+		resp.UpdatedObject = request.Object
 	}
 
 	// Get the CommonMetadata, so we can update it
@@ -101,6 +153,9 @@ func NewOpinionatedMutatingAdmissionController(wrap resource.MutatingAdmissionCo
 	}
 }
 
+// Compile-time interface compliance check
+var _ resource.DetailedAdmissionError = &SimpleAdmissionError{}
+
 // Compile-time interface compliance check
 var _ resource.MutatingAdmissionController = &OpinionatedMutatingAdmissionController{}
 
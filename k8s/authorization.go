@@ -0,0 +1,244 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	authorization "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	authorizationv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+// AccessCheck is an action on a kind to check authorization for.
+type AccessCheck struct {
+	// Kind is the kind the action is performed on
+	Kind resource.Schema
+	// Verb is the kubernetes API verb of the action, such as "get", "list", "create", "update", "patch", "delete", or "watch"
+	Verb string
+	// Identifier is the namespace and name of the resource the action is performed on.
+	// For actions on all resources in a namespace (such as list), leave the Name empty,
+	// and for cluster-wide actions leave both Namespace and Name empty.
+	Identifier resource.Identifier
+	// Subresource is the subresource the action is performed on, if any
+	Subresource string
+}
+
+// AccessDecision is the result of an access review.
+type AccessDecision struct {
+	// Allowed is true if the action is allowed
+	Allowed bool
+	// Denied is true if the action is explicitly denied. An action can be neither Allowed nor Denied,
+	// if no authorizer has an opinion on it, in which case it should be treated as not allowed.
+	Denied bool
+	// Reason is the (optional) reason given by the authorizer for the decision
+	Reason string
+}
+
+// AccessReviewerConfig is the configuration for an AccessReviewer
+type AccessReviewerConfig struct {
+	// CacheTTL is how long the decision for an access review is cached.
+	// If CacheTTL is <= 0, decisions are not cached, and each check makes a request to the API server.
+	CacheTTL time.Duration
+	// ImpersonationProvider, if non-nil, is used to impersonate an identity from the context of each request,
+	// in the same way as ClientConfig.ImpersonationProvider. This allows CanI to check the access of the impersonated identity.
+	ImpersonationProvider func(ctx context.Context) (Impersonation, bool)
+	// RequireImpersonation, if true, makes CanI return ErrNoImpersonation when ImpersonationProvider is set
+	// but returns no identity for the request, in the same way as ClientConfig.RequireImpersonation.
+	RequireImpersonation bool
+}
+
+// DefaultAccessReviewerConfig returns an AccessReviewerConfig with a short-lived decision cache
+func DefaultAccessReviewerConfig() AccessReviewerConfig {
+	return AccessReviewerConfig{
+		CacheTTL: 10 * time.Second,
+	}
+}
+
+type cachedAccessDecision struct {
+	decision AccessDecision
+	expires  time.Time
+}
+
+// AccessReviewer checks whether users may perform actions on kinds by issuing SubjectAccessReview
+// and SelfSubjectAccessReview requests to the API server. Decisions are cached for AccessReviewerConfig.CacheTTL.
+// It can be used to check whether a user is allowed to perform an action before an app performs it on their behalf.
+type AccessReviewer struct {
+	client      authorizationv1.AuthorizationV1Interface
+	config      AccessReviewerConfig
+	cache       map[string]cachedAccessDecision
+	mux         sync.RWMutex
+	nextCleanup time.Time
+}
+
+// NewAccessReviewer returns a new AccessReviewer which makes requests to the API server using the provided rest.Config.
+// The identity of the rest.Config must be allowed to create SubjectAccessReviews to use Can.
+func NewAccessReviewer(kubeConfig rest.Config, config AccessReviewerConfig) (*AccessReviewer, error) {
+	kubeConfig.ContentType = runtime.ContentTypeJSON
+	if config.ImpersonationProvider != nil {
+		kubeConfig.Wrap(newImpersonatingRoundTripper(config.ImpersonationProvider, false))
+	}
+	client, err := authorizationv1.NewForConfig(&kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating authorization client: %w", err)
+	}
+	return &AccessReviewer{
+		client: client,
+		config: config,
+		cache:  make(map[string]cachedAccessDecision),
+	}, nil
+}
+
+// Can returns whether the user is allowed to perform the action described by check, using a SubjectAccessReview.
+func (a *AccessReviewer) Can(ctx context.Context, user Impersonation, check AccessCheck) (AccessDecision, error) {
+	if user.UserName == "" && len(user.Groups) == 0 {
+		return AccessDecision{}, fmt.Errorf("user must have a UserName or Groups")
+	}
+	key := accessKey(check, false, &user)
+	return a.cached(key, func() (AccessDecision, error) {
+		extra := make(map[string]authorization.ExtraValue, len(user.Extra))
+		for k, v := range user.Extra {
+			extra[k] = v
+		}
+		resp, err := a.client.SubjectAccessReviews().Create(ctx, &authorization.SubjectAccessReview{
+			Spec: authorization.SubjectAccessReviewSpec{
+				ResourceAttributes: resourceAttributes(check),
+				User:               user.UserName,
+				UID:                user.UID,
+				Groups:             user.Groups,
+				Extra:              extra,
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return AccessDecision{}, parseKubernetesError(nil, 0, err)
+		}
+		return accessDecision(resp.Status), nil
+	})
+}
+
+// CanI returns whether the identity used by the AccessReviewer (or the identity impersonated for ctx,
+// if AccessReviewerConfig.ImpersonationProvider is set) is allowed to perform the action described by check,
+// using a SelfSubjectAccessReview.
+func (a *AccessReviewer) CanI(ctx context.Context, check AccessCheck) (AccessDecision, error) {
+	var user *Impersonation
+	if a.config.ImpersonationProvider != nil {
+		if imp, ok := a.config.ImpersonationProvider(ctx); ok && imp.UserName != "" {
+			user = &imp
+		} else if a.config.RequireImpersonation {
+			return AccessDecision{}, ErrNoImpersonation
+		}
+	}
+	key := accessKey(check, true, user)
+	return a.cached(key, func() (AccessDecision, error) {
+		resp, err := a.client.SelfSubjectAccessReviews().Create(ctx, &authorization.SelfSubjectAccessReview{
+			Spec: authorization.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: resourceAttributes(check),
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return AccessDecision{}, parseKubernetesError(nil, 0, err)
+		}
+		return accessDecision(resp.Status), nil
+	})
+}
+
+// cached returns the cached decision for key, or calls review and caches its decision if there is none
+func (a *AccessReviewer) cached(key string, review func() (AccessDecision, error)) (AccessDecision, error) {
+	if a.config.CacheTTL <= 0 {
+		return review()
+	}
+	a.mux.RLock()
+	cached, ok := a.cache[key]
+	a.mux.RUnlock()
+	if ok && now().Before(cached.expires) {
+		return cached.decision, nil
+	}
+	decision, err := review()
+	if err != nil {
+		return decision, err
+	}
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	current := now()
+	// Periodically remove expired decisions, so that the cache doesn't grow with each distinct check
+	if current.After(a.nextCleanup) {
+		for k, v := range a.cache {
+			if !current.Before(v.expires) {
+				delete(a.cache, k)
+			}
+		}
+		a.nextCleanup = current.Add(a.config.CacheTTL)
+	}
+	a.cache[key] = cachedAccessDecision{
+		decision: decision,
+		expires:  current.Add(a.config.CacheTTL),
+	}
+	return decision, nil
+}
+
+func resourceAttributes(check AccessCheck) *authorization.ResourceAttributes {
+	return &authorization.ResourceAttributes{
+		Namespace:   check.Identifier.Namespace,
+		Verb:        check.Verb,
+		Group:       check.Kind.Group(),
+		Version:     check.Kind.Version(),
+		Resource:    check.Kind.Plural(),
+		Subresource: check.Subresource,
+		Name:        check.Identifier.Name,
+	}
+}
+
+func accessDecision(status authorization.SubjectAccessReviewStatus) AccessDecision {
+	return AccessDecision{
+		Allowed: status.Allowed,
+		Denied:  status.Denied,
+		Reason:  status.Reason,
+	}
+}
+
+// accessCacheKey identifies a cached access decision. It is JSON-encoded to build the cache key,
+// so that no value of one field can be confused with the values of other fields.
+type accessCacheKey struct {
+	Group       string         `json:"group"`
+	Version     string         `json:"version"`
+	Resource    string         `json:"resource"`
+	Subresource string         `json:"subresource"`
+	Verb        string         `json:"verb"`
+	Namespace   string         `json:"namespace"`
+	Name        string         `json:"name"`
+	Self        bool           `json:"self"`
+	User        *Impersonation `json:"user,omitempty"`
+}
+
+// accessKey returns the cache key for check, performed by user (or the AccessReviewer's own identity if self is true).
+// user may be nil if self is true.
+func accessKey(check AccessCheck, self bool, user *Impersonation) string {
+	key := accessCacheKey{
+		Group:       check.Kind.Group(),
+		Version:     check.Kind.Version(),
+		Resource:    check.Kind.Plural(),
+		Subresource: check.Subresource,
+		Verb:        check.Verb,
+		Namespace:   check.Identifier.Namespace,
+		Name:        check.Identifier.Name,
+		Self:        self,
+	}
+	if user != nil {
+		// Groups are sorted so the same user has the same key regardless of group order.
+		// Extra is a map, which encoding/json always encodes in sorted key order.
+		normalized := *user
+		normalized.Groups = slices.Clone(user.Groups)
+		slices.Sort(normalized.Groups)
+		key.User = &normalized
+	}
+	// Marshaling can't fail, as the key only contains strings, bools, slices and maps of strings
+	b, _ := json.Marshal(key)
+	return string(b)
+}
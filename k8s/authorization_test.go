@@ -0,0 +1,172 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorization "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+func TestAccessReviewer(t *testing.T) {
+	current := time.Now()
+	now = func() time.Time {
+		return current
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	mux := sync.Mutex{}
+	requests := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		requests = append(requests, r.URL.Path)
+		mux.Unlock()
+		writer.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/apis/authorization.k8s.io/v1/subjectaccessreviews":
+			review := authorization.SubjectAccessReview{}
+			require.Nil(t, json.NewDecoder(r.Body).Decode(&review))
+			attrs := review.Spec.ResourceAttributes
+			require.NotNil(t, attrs)
+			assert.Equal(t, testKind.Group(), attrs.Group)
+			assert.Equal(t, testKind.Version(), attrs.Version)
+			assert.Equal(t, testKind.Plural(), attrs.Resource)
+			assert.Equal(t, "ns", attrs.Namespace)
+			assert.Equal(t, "foo", attrs.Name)
+			assert.Equal(t, []string{"editors"}, review.Spec.Groups)
+			review.Status.Allowed = review.Spec.User == "allowed" && attrs.Verb == "get"
+			review.Status.Reason = "test"
+			json.NewEncoder(writer).Encode(review)
+		case "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews":
+			review := authorization.SelfSubjectAccessReview{}
+			require.Nil(t, json.NewDecoder(r.Body).Decode(&review))
+			review.Status.Denied = review.Spec.ResourceAttributes.Verb == "delete"
+			review.Status.Allowed = r.Header.Get("Impersonate-User") == "allowed"
+			json.NewEncoder(writer).Encode(review)
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	reviewer, err := NewAccessReviewer(rest.Config{Host: server.URL}, AccessReviewerConfig{
+		CacheTTL:              time.Second,
+		ImpersonationProvider: ImpersonationFromContext,
+	})
+	require.Nil(t, err)
+	ctx := context.Background()
+	check := AccessCheck{Kind: testKind, Verb: "get", Identifier: resource.Identifier{Namespace: "ns", Name: "foo"}}
+
+	t.Run("subject access review", func(t *testing.T) {
+		decision, err := reviewer.Can(ctx, Impersonation{UserName: "allowed", Groups: []string{"editors"}}, check)
+		require.Nil(t, err)
+		assert.Equal(t, AccessDecision{Allowed: true, Reason: "test"}, decision)
+		decision, err = reviewer.Can(ctx, Impersonation{UserName: "other", Groups: []string{"editors"}}, check)
+		require.Nil(t, err)
+		assert.False(t, decision.Allowed)
+		update := check
+		update.Verb = "update"
+		decision, err = reviewer.Can(ctx, Impersonation{UserName: "allowed", Groups: []string{"editors"}}, update)
+		require.Nil(t, err)
+		assert.False(t, decision.Allowed)
+		assert.Len(t, requests, 3)
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		decision, err := reviewer.Can(ctx, Impersonation{UserName: "allowed", Groups: []string{"editors"}}, check)
+		require.Nil(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Len(t, requests, 3)
+		current = current.Add(2 * time.Second)
+		decision, err = reviewer.Can(ctx, Impersonation{UserName: "allowed", Groups: []string{"editors"}}, check)
+		require.Nil(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Len(t, requests, 4)
+	})
+
+	t.Run("self subject access review", func(t *testing.T) {
+		decision, err := reviewer.CanI(ctx, AccessCheck{Kind: testKind, Verb: "delete"})
+		require.Nil(t, err)
+		assert.True(t, decision.Denied)
+		assert.False(t, decision.Allowed)
+		// Impersonated identities are cached separately
+		decision, err = reviewer.CanI(WithImpersonation(ctx, Impersonation{UserName: "allowed"}), AccessCheck{Kind: testKind, Verb: "get"})
+		require.Nil(t, err)
+		assert.True(t, decision.Allowed)
+		decision, err = reviewer.CanI(ctx, AccessCheck{Kind: testKind, Verb: "get"})
+		require.Nil(t, err)
+		assert.False(t, decision.Allowed)
+	})
+
+	t.Run("no user", func(t *testing.T) {
+		_, err := reviewer.Can(ctx, Impersonation{}, check)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("impersonation required", func(t *testing.T) {
+		required, err := NewAccessReviewer(rest.Config{Host: server.URL}, AccessReviewerConfig{
+			ImpersonationProvider: ImpersonationFromContext,
+			RequireImpersonation:  true,
+		})
+		require.Nil(t, err)
+		count := len(requests)
+		_, err = required.CanI(ctx, AccessCheck{Kind: testKind, Verb: "get"})
+		assert.ErrorIs(t, err, ErrNoImpersonation)
+		assert.Len(t, requests, count)
+		decision, err := required.CanI(WithImpersonation(ctx, Impersonation{UserName: "allowed"}), AccessCheck{Kind: testKind, Verb: "get"})
+		require.Nil(t, err)
+		assert.True(t, decision.Allowed)
+		// Reviews of other users are made with the reviewer's own identity
+		decision, err = required.Can(ctx, Impersonation{UserName: "allowed", Groups: []string{"editors"}}, check)
+		require.Nil(t, err)
+		assert.True(t, decision.Allowed)
+	})
+}
+
+func TestAccessReviewer_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		writer.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	reviewer, err := NewAccessReviewer(rest.Config{Host: server.URL}, DefaultAccessReviewerConfig())
+	require.Nil(t, err)
+	_, err = reviewer.CanI(context.Background(), AccessCheck{Kind: testKind, Verb: "get"})
+	require.NotNil(t, err)
+	assert.True(t, IsForbidden(err))
+}
+
+func TestAccessKey(t *testing.T) {
+	check := AccessCheck{Kind: testKind, Verb: "get", Identifier: resource.Identifier{Namespace: "ns", Name: "foo"}}
+
+	// Values containing separators don't collide with other fields
+	assert.NotEqual(t,
+		accessKey(check, false, &Impersonation{UserName: "a|b", Groups: []string{"c"}}),
+		accessKey(check, false, &Impersonation{UserName: "a", UID: "b", Groups: []string{"c"}}))
+	assert.NotEqual(t,
+		accessKey(check, false, &Impersonation{UserName: "a", Groups: []string{"b,c"}}),
+		accessKey(check, false, &Impersonation{UserName: "a", Groups: []string{"b", "c"}}))
+	assert.NotEqual(t,
+		accessKey(check, false, &Impersonation{UserName: "a", Extra: map[string][]string{"k": {"v;l=w"}}}),
+		accessKey(check, false, &Impersonation{UserName: "a", Extra: map[string][]string{"k": {"v"}, "l": {"w"}}}))
+	withSlash := check
+	withSlash.Identifier.Namespace = "ns/foo"
+	withSlash.Identifier.Name = ""
+	assert.NotEqual(t, accessKey(check, true, nil), accessKey(withSlash, true, nil))
+	assert.NotEqual(t, accessKey(check, true, nil), accessKey(check, false, &Impersonation{}))
+
+	// Group order doesn't matter
+	assert.Equal(t,
+		accessKey(check, false, &Impersonation{UserName: "a", Groups: []string{"b", "c"}}),
+		accessKey(check, false, &Impersonation{UserName: "a", Groups: []string{"c", "b"}}))
+}
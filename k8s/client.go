@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"iter"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/rest"
 
 	"github.com/grafana/grafana-app-sdk/metrics"
@@ -49,6 +51,22 @@ type ClientConfig struct {
 	// It is passed the Kind and existing rest.Config, and should return a valid rest.Config
 	// (returning the same rest.Config as the input is valid)
 	KubeConfigProvider func(kind resource.Kind, kubeConfig rest.Config) rest.Config
+
+	// RateLimitConfig configures client-side rate limiting for requests made by clients generated by a ClientRegistry.
+	// If nil, the QPS and Burst (or RateLimiter) of the rest.Config are used for each client.
+	// Use WithRequestPriority to mark requests (such as those made by reconcilers) as lower priority.
+	RateLimitConfig *RateLimitConfig
+
+	// ImpersonationProvider, if non-nil, is called with the context of each request made by clients generated by a ClientRegistry.
+	// If it returns true, the request impersonates the returned identity, rather than using the identity of the rest.Config.
+	// Use ImpersonationFromContext to impersonate the identity set in the context with WithImpersonation,
+	// or provide a function which gets the identity from other request context (such as the calling user of a plugin request).
+	// The identity of the rest.Config must have permission to impersonate users.
+	ImpersonationProvider func(ctx context.Context) (Impersonation, bool)
+	// RequireImpersonation, if true, makes requests fail with ErrNoImpersonation when ImpersonationProvider is set
+	// but returns no identity (or an identity with an empty UserName) for the request, rather than making the request
+	// with the identity of the rest.Config.
+	RequireImpersonation bool
 }
 
 // DefaultClientConfig returns a ClientConfig using defaults that assume you have used the SDK codegen tooling
@@ -80,6 +98,37 @@ func (c *Client) List(ctx context.Context, namespace string, options resource.Li
 	return &into, err
 }
 
+// ListIter returns an iterator over all resources in the provided namespace, requesting pages of options.Limit resources
+// (or resource.DefaultListIteratorPageSize if options.Limit is <= 0) as the iteration progresses.
+// If an error is encountered, it is yielded with a nil resource.Object, and iteration stops.
+// See resource.ListIterator for details on consistency between pages.
+func (c *Client) ListIter(ctx context.Context, namespace string, options resource.ListOptions) iter.Seq2[resource.Object, error] {
+	return resource.ListIterator(ctx, c, namespace, options)
+}
+
+// ListMetadata lists resources in the provided namespace, but only requests their metadata from the API server
+// (as metav1.PartialObjectMetadata). The items in the returned list are objects of the client's kind with only their
+// metadata set; all other components (such as spec and status) are zero values.
+// This reduces the memory and network cost of listing large objects when only metadata (such as labels or finalizers) is needed.
+func (c *Client) ListMetadata(ctx context.Context, namespace string, options resource.ListOptions) (
+	resource.ListObject, error) {
+	if c.schema.Scope() == resource.ClusterScope && namespace != resource.NamespaceAll {
+		return nil, fmt.Errorf("cannot list resources with schema scope \"%s\" in namespace \"%s\", must be NamespaceAll (\"%s\")",
+			resource.ClusterScope, namespace, resource.NamespaceAll)
+	}
+	codec := c.metadataCodec()
+	into := resource.UntypedList{}
+	err := c.client.listMetadata(ctx, namespace, c.schema.Plural(), &into, options, func(raw []byte) (resource.Object, error) {
+		into := c.schema.ZeroValue()
+		err := codec.Read(bytes.NewReader(raw), into)
+		return into, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &into, nil
+}
+
 // ListInto lists resources in the provided namespace, and unmarshals the response into the provided resource.ListObject
 func (c *Client) ListInto(ctx context.Context, namespace string, options resource.ListOptions,
 	into resource.ListObject) error {
@@ -239,6 +288,29 @@ func (c *Client) Watch(ctx context.Context, namespace string, options resource.W
 	return c.client.watch(ctx, namespace, c.schema.Plural(), c.schema.ZeroValue(), options, c.codec)
 }
 
+// WatchMetadata makes a watch request for the namespace which only receives the metadata of resources from the API server
+// (as metav1.PartialObjectMetadata). The objects in watch events are objects of the client's kind with only their
+// metadata set; all other components (such as spec and status) are zero values. See ListMetadata.
+func (c *Client) WatchMetadata(ctx context.Context, namespace string, options resource.WatchOptions) (
+	resource.WatchResponse, error) {
+	if c.schema.Scope() == resource.ClusterScope && namespace != resource.NamespaceAll {
+		return nil, fmt.Errorf("cannot watch resources with schema scope \"%s\" in namespace \"%s\", must be NamespaceAll (\"%s\")",
+			resource.ClusterScope, namespace, resource.NamespaceAll)
+	}
+	return c.client.watchMetadata(ctx, namespace, c.schema.Plural(), c.schema.ZeroValue(), options, c.metadataCodec())
+}
+
+func (c *Client) metadataCodec() resource.Codec {
+	return &partialObjectMetadataCodec{
+		codec: c.codec,
+		gvk: schema.GroupVersionKind{
+			Group:   c.schema.Group(),
+			Version: c.schema.Version(),
+			Kind:    c.schema.Kind(),
+		},
+	}
+}
+
 // Metrics returns the prometheus collectors used by this Client for registration with a prometheus exporter
 func (c *Client) PrometheusCollectors() []prometheus.Collector {
 	return c.client.metrics()
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/rest"
 
@@ -20,10 +21,19 @@ func NewClientRegistry(kubeCconfig rest.Config, clientConfig ClientConfig) *Clie
 	kubeCconfig.NegotiatedSerializer = &GenericNegotiatedSerializer{}
 	kubeCconfig.UserAgent = rest.DefaultKubernetesUserAgent()
 
+	var globalLimiter, lowPriorityLimiter *rate.Limiter
+	if clientConfig.RateLimitConfig != nil {
+		globalLimiter = newRateLimiter(clientConfig.RateLimitConfig.Global)
+		lowPriorityLimiter = newRateLimiter(clientConfig.RateLimitConfig.LowPriority)
+	}
+
 	return &ClientRegistry{
-		clients:      make(map[schema.GroupVersionKind]rest.Interface),
-		cfg:          kubeCconfig,
-		clientConfig: clientConfig,
+		clients:            make(map[schema.GroupVersionKind]rest.Interface),
+		cfg:                kubeCconfig,
+		clientConfig:       clientConfig,
+		globalLimiter:      globalLimiter,
+		lowPriorityLimiter: lowPriorityLimiter,
+		kindLimiters:       make(map[schema.GroupKind]*rate.Limiter),
 		requestDurations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Namespace:                       clientConfig.MetricsConfig.Namespace,
 			Subsystem:                       "kubernetes_client",
@@ -40,6 +50,22 @@ func NewClientRegistry(kubeCconfig rest.Config, clientConfig ClientConfig) *Clie
 			Namespace: clientConfig.MetricsConfig.Namespace,
 			Help:      "Total number of kubernetes requests",
 		}, []string{"status_code", "verb", "kind", "subresource"}),
+		rateLimiterDurations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:                       clientConfig.MetricsConfig.Namespace,
+			Subsystem:                       "kubernetes_client",
+			Name:                            "rate_limiter_duration_seconds",
+			Help:                            "Time (in seconds) requests spent waiting on the client-side rate limiter.",
+			Buckets:                         metrics.LatencyBuckets,
+			NativeHistogramBucketFactor:     clientConfig.MetricsConfig.NativeHistogramBucketFactor,
+			NativeHistogramMaxBucketNumber:  clientConfig.MetricsConfig.NativeHistogramMaxBucketNumber,
+			NativeHistogramMinResetDuration: time.Hour,
+		}, []string{"kind", "priority"}),
+		throttledRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "throttled_requests_total",
+			Subsystem: "kubernetes_client",
+			Namespace: clientConfig.MetricsConfig.Namespace,
+			Help:      "Total number of kubernetes requests delayed by the client-side rate limiter",
+		}, []string{"kind", "priority"}),
 	}
 }
 
@@ -52,6 +78,12 @@ type ClientRegistry struct {
 	mutex            sync.Mutex
 	requestDurations *prometheus.HistogramVec
 	totalRequests    *prometheus.CounterVec
+	// Rate limiting, used when clientConfig.RateLimitConfig is non-nil
+	globalLimiter        *rate.Limiter
+	lowPriorityLimiter   *rate.Limiter
+	kindLimiters         map[schema.GroupKind]*rate.Limiter
+	rateLimiterDurations *prometheus.HistogramVec
+	throttledRequests    *prometheus.CounterVec
 }
 
 // ClientFor returns a Client with the underlying rest.Interface being a cached one for the Schema's GroupVersion.
@@ -82,7 +114,7 @@ func (c *ClientRegistry) ClientFor(sch resource.Kind) (resource.Client, error) {
 // PrometheusCollectors returns the prometheus metric collectors used by all clients generated by this ClientRegistry to allow for registration
 func (c *ClientRegistry) PrometheusCollectors() []prometheus.Collector {
 	return []prometheus.Collector{
-		c.totalRequests, c.requestDurations,
+		c.totalRequests, c.requestDurations, c.rateLimiterDurations, c.throttledRequests,
 	}
 }
 
@@ -109,6 +141,12 @@ func (c *ClientRegistry) getClient(sch resource.Kind) (rest.Interface, error) {
 	} else {
 		ccfg.NegotiatedSerializer = &GenericNegotiatedSerializer{}
 	}
+	if c.clientConfig.RateLimitConfig != nil {
+		ccfg.RateLimiter = c.rateLimiterFor(sch)
+	}
+	if c.clientConfig.ImpersonationProvider != nil {
+		ccfg.Wrap(newImpersonatingRoundTripper(c.clientConfig.ImpersonationProvider, c.clientConfig.RequireImpersonation))
+	}
 	if c.clientConfig.KubeConfigProvider != nil {
 		ccfg = c.clientConfig.KubeConfigProvider(sch, ccfg)
 	}
@@ -119,3 +157,34 @@ func (c *ClientRegistry) getClient(sch resource.Kind) (rest.Interface, error) {
 	c.clients[gvk] = client
 	return client, nil
 }
+
+// rateLimiterFor returns a flowcontrol.RateLimiter for the kind which applies the global limit, the kind's limit,
+// and the low priority limit (for low priority requests) from the RateLimitConfig.
+// It must be called while holding c.mutex.
+func (c *ClientRegistry) rateLimiterFor(sch resource.Kind) *clientRateLimiter {
+	limiters := make([]*rate.Limiter, 0, 2)
+	if c.globalLimiter != nil {
+		limiters = append(limiters, c.globalLimiter)
+	}
+	gk := schema.GroupKind{
+		Group: sch.Group(),
+		Kind:  sch.Kind(),
+	}
+	kindLimiter, ok := c.kindLimiters[gk]
+	if !ok {
+		if limit, ok := c.clientConfig.RateLimitConfig.Kinds[gk]; ok {
+			kindLimiter = newRateLimiter(limit)
+		}
+		c.kindLimiters[gk] = kindLimiter
+	}
+	if kindLimiter != nil {
+		limiters = append(limiters, kindLimiter)
+	}
+	return &clientRateLimiter{
+		kind:         sch.Plural(),
+		limiters:     limiters,
+		lowPriority:  c.lowPriorityLimiter,
+		waitDuration: c.rateLimiterDurations,
+		throttled:    c.throttledRequests,
+	}
+}
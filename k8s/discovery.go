@@ -0,0 +1,267 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+var _ resource.ClientGenerator = &DiscoveryClientRegistry{}
+
+// KindNotFoundError is returned by DiscoveryClientRegistry when a kind, or a specific version of a kind,
+// is not served by the API server.
+type KindNotFoundError struct {
+	GroupKind schema.GroupKind
+	// Version is the requested version of the kind, if a specific version was requested
+	Version string
+	// ServedVersions are the versions of the kind which are served by the API server, if any
+	ServedVersions []string
+}
+
+func (e *KindNotFoundError) Error() string {
+	if e.Version == "" || len(e.ServedVersions) == 0 {
+		return fmt.Sprintf("kind '%s' is not served by the API server", e.GroupKind)
+	}
+	return fmt.Sprintf("version '%s' of kind '%s' is not served by the API server (served versions: %s)",
+		e.Version, e.GroupKind, strings.Join(e.ServedVersions, ", "))
+}
+
+// IsKindNotFound returns true if the error is, or wraps, a KindNotFoundError
+func IsKindNotFound(err error) bool {
+	cast := &KindNotFoundError{}
+	return errors.As(err, &cast)
+}
+
+// serverGroupsAndResourcesGetter is the subset of discovery.DiscoveryInterface used by DiscoveryClientRegistry
+type serverGroupsAndResourcesGetter interface {
+	ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error)
+}
+
+// discoveredKind is a kind as reported by the API server's discovery endpoints
+type discoveredKind struct {
+	preferredVersion string
+	// versions are the served versions, in the server's order of preference
+	versions  []string
+	resources map[string]metav1.APIResource
+}
+
+// DiscoveryClientRegistry is a resource.ClientGenerator which uses the API server's discovery information
+// to check that kinds are served before returning clients for them, and to resolve the preferred version of a kind.
+// This is useful for apps which interoperate with kinds from other apps, which may not be installed,
+// or may be served at a different version than the one the app was built with.
+//
+// Discovery information is cached, and refreshed on the interval provided to NewDiscoveryClientRegistry.
+// Clients are made by an underlying ClientRegistry, so they share its cache, metrics, and rate limiting.
+type DiscoveryClientRegistry struct {
+	registry       *ClientRegistry
+	discovery      serverGroupsAndResourcesGetter
+	kinds          map[schema.GroupKind]discoveredKind
+	mux            sync.RWMutex
+	lastUpdate     time.Time
+	updateInterval time.Duration
+	group          singleflight.Group
+}
+
+// NewDiscoveryClientRegistry returns a new DiscoveryClientRegistry using the provided rest.Config and ClientConfig for
+// its clients and discovery client, and cacheUpdateInterval as the interval to refresh its discovery cache from the API server.
+// To disable the cache refresh (and only update on first request and whenever ForceRefresh() is called),
+// set this value to <= 0.
+func NewDiscoveryClientRegistry(kubeConfig rest.Config, clientConfig ClientConfig, cacheUpdateInterval time.Duration) (
+	*DiscoveryClientRegistry, error) {
+	disc, err := discovery.NewDiscoveryClientForConfig(&kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating discovery client: %w", err)
+	}
+	return &DiscoveryClientRegistry{
+		registry:       NewClientRegistry(kubeConfig, clientConfig),
+		discovery:      disc,
+		updateInterval: cacheUpdateInterval,
+	}, nil
+}
+
+// ClientFor returns a Client for the provided kind at the kind's version.
+// If the API server does not serve that version of the kind, it returns a KindNotFoundError.
+func (d *DiscoveryClientRegistry) ClientFor(kind resource.Kind) (resource.Client, error) {
+	disc, err := d.getKind(schema.GroupKind{Group: kind.Group(), Kind: kind.Kind()})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := disc.resources[kind.Version()]; !ok {
+		return nil, &KindNotFoundError{
+			GroupKind:      schema.GroupKind{Group: kind.Group(), Kind: kind.Kind()},
+			Version:        kind.Version(),
+			ServedVersions: disc.versions,
+		}
+	}
+	return d.registry.ClientFor(kind)
+}
+
+// ClientForGroupKind returns a Client for the provided GroupKind at the API server's preferred version of the kind.
+// If one of the provided kinds has the preferred version (and the same group and kind), that kind is used for the client.
+// Otherwise, the client uses resource.UntypedObject and resource.UntypedList, with the plural and scope
+// reported by the API server.
+// If the API server does not serve the kind, it returns a KindNotFoundError.
+func (d *DiscoveryClientRegistry) ClientForGroupKind(groupKind schema.GroupKind, kinds ...resource.Kind) (resource.Client, error) {
+	kind, err := d.PreferredKind(groupKind, kinds...)
+	if err != nil {
+		return nil, err
+	}
+	return d.registry.ClientFor(kind)
+}
+
+// PreferredKind returns the resource.Kind for the API server's preferred version of the provided GroupKind.
+// If one of the provided kinds has the preferred version (and the same group and kind), it is returned.
+// Otherwise, an untyped resource.Kind is built from the discovery information. See ClientForGroupKind.
+func (d *DiscoveryClientRegistry) PreferredKind(groupKind schema.GroupKind, kinds ...resource.Kind) (resource.Kind, error) {
+	disc, err := d.getKind(groupKind)
+	if err != nil {
+		return resource.Kind{}, err
+	}
+	for _, kind := range kinds {
+		if kind.Group() == groupKind.Group && kind.Kind() == groupKind.Kind && kind.Version() == disc.preferredVersion {
+			return kind, nil
+		}
+	}
+	res := disc.resources[disc.preferredVersion]
+	scope := resource.ClusterScope
+	if res.Namespaced {
+		scope = resource.NamespacedScope
+	}
+	return resource.Kind{
+		Schema: resource.NewSimpleSchema(groupKind.Group, disc.preferredVersion, &resource.UntypedObject{}, &resource.UntypedList{},
+			resource.WithKind(groupKind.Kind), resource.WithPlural(res.Name), resource.WithScope(scope)),
+		Codecs: map[resource.KindEncoding]resource.Codec{
+			resource.KindEncodingJSON: resource.NewJSONCodec(),
+		},
+	}, nil
+}
+
+// PreferredVersion returns the API server's preferred version of the provided GroupKind.
+// If the API server does not serve the kind, it returns a KindNotFoundError.
+func (d *DiscoveryClientRegistry) PreferredVersion(groupKind schema.GroupKind) (string, error) {
+	disc, err := d.getKind(groupKind)
+	if err != nil {
+		return "", err
+	}
+	return disc.preferredVersion, nil
+}
+
+// ServedVersions returns all versions of the provided GroupKind served by the API server,
+// in the server's order of preference.
+// If the API server does not serve the kind, it returns a KindNotFoundError.
+func (d *DiscoveryClientRegistry) ServedVersions(groupKind schema.GroupKind) ([]string, error) {
+	disc, err := d.getKind(groupKind)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(disc.versions), nil
+}
+
+// ForceRefresh forces an update of the cached discovery information
+func (d *DiscoveryClientRegistry) ForceRefresh() error {
+	return d.updateKinds()
+}
+
+// PrometheusCollectors returns the prometheus metric collectors used by all clients generated by this DiscoveryClientRegistry
+func (d *DiscoveryClientRegistry) PrometheusCollectors() []prometheus.Collector {
+	return d.registry.PrometheusCollectors()
+}
+
+func (d *DiscoveryClientRegistry) getKind(groupKind schema.GroupKind) (discoveredKind, error) {
+	_, err, _ := d.group.Do("check-cache-update", func() (any, error) {
+		d.mux.RLock()
+		stale := d.kinds == nil || (d.updateInterval > 0 && d.lastUpdate.Before(now().Add(-d.updateInterval)))
+		d.mux.RUnlock()
+		if stale {
+			if err := d.updateKinds(); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return discoveredKind{}, err
+	}
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+
+	disc, ok := d.kinds[groupKind]
+	if !ok {
+		return discoveredKind{}, &KindNotFoundError{GroupKind: groupKind}
+	}
+	return disc, nil
+}
+
+func (d *DiscoveryClientRegistry) updateKinds() error {
+	groups, resourceLists, err := d.discovery.ServerGroupsAndResources()
+	// A partial failure still returns the groups which could be discovered, so use those
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return fmt.Errorf("error getting server groups and resources: %w", err)
+	}
+	resources := make(map[schema.GroupVersion][]metav1.APIResource)
+	for _, list := range resourceLists {
+		if list == nil {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		resources[gv] = list.APIResources
+	}
+	kinds := make(map[schema.GroupKind]discoveredKind)
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		// The group's preferred version takes precedence, followed by the remaining versions in the server's order
+		versions := make([]string, 0, len(group.Versions))
+		if group.PreferredVersion.Version != "" {
+			versions = append(versions, group.PreferredVersion.Version)
+		}
+		for _, v := range group.Versions {
+			if v.Version != group.PreferredVersion.Version {
+				versions = append(versions, v.Version)
+			}
+		}
+		for _, version := range versions {
+			for _, res := range resources[schema.GroupVersion{Group: group.Name, Version: version}] {
+				// Skip subresources
+				if strings.Contains(res.Name, "/") {
+					continue
+				}
+				gk := schema.GroupKind{Group: group.Name, Kind: res.Kind}
+				kind, ok := kinds[gk]
+				if !ok {
+					kind = discoveredKind{
+						preferredVersion: version,
+						resources:        make(map[string]metav1.APIResource),
+					}
+				}
+				if _, ok := kind.resources[version]; ok {
+					continue
+				}
+				kind.versions = append(kind.versions, version)
+				kind.resources[version] = res
+				kinds[gk] = kind
+			}
+		}
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.kinds = kinds
+	d.lastUpdate = now()
+	return nil
+}
@@ -0,0 +1,172 @@
+package k8s
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+func TestDiscoveryClientRegistry(t *testing.T) {
+	disc := &mockDiscovery{
+		groups: []*metav1.APIGroup{{
+			Name: "foo.grafana.app",
+			Versions: []metav1.GroupVersionForDiscovery{
+				{GroupVersion: "foo.grafana.app/v1", Version: "v1"},
+				{GroupVersion: "foo.grafana.app/v2", Version: "v2"},
+				{GroupVersion: "foo.grafana.app/v3alpha1", Version: "v3alpha1"},
+			},
+			PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "foo.grafana.app/v2", Version: "v2"},
+		}},
+		resources: []*metav1.APIResourceList{{
+			GroupVersion: "foo.grafana.app/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "foos", Kind: "Foo", Namespaced: true},
+				{Name: "bars", Kind: "Bar", Namespaced: false},
+			},
+		}, {
+			GroupVersion: "foo.grafana.app/v2",
+			APIResources: []metav1.APIResource{
+				{Name: "foos", Kind: "Foo", Namespaced: true},
+				{Name: "foos/status", Kind: "Foo", Namespaced: true},
+			},
+		}, {
+			GroupVersion: "foo.grafana.app/v3alpha1",
+			APIResources: []metav1.APIResource{
+				{Name: "foos", Kind: "Foo", Namespaced: true},
+			},
+		}},
+	}
+	registry := &DiscoveryClientRegistry{
+		registry:  NewClientRegistry(rest.Config{Host: "http://localhost"}, ClientConfig{}),
+		discovery: disc,
+	}
+	fooKind := schema.GroupKind{Group: "foo.grafana.app", Kind: "Foo"}
+	newKind := func(version, kind string) resource.Kind {
+		return resource.Kind{
+			Schema: resource.NewSimpleSchema("foo.grafana.app", version, &resource.TypedSpecObject[string]{}, &resource.TypedList[*resource.TypedSpecObject[string]]{}, resource.WithKind(kind)),
+			Codecs: map[resource.KindEncoding]resource.Codec{resource.KindEncodingJSON: resource.NewJSONCodec()},
+		}
+	}
+
+	t.Run("versions", func(t *testing.T) {
+		version, err := registry.PreferredVersion(fooKind)
+		require.Nil(t, err)
+		assert.Equal(t, "v2", version)
+		versions, err := registry.ServedVersions(fooKind)
+		require.Nil(t, err)
+		assert.Equal(t, []string{"v2", "v1", "v3alpha1"}, versions)
+		// Bar is not served at the group's preferred version, so its preferred version is the first one which serves it
+		version, err = registry.PreferredVersion(schema.GroupKind{Group: "foo.grafana.app", Kind: "Bar"})
+		require.Nil(t, err)
+		assert.Equal(t, "v1", version)
+	})
+
+	t.Run("ClientFor", func(t *testing.T) {
+		client, err := registry.ClientFor(newKind("v1", "Foo"))
+		require.Nil(t, err)
+		assert.Equal(t, "v1", client.(*Client).schema.Version())
+
+		_, err = registry.ClientFor(newKind("v4", "Foo"))
+		require.NotNil(t, err)
+		assert.True(t, IsKindNotFound(err))
+		assert.Equal(t, "version 'v4' of kind 'Foo.foo.grafana.app' is not served by the API server (served versions: v2, v1, v3alpha1)", err.Error())
+
+		_, err = registry.ClientFor(newKind("v1", "Baz"))
+		require.NotNil(t, err)
+		assert.True(t, IsKindNotFound(err))
+		assert.Equal(t, "kind 'Baz.foo.grafana.app' is not served by the API server", err.Error())
+	})
+
+	t.Run("ClientForGroupKind typed", func(t *testing.T) {
+		v2 := newKind("v2", "Foo")
+		client, err := registry.ClientForGroupKind(fooKind, newKind("v1", "Foo"), v2)
+		require.Nil(t, err)
+		assert.Equal(t, v2, client.(*Client).schema)
+	})
+
+	t.Run("ClientForGroupKind untyped", func(t *testing.T) {
+		client, err := registry.ClientForGroupKind(schema.GroupKind{Group: "foo.grafana.app", Kind: "Bar"}, newKind("v2", "Foo"))
+		require.Nil(t, err)
+		sch := client.(*Client).schema
+		assert.Equal(t, "v1", sch.Version())
+		assert.Equal(t, "bars", sch.Plural())
+		assert.Equal(t, resource.ClusterScope, sch.Scope())
+		_, ok := sch.ZeroValue().(*resource.UntypedObject)
+		assert.True(t, ok)
+	})
+
+	t.Run("ClientForGroupKind not found", func(t *testing.T) {
+		_, err := registry.ClientForGroupKind(schema.GroupKind{Group: "bar.grafana.app", Kind: "Foo"})
+		assert.True(t, IsKindNotFound(err))
+	})
+
+	t.Run("discovery error", func(t *testing.T) {
+		cerr := fmt.Errorf("I AM ERROR")
+		r := &DiscoveryClientRegistry{discovery: &mockDiscovery{err: cerr}}
+		_, err := r.PreferredVersion(fooKind)
+		assert.ErrorIs(t, err, cerr)
+	})
+}
+
+func TestDiscoveryClientRegistry_Refresh(t *testing.T) {
+	current := time.Now()
+	now = func() time.Time {
+		return current
+	}
+	defer func() {
+		now = time.Now
+	}()
+	disc := &mockDiscovery{}
+	registry := &DiscoveryClientRegistry{
+		discovery:      disc,
+		updateInterval: time.Minute,
+	}
+	gk := schema.GroupKind{Group: "foo.grafana.app", Kind: "Foo"}
+
+	_, err := registry.PreferredVersion(gk)
+	assert.True(t, IsKindNotFound(err))
+	assert.Equal(t, 1, disc.calls)
+
+	// The kind is installed, but the cache is not stale yet
+	disc.groups = []*metav1.APIGroup{{
+		Name:             "foo.grafana.app",
+		Versions:         []metav1.GroupVersionForDiscovery{{GroupVersion: "foo.grafana.app/v1", Version: "v1"}},
+		PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "foo.grafana.app/v1", Version: "v1"},
+	}}
+	disc.resources = []*metav1.APIResourceList{{
+		GroupVersion: "foo.grafana.app/v1",
+		APIResources: []metav1.APIResource{{Name: "foos", Kind: "Foo", Namespaced: true}},
+	}}
+	_, err = registry.PreferredVersion(gk)
+	assert.True(t, IsKindNotFound(err))
+	assert.Equal(t, 1, disc.calls)
+
+	current = current.Add(2 * time.Minute)
+	version, err := registry.PreferredVersion(gk)
+	require.Nil(t, err)
+	assert.Equal(t, "v1", version)
+	assert.Equal(t, 2, disc.calls)
+
+	require.Nil(t, registry.ForceRefresh())
+	assert.Equal(t, 3, disc.calls)
+}
+
+type mockDiscovery struct {
+	groups    []*metav1.APIGroup
+	resources []*metav1.APIResourceList
+	err       error
+	calls     int
+}
+
+func (m *mockDiscovery) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	m.calls++
+	return m.groups, m.resources, m.err
+}
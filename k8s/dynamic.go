@@ -77,13 +77,13 @@ func (d *DynamicPatcher) Patch(ctx context.Context, groupKind schema.GroupKind,
 	if preferred.Namespaced {
 		resp, err := res.Namespace(identifier.Namespace).Patch(ctx, identifier.Name, types.JSONPatchType, data, metav1.PatchOptions{})
 		if err != nil {
-			return nil, err
+			return nil, parseKubernetesError(nil, 0, err)
 		}
 		return resource.NewUnstructuredWrapper(resp), nil
 	}
 	resp, err := res.Patch(ctx, identifier.Name, types.JSONPatchType, data, metav1.PatchOptions{})
 	if err != nil {
-		return nil, err
+		return nil, parseKubernetesError(nil, 0, err)
 	}
 	return resource.NewUnstructuredWrapper(resp), nil
 }
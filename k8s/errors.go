@@ -1,5 +1,15 @@
 package k8s
 
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
 // NewServerResponseError creates a new instance of ServerResponseError
 func NewServerResponseError(err error, statusCode int) *ServerResponseError {
 	return &ServerResponseError{
@@ -10,9 +20,12 @@ func NewServerResponseError(err error, statusCode int) *ServerResponseError {
 
 // ServerResponseError represents an HTTP error from the kubernetes control plane.
 // It contains the underlying error returned by the kubernetes go client, and the status code returned from the API.
+// ServerResponseError implements k8s.io/apimachinery/pkg/api/errors.APIStatus, so the helper functions in this package
+// (such as IsNotFound or IsConflict), as well as those in the apimachinery errors package, can be used with it.
 type ServerResponseError struct {
 	err        error
 	statusCode int
+	status     *metav1.Status
 }
 
 // Error returns the Error() of the underlying kubernetes client error
@@ -25,7 +38,131 @@ func (s *ServerResponseError) StatusCode() int {
 	return s.statusCode
 }
 
+// Status returns the metav1.Status returned by the kubernetes API for the failed request, including the reason and details.
+// If the API did not return a metav1.Status, one is constructed from the status code and error message.
+func (s *ServerResponseError) Status() metav1.Status {
+	var status metav1.Status
+	statusErr := &k8serrors.StatusError{}
+	switch {
+	case s.status != nil:
+		status = *s.status
+	case errors.As(s.err, &statusErr):
+		status = statusErr.ErrStatus
+	default:
+		status = metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: s.err.Error(),
+		}
+	}
+	if status.Code == 0 {
+		status.Code = int32(s.statusCode)
+	}
+	return status
+}
+
 // Unwrap returns the underlying kubernetes go client error
 func (s *ServerResponseError) Unwrap() error {
 	return s.err
 }
+
+var _ k8serrors.APIStatus = &ServerResponseError{}
+
+// IsNotFound returns true if the error indicates that the requested resource does not exist.
+func IsNotFound(err error) bool {
+	return k8serrors.IsNotFound(err)
+}
+
+// IsAlreadyExists returns true if the error indicates that the resource being created already exists.
+func IsAlreadyExists(err error) bool {
+	return k8serrors.IsAlreadyExists(err)
+}
+
+// IsConflict returns true if the error indicates that the request could not be completed due to a conflict,
+// such as an update with an out-of-date resource version.
+func IsConflict(err error) bool {
+	return k8serrors.IsConflict(err)
+}
+
+// IsForbidden returns true if the error indicates that the request was not permitted for the requesting user.
+func IsForbidden(err error) bool {
+	return k8serrors.IsForbidden(err)
+}
+
+// IsUnauthorized returns true if the error indicates that the request was made without valid authentication.
+func IsUnauthorized(err error) bool {
+	return k8serrors.IsUnauthorized(err)
+}
+
+// IsInvalid returns true if the error indicates that the submitted resource failed validation.
+// The invalid fields are available in the Details of the error's metav1.Status, see StatusForError.
+func IsInvalid(err error) bool {
+	return k8serrors.IsInvalid(err)
+}
+
+// IsTooManyRequests returns true if the error indicates that the request was rate-limited by the server.
+// Use RetryAfter to get the delay requested by the server.
+func IsTooManyRequests(err error) bool {
+	return k8serrors.IsTooManyRequests(err)
+}
+
+// RetryAfter returns the duration the server requested the client wait before retrying the request,
+// and true if the error contains such a request.
+func RetryAfter(err error) (time.Duration, bool) {
+	seconds, ok := k8serrors.SuggestsClientDelay(err)
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// StatusForError returns the metav1.Status of an error returned by the kubernetes API, and true if err is (or wraps)
+// an error with a metav1.Status, such as a *ServerResponseError.
+func StatusForError(err error) (metav1.Status, bool) {
+	var apiStatus k8serrors.APIStatus
+	if !errors.As(err, &apiStatus) {
+		return metav1.Status{}, false
+	}
+	return apiStatus.Status(), true
+}
+
+// WatchExpiredError is returned in the Error of a resource.WatchEvent (with an EventType of "ERROR") when a watch cannot
+// continue from its resource version, because the API server has compacted it (HTTP 410 Gone).
+// The watch is stopped after this event, and the consumer must re-list the resources to get their current state and
+// resource version before starting a new watch.
+type WatchExpiredError struct {
+	// ResourceVersion is the resource version the watch could not continue from
+	ResourceVersion string
+	err             error
+}
+
+// Error returns an error message including the expired resource version
+func (w *WatchExpiredError) Error() string {
+	return fmt.Sprintf("watch expired at resource version '%s', a re-list is required: %s", w.ResourceVersion, w.err.Error())
+}
+
+// StatusCode returns http.StatusGone
+func (*WatchExpiredError) StatusCode() int {
+	return http.StatusGone
+}
+
+// Unwrap returns the underlying error returned by the API server
+func (w *WatchExpiredError) Unwrap() error {
+	return w.err
+}
+
+// IsWatchExpired returns true if the error is, or wraps, a WatchExpiredError,
+// or is an error returned by the API server indicating that the requested resource version has expired.
+func IsWatchExpired(err error) bool {
+	if err == nil {
+		return false
+	}
+	expired := &WatchExpiredError{}
+	if errors.As(err, &expired) {
+		return true
+	}
+	srvErr := &ServerResponseError{}
+	if errors.As(err, &srvErr) && srvErr.StatusCode() == http.StatusGone {
+		return true
+	}
+	return k8serrors.IsGone(err) || k8serrors.IsResourceExpired(err)
+}
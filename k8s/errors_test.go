@@ -0,0 +1,116 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+func TestServerResponseError_Status(t *testing.T) {
+	t.Run("code only", func(t *testing.T) {
+		err := NewServerResponseError(fmt.Errorf("not found"), http.StatusNotFound)
+		status := err.Status()
+		assert.Equal(t, int32(http.StatusNotFound), status.Code)
+		assert.Equal(t, metav1.StatusFailure, status.Status)
+		assert.Equal(t, "not found", status.Message)
+		assert.True(t, IsNotFound(err))
+		assert.False(t, IsConflict(err))
+	})
+
+	t.Run("wrapped StatusError", func(t *testing.T) {
+		statusErr := k8serrors.NewAlreadyExists(schema.GroupResource{Group: "foo", Resource: "bars"}, "bar")
+		err := NewServerResponseError(statusErr, http.StatusConflict)
+		assert.Equal(t, statusErr.ErrStatus, err.Status())
+		assert.True(t, IsAlreadyExists(err))
+		// AlreadyExists has a 409 code, but a known reason which is not Conflict
+		assert.False(t, IsConflict(err))
+	})
+}
+
+func TestErrorHelpers(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		check  func(error) bool
+		expect bool
+	}{
+		{"nil", nil, IsNotFound, false},
+		{"plain error", fmt.Errorf("foo"), IsNotFound, false},
+		{"not found", NewServerResponseError(fmt.Errorf("foo"), http.StatusNotFound), IsNotFound, true},
+		{"wrapped not found", fmt.Errorf("wrapped: %w", NewServerResponseError(fmt.Errorf("foo"), http.StatusNotFound)), IsNotFound, true},
+		{"conflict", NewServerResponseError(fmt.Errorf("foo"), http.StatusConflict), IsConflict, true},
+		{"already exists", NewServerResponseError(k8serrors.NewAlreadyExists(schema.GroupResource{}, "foo"), http.StatusConflict), IsAlreadyExists, true},
+		{"forbidden", NewServerResponseError(fmt.Errorf("foo"), http.StatusForbidden), IsForbidden, true},
+		{"unauthorized", NewServerResponseError(fmt.Errorf("foo"), http.StatusUnauthorized), IsUnauthorized, true},
+		{"invalid", NewServerResponseError(fmt.Errorf("foo"), http.StatusUnprocessableEntity), IsInvalid, true},
+		{"too many requests", NewServerResponseError(fmt.Errorf("foo"), http.StatusTooManyRequests), IsTooManyRequests, true},
+		{"forbidden is not not found", NewServerResponseError(fmt.Errorf("foo"), http.StatusForbidden), IsNotFound, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expect, test.check(test.err))
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	_, ok := RetryAfter(fmt.Errorf("foo"))
+	assert.False(t, ok)
+	_, ok = RetryAfter(NewServerResponseError(fmt.Errorf("foo"), http.StatusTooManyRequests))
+	assert.False(t, ok)
+	d, ok := RetryAfter(NewServerResponseError(k8serrors.NewTooManyRequests("slow down", 5), http.StatusTooManyRequests))
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, d)
+}
+
+func TestClient_Errors(t *testing.T) {
+	client, server := getClientTestSetup(testKind)
+	defer server.Close()
+
+	status := metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusFailure,
+		Message:  "test.group \"foo\" is invalid: spec.foo: Required value",
+		Reason:   metav1.StatusReasonInvalid,
+		Details: &metav1.StatusDetails{
+			Name: "foo",
+			Causes: []metav1.StatusCause{{
+				Type:    metav1.CauseTypeFieldValueRequired,
+				Message: "Required value",
+				Field:   "spec.foo",
+			}},
+		},
+		Code: http.StatusUnprocessableEntity,
+	}
+	server.responseFunc = func(writer http.ResponseWriter, r *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(writer).Encode(status)
+	}
+
+	_, err := client.Get(context.Background(), resource.Identifier{Namespace: "ns", Name: "foo"})
+	require.NotNil(t, err)
+	assert.True(t, IsInvalid(err))
+	assert.False(t, IsNotFound(err))
+	parsed, ok := StatusForError(err)
+	require.True(t, ok)
+	assert.Equal(t, status.Reason, parsed.Reason)
+	assert.Equal(t, status.Code, parsed.Code)
+	require.NotNil(t, parsed.Details)
+	assert.Equal(t, status.Details.Causes, parsed.Details.Causes)
+	// Still compatible with the resource package's error interface
+	cast, ok := err.(resource.APIServerResponseError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusUnprocessableEntity, cast.StatusCode())
+}
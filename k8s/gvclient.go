@@ -9,7 +9,6 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -21,7 +20,6 @@ import (
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
 
-	"github.com/grafana/grafana-app-sdk/logging"
 	"github.com/grafana/grafana-app-sdk/resource"
 )
 
@@ -143,8 +141,9 @@ func (g *groupVersionClient) exists(ctx context.Context, identifier resource.Ide
 			return false, nil
 		}
 		if sc > 0 {
+			err = parseKubernetesError(nil, sc, err)
 			span.SetStatus(codes.Error, err.Error())
-			return false, NewServerResponseError(err, sc)
+			return false, err
 		}
 		span.SetStatus(codes.Error, err.Error())
 		return false, err
@@ -392,16 +391,32 @@ func (g *groupVersionClient) delete(ctx context.Context, identifier resource.Ide
 	)
 	g.incRequestCounter(sc, "DELETE", plural, "spec")
 	if err != nil && sc >= 300 {
-		return NewServerResponseError(err, sc)
+		return parseKubernetesError(nil, sc, err)
 	}
 	return err
 }
 
 func (g *groupVersionClient) list(ctx context.Context, namespace, plural string, into resource.ListObject,
 	options resource.ListOptions, itemParser func([]byte) (resource.Object, error)) error {
+	return g.listWithAccept(ctx, namespace, plural, into, options, itemParser, "")
+}
+
+// listMetadata lists only the metadata of resources, as metav1.PartialObjectMetadata, and passes the raw
+// PartialObjectMetadata bytes of each item to itemParser
+func (g *groupVersionClient) listMetadata(ctx context.Context, namespace, plural string, into resource.ListObject,
+	options resource.ListOptions, itemParser func([]byte) (resource.Object, error)) error {
+	return g.listWithAccept(ctx, namespace, plural, into, options, itemParser, partialObjectMetadataListAccept)
+}
+
+//nolint:revive
+func (g *groupVersionClient) listWithAccept(ctx context.Context, namespace, plural string, into resource.ListObject,
+	options resource.ListOptions, itemParser func([]byte) (resource.Object, error), accept string) error {
 	ctx, span := GetTracer().Start(ctx, "kubernetes-list")
 	defer span.End()
 	req := g.client.Get().Resource(plural)
+	if accept != "" {
+		req = req.SetHeader("Accept", accept)
+	}
 	if strings.TrimSpace(namespace) != "" {
 		req = req.Namespace(namespace)
 	}
@@ -443,25 +458,52 @@ func (g *groupVersionClient) list(ctx context.Context, namespace, plural string,
 //nolint:revive
 func (g *groupVersionClient) watch(ctx context.Context, namespace, plural string,
 	exampleObject resource.Object, options resource.WatchOptions, codec resource.Codec) (*WatchResponse, error) {
+	return g.watchWithAccept(ctx, namespace, plural, exampleObject, options, codec, "")
+}
+
+// watchMetadata watches only the metadata of resources, as metav1.PartialObjectMetadata.
+// codec must be able to read PartialObjectMetadata bytes into exampleObject.
+//
+//nolint:revive
+func (g *groupVersionClient) watchMetadata(ctx context.Context, namespace, plural string,
+	exampleObject resource.Object, options resource.WatchOptions, codec resource.Codec) (*WatchResponse, error) {
+	return g.watchWithAccept(ctx, namespace, plural, exampleObject, options, codec, partialObjectMetadataAccept)
+}
+
+//nolint:revive,funlen
+func (g *groupVersionClient) watchWithAccept(ctx context.Context, namespace, plural string,
+	exampleObject resource.Object, options resource.WatchOptions, codec resource.Codec, accept string) (*WatchResponse, error) {
 	ctx, span := GetTracer().Start(ctx, "kubernetes-watch")
 	defer span.End()
-	g.client.Get()
-	req := g.client.Get().Resource(plural).
-		Param("watch", "1")
-	if strings.TrimSpace(namespace) != "" {
-		req = req.Namespace(namespace)
-	}
-	if len(options.LabelFilters) > 0 {
-		req = req.Param("labelSelector", strings.Join(options.LabelFilters, ","))
-	}
-	if len(options.FieldSelectors) > 0 {
-		req = req.Param("fieldSelector", strings.Join(options.FieldSelectors, ","))
-	}
-	if options.ResourceVersion != "" {
-		req = req.Param("resourceVersion", options.ResourceVersion)
+	// newRequest builds the watch request from the provided resource version. It is used both for the initial request,
+	// and for re-establishing the watch when options.Reconnect is true.
+	newRequest := func(resourceVersion string) *rest.Request {
+		req := g.client.Get().Resource(plural).
+			Param("watch", "1")
+		if accept != "" {
+			req = req.SetHeader("Accept", accept)
+		}
+		if strings.TrimSpace(namespace) != "" {
+			req = req.Namespace(namespace)
+		}
+		if len(options.LabelFilters) > 0 {
+			req = req.Param("labelSelector", strings.Join(options.LabelFilters, ","))
+		}
+		if len(options.FieldSelectors) > 0 {
+			req = req.Param("fieldSelector", strings.Join(options.FieldSelectors, ","))
+		}
+		if resourceVersion != "" {
+			req = req.Param("resourceVersion", resourceVersion)
+		}
+		if options.AllowWatchBookmarks {
+			req = req.Param("allowWatchBookmarks", "true")
+		}
+		return req
 	}
+	req := newRequest(options.ResourceVersion)
 	resp, err := req.Watch(ctx)
 	if err != nil {
+		err = parseKubernetesError(nil, 0, err)
 		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
@@ -478,11 +520,24 @@ func (g *groupVersionClient) watch(ctx context.Context, namespace, plural string
 		channelBufferSize = 1
 	}
 	w := &WatchResponse{
-		ex:     exampleObject,
-		codec:  codec,
-		watch:  resp,
-		ch:     make(chan resource.WatchEvent, channelBufferSize),
-		stopCh: make(chan struct{}),
+		ex:              exampleObject,
+		codec:           codec,
+		watch:           resp,
+		ch:              make(chan resource.WatchEvent, channelBufferSize),
+		resourceVersion: options.ResourceVersion,
+	}
+	if options.Reconnect {
+		// Reconnects use a child of the watch's context, so that either cancelling it or calling Stop() ends the watch
+		reconnectCtx, cancel := context.WithCancel(ctx)
+		w.cancelReconnect = cancel
+		w.reconnect = func(resourceVersion string) (watch.Interface, error) {
+			wi, err := newRequest(resourceVersion).Watch(reconnectCtx)
+			if err != nil {
+				return nil, parseKubernetesError(nil, 0, err)
+			}
+			g.incRequestCounter(http.StatusOK, "WATCH", plural, "spec")
+			return wi, nil
+		}
 	}
 	return w, nil
 }
@@ -509,100 +564,6 @@ func (g *groupVersionClient) metrics() []prometheus.Collector {
 	}
 }
 
-// WatchResponse wraps a kubernetes watch.Interface in order to implement resource.WatchResponse.
-// The underlying watch.Interface can be accessed with KubernetesWatch().
-type WatchResponse struct {
-	watch    watch.Interface
-	ch       chan resource.WatchEvent
-	stopCh   chan struct{}
-	ex       resource.Object
-	codec    resource.Codec
-	started  bool
-	startMux sync.Mutex
-}
-
-//nolint:revive,staticcheck,gocritic
-func (w *WatchResponse) start() {
-	for {
-		select {
-		case evt := <-w.watch.ResultChan():
-			if evt.Object == nil {
-				if logging.DefaultLogger != nil {
-					logging.DefaultLogger.Warn("Received nil object in watch event")
-				}
-				break
-			}
-			var obj resource.Object
-			if cast, ok := evt.Object.(resource.Object); ok {
-				obj = cast
-			} else if cast, ok := evt.Object.(intoObject); ok {
-				obj = w.ex.Copy()
-				err := cast.Into(obj, w.codec)
-				if err != nil {
-					// TODO: hmm
-					break
-				}
-			} else if cast, ok := evt.Object.(wrappedObject); ok {
-				obj = cast.ResourceObject()
-			} else {
-				// TODO: hmm
-				if logging.DefaultLogger != nil {
-					logging.DefaultLogger.Error(
-						"Unable to parse watch event object, does not implement resource.Object or have Into() or ResourceObject(). Please check your NegotiatedSerializer.",
-						"groupVersionKind", evt.Object.GetObjectKind().GroupVersionKind().String())
-				}
-			}
-			w.ch <- resource.WatchEvent{
-				EventType: string(evt.Type),
-				Object:    obj,
-			}
-		case <-w.stopCh:
-			close(w.stopCh)
-			return
-		}
-	}
-}
-
-// Stop stops the translation channel between the kubernetes watch.Interface,
-// and stops the continued watch request encapsulated by the watch.Interface.
-func (w *WatchResponse) Stop() {
-	w.startMux.Lock()
-	defer w.startMux.Unlock()
-	w.stopCh <- struct{}{}
-	close(w.ch)
-	w.watch.Stop()
-	w.started = false
-}
-
-// WatchEvents returns a channel that receives watch events.
-// All calls to this method will return the same channel.
-// This channel will stop receiving events if KubernetesWatch() is called, as that halts the event translation process.
-// If Stop() is called, ths channel is closed.
-func (w *WatchResponse) WatchEvents() <-chan resource.WatchEvent {
-	w.startMux.Lock()
-	defer w.startMux.Unlock()
-	if !w.started {
-		// Start the translation buffer
-		go w.start()
-		w.started = true
-	}
-	return w.ch
-}
-
-// KubernetesWatch returns the underlying watch.Interface.
-// Calling this method will shut down the translation channel between the watch.Interface and ResultChan().
-// Using both KubernetesWatch() and ResultChan() simultaneously is not supported, and may result in undefined behavior.
-func (w *WatchResponse) KubernetesWatch() watch.Interface {
-	w.startMux.Lock()
-	defer w.startMux.Unlock()
-	// Stop the internal channel with the translation layer
-	if w.started {
-		w.stopCh <- struct{}{}
-		w.started = false
-	}
-	return w.watch
-}
-
 type k8sErrBody struct {
 	Message string `json:"message"`
 	Code    int    `json:"code"`
@@ -617,13 +578,24 @@ type k8sErrBody struct {
 //
 //nolint:govet
 func parseKubernetesError(responseBytes []byte, statusCode int, err error) error {
+	var status *metav1.Status
+	if len(responseBytes) > 0 {
+		// Keep the full status (with reason and details) if the body is a metav1.Status,
+		// as it may not have been decoded into the error by the NegotiatedSerializer
+		s := metav1.Status{}
+		if e := json.Unmarshal(responseBytes, &s); e == nil && s.Kind == "Status" {
+			status = &s
+		}
+	}
 	if err != nil {
 		statusErr := &k8serrors.StatusError{}
 		if errors.As(err, &statusErr) {
 			if statusCode == 0 || (statusErr.ErrStatus.Code > 0 && statusCode != int(statusErr.ErrStatus.Code)) {
-				return NewServerResponseError(statusErr, int(statusErr.ErrStatus.Code))
+				statusCode = int(statusErr.ErrStatus.Code)
 			}
-			return NewServerResponseError(statusErr, statusCode)
+			srvErr := NewServerResponseError(statusErr, statusCode)
+			srvErr.status = status
+			return srvErr
 		}
 	}
 	if len(responseBytes) > 0 {
@@ -638,7 +610,9 @@ func parseKubernetesError(responseBytes []byte, statusCode int, err error) error
 	}
 	// HTTP error?
 	if statusCode >= 300 {
-		return NewServerResponseError(err, statusCode)
+		srvErr := NewServerResponseError(err, statusCode)
+		srvErr.status = status
+		return srvErr
 	}
 	return err
 }
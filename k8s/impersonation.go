@@ -0,0 +1,90 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/transport"
+)
+
+// Impersonation is an identity to impersonate when making requests to the API server.
+// The identity used to make the request (from the rest.Config) must have permission to impersonate it.
+// See https://kubernetes.io/docs/reference/access-authn-authz/authentication/#user-impersonation
+type Impersonation struct {
+	// UserName is the username of the user to impersonate. An Impersonation with an empty UserName is ignored.
+	UserName string
+	// UID is the UID of the user to impersonate, and is optional.
+	UID string
+	// Groups are the groups of the user to impersonate.
+	Groups []string
+	// Extra contains additional information about the user to impersonate, such as scopes.
+	Extra map[string][]string
+}
+
+// ErrNoImpersonation is returned for requests made with RequireImpersonation set
+// when the ImpersonationProvider doesn't return an identity to impersonate.
+var ErrNoImpersonation = errors.New("no identity to impersonate for request")
+
+type impersonationContextKey struct{}
+
+// WithImpersonation returns a copy of the provided context with the Impersonation set to impersonation.
+// When a ClientConfig's ImpersonationProvider is ImpersonationFromContext, requests made by a Client with this context
+// will impersonate the identity.
+func WithImpersonation(ctx context.Context, impersonation Impersonation) context.Context {
+	return context.WithValue(ctx, impersonationContextKey{}, impersonation)
+}
+
+// ImpersonationFromContext returns the Impersonation set in the context by WithImpersonation,
+// and false if none has been set. It can be used as the ImpersonationProvider in ClientConfig.
+func ImpersonationFromContext(ctx context.Context) (Impersonation, bool) {
+	imp, ok := ctx.Value(impersonationContextKey{}).(Impersonation)
+	return imp, ok
+}
+
+// impersonatingRoundTripper sets the impersonation headers on each request based on the request's context
+type impersonatingRoundTripper struct {
+	provider func(context.Context) (Impersonation, bool)
+	// required makes requests without an identity to impersonate fail, rather than using the base identity
+	required bool
+	delegate http.RoundTripper
+}
+
+func newImpersonatingRoundTripper(provider func(context.Context) (Impersonation, bool), required bool) transport.WrapperFunc {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &impersonatingRoundTripper{
+			provider: provider,
+			required: required,
+			delegate: rt,
+		}
+	}
+}
+
+func (i *impersonatingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	imp, ok := i.provider(req.Context())
+	if !ok || imp.UserName == "" {
+		if i.required {
+			return nil, ErrNoImpersonation
+		}
+		return i.delegate.RoundTrip(req)
+	}
+	req = utilnet.CloneRequest(req)
+	// Any impersonation set by the rest.Config is replaced by the per-request identity
+	for key := range req.Header {
+		if strings.HasPrefix(key, "Impersonate-") {
+			req.Header.Del(key)
+		}
+	}
+	return transport.NewImpersonatingRoundTripper(transport.ImpersonationConfig{
+		UserName: imp.UserName,
+		UID:      imp.UID,
+		Groups:   imp.Groups,
+		Extra:    imp.Extra,
+	}, i.delegate).RoundTrip(req)
+}
+
+func (i *impersonatingRoundTripper) WrappedRoundTripper() http.RoundTripper {
+	return i.delegate
+}
@@ -0,0 +1,92 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+func TestClientRegistry_Impersonation(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(k8sResponseObject)
+	}))
+	defer server.Close()
+	id := resource.Identifier{Namespace: "ns", Name: "foo"}
+
+	t.Run("no provider", func(t *testing.T) {
+		client, err := NewClientRegistry(rest.Config{Host: server.URL}, ClientConfig{}).ClientFor(testKind)
+		require.Nil(t, err)
+		_, err = client.Get(WithImpersonation(context.Background(), Impersonation{UserName: "foo"}), id)
+		require.Nil(t, err)
+		assert.Empty(t, headers.Get("Impersonate-User"))
+	})
+
+	client, err := NewClientRegistry(rest.Config{
+		Host: server.URL,
+		Impersonate: rest.ImpersonationConfig{
+			UserName: "config-user",
+			Groups:   []string{"config-group"},
+		},
+	}, ClientConfig{
+		ImpersonationProvider: ImpersonationFromContext,
+	}).ClientFor(testKind)
+	require.Nil(t, err)
+
+	t.Run("no identity in context", func(t *testing.T) {
+		_, err := client.Get(context.Background(), id)
+		require.Nil(t, err)
+		assert.Equal(t, "config-user", headers.Get("Impersonate-User"))
+		assert.Equal(t, []string{"config-group"}, headers.Values("Impersonate-Group"))
+	})
+
+	t.Run("identity in context", func(t *testing.T) {
+		ctx := WithImpersonation(context.Background(), Impersonation{
+			UserName: "user",
+			UID:      "123",
+			Groups:   []string{"a", "b"},
+			Extra:    map[string][]string{"scopes": {"foo", "bar"}},
+		})
+		_, err := client.Get(ctx, id)
+		require.Nil(t, err)
+		assert.Equal(t, "user", headers.Get("Impersonate-User"))
+		assert.Equal(t, "123", headers.Get("Impersonate-Uid"))
+		assert.Equal(t, []string{"a", "b"}, headers.Values("Impersonate-Group"))
+		assert.Equal(t, []string{"foo", "bar"}, headers.Values("Impersonate-Extra-Scopes"))
+	})
+
+	t.Run("impersonation required", func(t *testing.T) {
+		client, err := NewClientRegistry(rest.Config{Host: server.URL}, ClientConfig{
+			ImpersonationProvider: ImpersonationFromContext,
+			RequireImpersonation:  true,
+		}).ClientFor(testKind)
+		require.Nil(t, err)
+		headers = nil
+		_, err = client.Get(context.Background(), id)
+		assert.ErrorIs(t, err, ErrNoImpersonation)
+		assert.Nil(t, headers)
+		_, err = client.Get(WithImpersonation(context.Background(), Impersonation{}), id)
+		assert.ErrorIs(t, err, ErrNoImpersonation)
+		_, err = client.Get(WithImpersonation(context.Background(), Impersonation{UserName: "user"}), id)
+		require.Nil(t, err)
+		assert.Equal(t, "user", headers.Get("Impersonate-User"))
+	})
+}
+
+func TestImpersonationFromContext(t *testing.T) {
+	_, ok := ImpersonationFromContext(context.Background())
+	assert.False(t, ok)
+	imp, ok := ImpersonationFromContext(WithImpersonation(context.Background(), Impersonation{UserName: "foo"}))
+	assert.True(t, ok)
+	assert.Equal(t, "foo", imp.UserName)
+}
@@ -0,0 +1,45 @@
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+const (
+	// partialObjectMetadataAccept is the Accept header used to request a single object as metav1.PartialObjectMetadata
+	partialObjectMetadataAccept = "application/json;as=PartialObjectMetadata;g=meta.k8s.io;v=v1"
+	// partialObjectMetadataListAccept is the Accept header used to request a list as metav1.PartialObjectMetadataList
+	partialObjectMetadataListAccept = "application/json;as=PartialObjectMetadataList;g=meta.k8s.io;v=v1"
+)
+
+// partialObjectMetadataCodec reads metav1.PartialObjectMetadata JSON into a resource.Object of a specific kind,
+// leaving all non-metadata components of the object (such as spec and status) as their zero values.
+// The API server returns PartialObjectMetadata with an apiVersion and kind of meta.k8s.io/v1 PartialObjectMetadata,
+// so the GroupVersionKind is replaced with the kind's before reading the bytes with the underlying codec.
+type partialObjectMetadataCodec struct {
+	codec resource.Codec
+	gvk   schema.GroupVersionKind
+}
+
+func (p *partialObjectMetadataCodec) Read(in io.Reader, into resource.Object) error {
+	md := metav1.PartialObjectMetadata{}
+	if err := json.NewDecoder(in).Decode(&md); err != nil {
+		return err
+	}
+	md.SetGroupVersionKind(p.gvk)
+	raw, err := json.Marshal(md)
+	if err != nil {
+		return err
+	}
+	return p.codec.Read(bytes.NewReader(raw), into)
+}
+
+func (p *partialObjectMetadataCodec) Write(out io.Writer, obj resource.Object) error {
+	return p.codec.Write(out, obj)
+}
@@ -0,0 +1,67 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+func TestClient_ListMetadata(t *testing.T) {
+	client, server := getClientTestSetup(testKind)
+	defer server.Close()
+
+	server.responseFunc = func(writer http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, partialObjectMetadataListAccept, r.Header.Get("Accept"))
+		assert.Equal(t, "a=b", r.URL.Query().Get("labelSelector"))
+		list := metav1.PartialObjectMetadataList{
+			TypeMeta: metav1.TypeMeta{Kind: "PartialObjectMetadataList", APIVersion: "meta.k8s.io/v1"},
+			ListMeta: metav1.ListMeta{ResourceVersion: "10"},
+			Items:    []metav1.PartialObjectMetadata{*partialObjectMetadataTestObject("1")},
+		}
+		require.Nil(t, json.NewEncoder(writer).Encode(list))
+	}
+
+	list, err := client.ListMetadata(context.Background(), "ns", resource.ListOptions{LabelFilters: []string{"a=b"}})
+	require.Nil(t, err)
+	assert.Equal(t, "10", list.GetResourceVersion())
+	require.Len(t, list.GetItems(), 1)
+	item, ok := list.GetItems()[0].(*resource.TypedSpecObject[testSpec])
+	require.True(t, ok)
+	assert.Equal(t, testKind.GroupVersionKind(), item.GroupVersionKind())
+	assert.Equal(t, k8sResponseObject.Name, item.GetName())
+	assert.Equal(t, k8sResponseObject.Labels, item.GetLabels())
+	assert.Equal(t, "1", item.GetResourceVersion())
+	assert.Equal(t, testSpec{}, item.Spec)
+}
+
+func TestClient_WatchMetadata(t *testing.T) {
+	client, _ := getWatchTestSetup(t, func(writer http.ResponseWriter, r *http.Request, _ int) {
+		assert.Equal(t, partialObjectMetadataAccept, r.Header.Get("Accept"))
+		writeWatchEvent(t, writer, "ADDED", partialObjectMetadataTestObject("1"))
+	})
+	resp, err := client.WatchMetadata(context.Background(), "ns", resource.WatchOptions{})
+	require.Nil(t, err)
+	defer resp.Stop()
+	evt := <-resp.WatchEvents()
+	assert.Equal(t, "ADDED", evt.EventType)
+	require.NotNil(t, evt.Object)
+	assert.Equal(t, testKind.GroupVersionKind(), evt.Object.GroupVersionKind())
+	assert.Equal(t, k8sResponseObject.Name, evt.Object.GetName())
+	assert.Equal(t, "1", evt.Object.GetResourceVersion())
+}
+
+func partialObjectMetadataTestObject(resourceVersion string) *metav1.PartialObjectMetadata {
+	md := k8sResponseObject.ObjectMeta
+	md.ResourceVersion = resourceVersion
+	return &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{Kind: "PartialObjectMetadata", APIVersion: "meta.k8s.io/v1"},
+		ObjectMeta: md,
+	}
+}
@@ -0,0 +1,168 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// RequestPriority is the priority of a request made to the API server, used by client-side rate limiting.
+// The priority of a request is taken from its context, see WithRequestPriority.
+type RequestPriority int
+
+const (
+	// RequestPriorityDefault is the priority of any request which does not have a priority set in its context.
+	RequestPriorityDefault RequestPriority = iota
+	// RequestPriorityLow is the priority for background traffic, such as reconcilers, which can tolerate being throttled
+	// in favor of other traffic (such as requests made while handling webhooks or user requests).
+	// Requests with this priority are subject to RateLimitConfig.LowPriority in addition to the other configured limits.
+	RequestPriorityLow
+)
+
+// String returns the string representation of the RequestPriority
+func (p RequestPriority) String() string {
+	switch p {
+	case RequestPriorityDefault:
+		return "default"
+	case RequestPriorityLow:
+		return "low"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(p))
+	}
+}
+
+type requestPriorityContextKey struct{}
+
+// WithRequestPriority returns a copy of the provided context with the RequestPriority set to priority.
+// Requests made by a Client with this context will be rate limited according to the priority.
+func WithRequestPriority(ctx context.Context, priority RequestPriority) context.Context {
+	return context.WithValue(ctx, requestPriorityContextKey{}, priority)
+}
+
+// RequestPriorityFromContext returns the RequestPriority set in the context by WithRequestPriority,
+// or RequestPriorityDefault if none has been set.
+func RequestPriorityFromContext(ctx context.Context) RequestPriority {
+	if p, ok := ctx.Value(requestPriorityContextKey{}).(RequestPriority); ok {
+		return p
+	}
+	return RequestPriorityDefault
+}
+
+// RateLimit is a token-bucket rate limit of QPS sustained requests per second, with bursts of up to Burst requests.
+type RateLimit struct {
+	// QPS is the sustained number of requests per second allowed. If QPS is 0, the limit is not applied.
+	QPS float32
+	// Burst is the maximum number of requests allowed in a burst. If Burst is less than 1, it is treated as 1.
+	Burst int
+}
+
+// RateLimitConfig configures client-side rate limiting of requests made to the API server by clients created by a ClientRegistry.
+// When set, it replaces the QPS, Burst, and RateLimiter of the rest.Config used by the ClientRegistry.
+type RateLimitConfig struct {
+	// Global is the rate limit shared by all clients created by the ClientRegistry.
+	Global RateLimit
+	// Kinds contains per-kind rate limits, which are applied in addition to the Global limit.
+	// A kind's limit is shared by all versions of the kind.
+	Kinds map[schema.GroupKind]RateLimit
+	// LowPriority is the rate limit applied to requests made with RequestPriorityLow, in addition to the Global and per-kind limits.
+	// Setting this lower than the Global limit reserves the remaining capacity for higher-priority traffic.
+	LowPriority RateLimit
+}
+
+func newRateLimiter(limit RateLimit) *rate.Limiter {
+	if limit.QPS <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(limit.QPS), max(limit.Burst, 1))
+}
+
+// clientRateLimiter implements flowcontrol.RateLimiter, and applies a set of shared rate limiters to requests,
+// based on the RequestPriority in the request context.
+type clientRateLimiter struct {
+	kind         string
+	limiters     []*rate.Limiter
+	lowPriority  *rate.Limiter
+	waitDuration *prometheus.HistogramVec
+	throttled    *prometheus.CounterVec
+}
+
+func (c *clientRateLimiter) limitersFor(priority RequestPriority) []*rate.Limiter {
+	if priority == RequestPriorityLow && c.lowPriority != nil {
+		return append(append(make([]*rate.Limiter, 0, len(c.limiters)+1), c.limiters...), c.lowPriority)
+	}
+	return c.limiters
+}
+
+// reserve reserves a token from every applicable limiter, returning the reservations and the longest delay among them
+func (*clientRateLimiter) reserve(limiters []*rate.Limiter) ([]*rate.Reservation, time.Duration) {
+	reservations := make([]*rate.Reservation, 0, len(limiters))
+	delay := time.Duration(0)
+	for _, l := range limiters {
+		r := l.Reserve()
+		reservations = append(reservations, r)
+		if d := r.Delay(); d > delay {
+			delay = d
+		}
+	}
+	return reservations, delay
+}
+
+// TryAccept returns true if a token is available from all applicable limiters for a default-priority request
+func (c *clientRateLimiter) TryAccept() bool {
+	reservations, delay := c.reserve(c.limitersFor(RequestPriorityDefault))
+	if delay > 0 {
+		for _, r := range reservations {
+			r.Cancel()
+		}
+		return false
+	}
+	return true
+}
+
+// Accept blocks until a token is available from all applicable limiters for a default-priority request
+func (c *clientRateLimiter) Accept() {
+	_ = c.Wait(context.Background())
+}
+
+// Wait blocks until a token is available from all limiters applicable for the RequestPriority of the context,
+// or until the context is done, in which case it returns the context's error.
+func (c *clientRateLimiter) Wait(ctx context.Context) error {
+	priority := RequestPriorityFromContext(ctx)
+	reservations, delay := c.reserve(c.limitersFor(priority))
+	c.waitDuration.WithLabelValues(c.kind, priority.String()).Observe(delay.Seconds())
+	if delay <= 0 {
+		return nil
+	}
+	c.throttled.WithLabelValues(c.kind, priority.String()).Inc()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		for _, r := range reservations {
+			r.Cancel()
+		}
+		return ctx.Err()
+	}
+}
+
+// Stop is a no-op, as the underlying limiters are shared between clients
+func (*clientRateLimiter) Stop() {}
+
+// QPS returns the lowest QPS of the limiters applied to default-priority requests
+func (c *clientRateLimiter) QPS() float32 {
+	qps := float32(math.MaxFloat32)
+	for _, l := range c.limiters {
+		qps = min(qps, float32(l.Limit()))
+	}
+	return qps
+}
+
+var _ flowcontrol.RateLimiter = &clientRateLimiter{}
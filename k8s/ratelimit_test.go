@@ -0,0 +1,76 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+func TestRequestPriorityFromContext(t *testing.T) {
+	assert.Equal(t, RequestPriorityDefault, RequestPriorityFromContext(context.Background()))
+	assert.Equal(t, RequestPriorityLow, RequestPriorityFromContext(WithRequestPriority(context.Background(), RequestPriorityLow)))
+}
+
+func TestClientRegistry_rateLimiterFor(t *testing.T) {
+	registry := NewClientRegistry(rest.Config{}, ClientConfig{
+		RateLimitConfig: &RateLimitConfig{
+			Global: RateLimit{QPS: 100, Burst: 10},
+			Kinds: map[schema.GroupKind]RateLimit{
+				{Group: testKind.Group(), Kind: testKind.Kind()}: {QPS: 50, Burst: 2},
+			},
+			LowPriority: RateLimit{QPS: 1, Burst: 1},
+		},
+	})
+
+	limiter := registry.rateLimiterFor(testKind)
+	assert.Len(t, limiter.limiters, 2)
+	assert.Equal(t, float32(50), limiter.QPS())
+	// The per-kind limiter is shared between clients for the kind
+	assert.Same(t, limiter.limiters[1], registry.rateLimiterFor(testKind).limiters[1])
+
+	t.Run("default priority", func(t *testing.T) {
+		// Burst for the kind is 2
+		assert.True(t, limiter.TryAccept())
+		assert.True(t, limiter.TryAccept())
+		assert.False(t, limiter.TryAccept())
+	})
+
+	t.Run("low priority", func(t *testing.T) {
+		lowPriority := registry.rateLimiterFor(resource.Kind{
+			Schema: resource.NewSimpleSchema("other", "v1", &resource.TypedSpecObject[testSpec]{}, &resource.TypedList[*resource.TypedSpecObject[testSpec]]{}, resource.WithKind("other")),
+		})
+		ctx := WithRequestPriority(context.Background(), RequestPriorityLow)
+		require.Nil(t, lowPriority.Wait(ctx))
+		// Low priority burst is 1, and refills at 1 QPS, so a second request must wait
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, lowPriority.Wait(ctx), context.DeadlineExceeded)
+		assert.Equal(t, float64(1), testutil.ToFloat64(registry.throttledRequests.WithLabelValues(lowPriority.kind, "low")))
+		// Default priority traffic is not subject to the low priority limit
+		require.Nil(t, lowPriority.Wait(context.Background()))
+		assert.Equal(t, float64(0), testutil.ToFloat64(registry.throttledRequests.WithLabelValues(lowPriority.kind, "default")))
+	})
+}
+
+func TestClientRegistry_ClientFor_RateLimitConfig(t *testing.T) {
+	registry := NewClientRegistry(rest.Config{}, ClientConfig{
+		RateLimitConfig: &RateLimitConfig{
+			Global: RateLimit{QPS: 10, Burst: 10},
+		},
+	})
+	client, err := registry.ClientFor(testKind)
+	require.Nil(t, err)
+	cast, ok := client.(*Client)
+	require.True(t, ok)
+	restClient, ok := cast.client.client.(*rest.RESTClient)
+	require.True(t, ok)
+	assert.IsType(t, &clientRateLimiter{}, restClient.GetRateLimiter())
+}
@@ -7,7 +7,8 @@ import (
 	"reflect"
 	"strings"
 
-	admission "k8s.io/api/admission/v1beta1"
+	admission "k8s.io/api/admission/v1"
+	conversion "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/grafana/grafana-app-sdk/resource"
@@ -108,16 +109,82 @@ func getV1ObjectMetaFields() map[string]struct{} {
 	return fields
 }
 
+const (
+	admissionReviewAPIVersionV1       = "admission.k8s.io/v1"
+	admissionReviewAPIVersionV1beta1  = "admission.k8s.io/v1beta1"
+	conversionReviewAPIVersionV1      = "apiextensions.k8s.io/v1"
+	conversionReviewAPIVersionV1beta1 = "apiextensions.k8s.io/v1beta1"
+)
+
+// unmarshalKubernetesAdmissionReview unmarshals an admission.k8s.io/v1 or admission.k8s.io/v1beta1 AdmissionReview
+// into an admission.k8s.io/v1 AdmissionReview. The TypeMeta of the returned AdmissionReview retains the version sent
+// in the request, so that the response can be sent in the same version.
+// An AdmissionReview with no apiVersion is treated as admission.k8s.io/v1beta1 for backwards compatibility.
 func unmarshalKubernetesAdmissionReview(raw []byte, format resource.WireFormat) (*admission.AdmissionReview, error) {
 	if format != resource.WireFormatJSON {
 		return nil, fmt.Errorf("unsupported WireFormat '%s'", fmt.Sprint(format))
 	}
 
+	// The v1 and v1beta1 AdmissionReview share the same wire representation,
+	// so we can decode either version directly into the v1 type.
 	rev := admission.AdmissionReview{}
 	err := json.Unmarshal(raw, &rev)
 	if err != nil {
 		return nil, err
 	}
+	switch rev.APIVersion {
+	case "", admissionReviewAPIVersionV1, admissionReviewAPIVersionV1beta1:
+	default:
+		return nil, fmt.Errorf("unsupported AdmissionReview apiVersion '%s'", rev.APIVersion)
+	}
+	if rev.Request == nil {
+		return nil, fmt.Errorf("AdmissionReview has no request")
+	}
+	// requestKind and requestResource are optional in v1beta1, and are not sent by older API servers.
+	// If they are absent, the kind and resource of the request are the same as the ones being admitted.
+	if rev.Request.RequestKind == nil {
+		rev.Request.RequestKind = &rev.Request.Kind
+	}
+	if rev.Request.RequestResource == nil {
+		rev.Request.RequestResource = &rev.Request.Resource
+	}
+	return &rev, nil
+}
+
+// admissionReviewResponseTypeMeta returns the TypeMeta to use for the response to an AdmissionReview with the provided TypeMeta
+func admissionReviewResponseTypeMeta(requestTypeMeta metav1.TypeMeta) metav1.TypeMeta {
+	if requestTypeMeta.APIVersion == "" {
+		return requestTypeMeta
+	}
+	return metav1.TypeMeta{
+		APIVersion: requestTypeMeta.APIVersion,
+		Kind:       "AdmissionReview",
+	}
+}
+
+// unmarshalKubernetesConversionReview unmarshals an apiextensions.k8s.io/v1 or apiextensions.k8s.io/v1beta1 ConversionReview
+// into an apiextensions.k8s.io/v1 ConversionReview. The TypeMeta of the returned ConversionReview retains the version sent
+// in the request, so that the response can be sent in the same version.
+func unmarshalKubernetesConversionReview(raw []byte, format resource.WireFormat) (*conversion.ConversionReview, error) {
+	if format != resource.WireFormatJSON {
+		return nil, fmt.Errorf("unsupported WireFormat '%s'", fmt.Sprint(format))
+	}
+
+	// The v1 and v1beta1 ConversionReview share the same wire representation,
+	// so we can decode either version directly into the v1 type.
+	rev := conversion.ConversionReview{}
+	err := json.Unmarshal(raw, &rev)
+	if err != nil {
+		return nil, err
+	}
+	switch rev.APIVersion {
+	case "", conversionReviewAPIVersionV1, conversionReviewAPIVersionV1beta1:
+	default:
+		return nil, fmt.Errorf("unsupported ConversionReview apiVersion '%s'", rev.APIVersion)
+	}
+	if rev.Request == nil {
+		return nil, fmt.Errorf("ConversionReview has no request")
+	}
 	return &rev, nil
 }
 
@@ -0,0 +1,289 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/grafana/grafana-app-sdk/logging"
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+const (
+	watchReconnectInitialBackoff = 250 * time.Millisecond
+	watchReconnectMaxBackoff     = 30 * time.Second
+)
+
+// WatchResponse wraps a kubernetes watch.Interface in order to implement resource.WatchResponse.
+// The underlying watch.Interface can be accessed with KubernetesWatch().
+//
+// If the watch was created with resource.WatchOptions.Reconnect, WatchResponse tracks the resource version of each
+// received event (including BOOKMARK events, which are not forwarded), and re-establishes the watch from that resource version
+// whenever the underlying watch ends. If the resource version has expired, an ERROR event with a *WatchExpiredError
+// is sent, and the channel returned by WatchEvents() is closed.
+type WatchResponse struct {
+	watch     watch.Interface
+	ch        chan resource.WatchEvent
+	ex        resource.Object
+	codec     resource.Codec
+	reconnect func(resourceVersion string) (watch.Interface, error)
+	// cancelReconnect cancels any in-flight or future reconnect requests
+	cancelReconnect context.CancelFunc
+	started         bool
+	startMux        sync.Mutex
+	stopCh          chan struct{}
+	doneCh          chan struct{}
+	closeOnce       sync.Once
+
+	resourceVersion string
+	rvMux           sync.RWMutex
+}
+
+// start runs the translation loop between the kubernetes watch.Interface and the WatchEvents() channel
+// until stopCh is closed or the watch ends
+func (w *WatchResponse) start(stopCh <-chan struct{}, doneCh chan<- struct{}) {
+	defer close(doneCh)
+	for {
+		var cont bool
+		select {
+		case <-stopCh:
+			return
+		case evt, ok := <-w.watch.ResultChan():
+			if ok {
+				cont = w.handleEvent(evt, stopCh)
+			} else {
+				// The watch request has ended, either by server timeout or a connection error
+				cont = w.reconnect != nil && w.reconnectWatch(stopCh)
+			}
+		}
+		if !cont {
+			select {
+			case <-stopCh:
+				// Stop() or KubernetesWatch() is responsible for the events channel
+			default:
+				// The watch has ended on its own, so there will be no more events
+				w.closeEvents()
+			}
+			return
+		}
+	}
+}
+
+// handleEvent translates and forwards a single watch event, returning false if the translation loop should exit
+//
+//nolint:revive,gocritic
+func (w *WatchResponse) handleEvent(evt watch.Event, stopCh <-chan struct{}) bool {
+	if evt.Object == nil {
+		if logging.DefaultLogger != nil {
+			logging.DefaultLogger.Warn("Received nil object in watch event")
+		}
+		return true
+	}
+	switch evt.Type {
+	case watch.Error:
+		err := w.watchEventError(evt.Object)
+		if w.reconnect == nil || IsWatchExpired(err) {
+			sent := w.send(resource.WatchEvent{
+				EventType: string(evt.Type),
+				Error:     err,
+			}, stopCh)
+			// An expired resource version is not recoverable by reconnecting
+			return sent && !IsWatchExpired(err)
+		}
+		if logging.DefaultLogger != nil {
+			logging.DefaultLogger.Warn("Received error in watch, reconnecting", "error", err)
+		}
+		w.watch.Stop()
+		return w.reconnectWatch(stopCh)
+	case watch.Bookmark:
+		w.setResourceVersion(evt.Object)
+		if w.reconnect != nil {
+			// Bookmarks only carry the resource version, which is tracked internally when reconnecting
+			return true
+		}
+	default:
+	}
+	var obj resource.Object
+	if cast, ok := evt.Object.(resource.Object); ok {
+		obj = cast
+	} else if cast, ok := evt.Object.(intoObject); ok {
+		obj = w.ex.Copy()
+		err := cast.Into(obj, w.codec)
+		if err != nil {
+			if logging.DefaultLogger != nil {
+				logging.DefaultLogger.Error("Unable to parse watch event object", "error", err, "eventType", string(evt.Type))
+			}
+			return true
+		}
+	} else if cast, ok := evt.Object.(wrappedObject); ok {
+		obj = cast.ResourceObject()
+	} else if logging.DefaultLogger != nil {
+		logging.DefaultLogger.Error(
+			"Unable to parse watch event object, does not implement resource.Object or have Into() or ResourceObject(). Please check your NegotiatedSerializer.",
+			"groupVersionKind", evt.Object.GetObjectKind().GroupVersionKind().String())
+	}
+	w.setResourceVersion(evt.Object)
+	return w.send(resource.WatchEvent{
+		EventType: string(evt.Type),
+		Object:    obj,
+	}, stopCh)
+}
+
+// reconnectWatch re-establishes the watch from the last seen resource version, retrying with backoff.
+// It returns false if the translation loop should exit, either because stopCh was closed,
+// the watch context is done, or the resource version has expired.
+func (w *WatchResponse) reconnectWatch(stopCh <-chan struct{}) bool {
+	backoff := watchReconnectInitialBackoff
+	for {
+		resourceVersion := w.ResourceVersion()
+		wi, err := w.reconnect(resourceVersion)
+		if err == nil {
+			w.watch = wi
+			return true
+		}
+		if IsWatchExpired(err) {
+			w.send(resource.WatchEvent{
+				EventType: string(watch.Error),
+				Error: &WatchExpiredError{
+					ResourceVersion: resourceVersion,
+					err:             err,
+				},
+			}, stopCh)
+			return false
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		if logging.DefaultLogger != nil {
+			logging.DefaultLogger.Warn("Unable to reconnect watch, retrying", "error", err, "resourceVersion", resourceVersion, "backoff", backoff)
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-stopCh:
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, watchReconnectMaxBackoff)
+	}
+}
+
+// watchEventError converts the metav1.Status object of an ERROR event into an error.
+// If the status indicates that the resource version has expired, the returned error is a *WatchExpiredError.
+func (w *WatchResponse) watchEventError(obj runtime.Object) error {
+	var status metav1.Status
+	switch cast := obj.(type) {
+	case *metav1.Status:
+		status = *cast
+	case *UntypedObjectWrapper:
+		if err := json.Unmarshal(cast.object, &status); err != nil {
+			return NewServerResponseError(err, http.StatusInternalServerError)
+		}
+	default:
+		return k8serrors.FromObject(obj)
+	}
+	err := &k8serrors.StatusError{ErrStatus: status}
+	if status.Code == http.StatusGone || status.Reason == metav1.StatusReasonExpired || status.Reason == metav1.StatusReasonGone {
+		return &WatchExpiredError{
+			ResourceVersion: w.ResourceVersion(),
+			err:             err,
+		}
+	}
+	return NewServerResponseError(err, int(status.Code))
+}
+
+// send sends the event to the WatchEvents() channel, returning false if stopCh was closed before it could be sent
+//
+//nolint:gocritic
+func (w *WatchResponse) send(evt resource.WatchEvent, stopCh <-chan struct{}) bool {
+	select {
+	case w.ch <- evt:
+		return true
+	case <-stopCh:
+		return false
+	}
+}
+
+func (w *WatchResponse) setResourceVersion(obj runtime.Object) {
+	cast, ok := obj.(interface{ GetResourceVersion() string })
+	if !ok || cast.GetResourceVersion() == "" {
+		return
+	}
+	w.rvMux.Lock()
+	defer w.rvMux.Unlock()
+	w.resourceVersion = cast.GetResourceVersion()
+}
+
+// ResourceVersion returns the resource version of the last event received by the watch (including BOOKMARK events),
+// or the resource version the watch was started from if no events with a resource version have been received.
+func (w *WatchResponse) ResourceVersion() string {
+	w.rvMux.RLock()
+	defer w.rvMux.RUnlock()
+	return w.resourceVersion
+}
+
+func (w *WatchResponse) closeEvents() {
+	w.closeOnce.Do(func() {
+		close(w.ch)
+	})
+}
+
+// stopTranslation stops the translation loop (if running) and waits for it to exit. The caller must hold startMux.
+func (w *WatchResponse) stopTranslation() {
+	if !w.started {
+		return
+	}
+	close(w.stopCh)
+	<-w.doneCh
+	w.started = false
+}
+
+// Stop stops the translation channel between the kubernetes watch.Interface,
+// and stops the continued watch request encapsulated by the watch.Interface.
+func (w *WatchResponse) Stop() {
+	w.startMux.Lock()
+	defer w.startMux.Unlock()
+	if w.cancelReconnect != nil {
+		w.cancelReconnect()
+	}
+	w.stopTranslation()
+	w.closeEvents()
+	w.watch.Stop()
+}
+
+// WatchEvents returns a channel that receives watch events.
+// All calls to this method will return the same channel.
+// This channel will stop receiving events if KubernetesWatch() is called, as that halts the event translation process.
+// If Stop() is called, or the watch ends (and is not reconnected), ths channel is closed.
+func (w *WatchResponse) WatchEvents() <-chan resource.WatchEvent {
+	w.startMux.Lock()
+	defer w.startMux.Unlock()
+	if !w.started {
+		// Start the translation buffer
+		w.stopCh = make(chan struct{})
+		w.doneCh = make(chan struct{})
+		go w.start(w.stopCh, w.doneCh)
+		w.started = true
+	}
+	return w.ch
+}
+
+// KubernetesWatch returns the underlying watch.Interface.
+// Calling this method will shut down the translation channel between the watch.Interface and ResultChan().
+// Using both KubernetesWatch() and ResultChan() simultaneously is not supported, and may result in undefined behavior.
+// The returned watch.Interface is not reconnected if it ends.
+func (w *WatchResponse) KubernetesWatch() watch.Interface {
+	w.startMux.Lock()
+	defer w.startMux.Unlock()
+	// Stop the internal channel with the translation layer
+	w.stopTranslation()
+	return w.watch
+}
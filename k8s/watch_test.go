@@ -0,0 +1,221 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+func TestClient_Watch(t *testing.T) {
+	t.Run("stream end closes channel", func(t *testing.T) {
+		client, _ := getWatchTestSetup(t, func(writer http.ResponseWriter, r *http.Request, _ int) {
+			writeWatchEvent(t, writer, "ADDED", watchTestObject("1"))
+		})
+		resp, err := client.Watch(context.Background(), "ns", resource.WatchOptions{})
+		require.Nil(t, err)
+		events := resp.WatchEvents()
+		evt := <-events
+		assert.Equal(t, "ADDED", evt.EventType)
+		assert.Equal(t, "1", evt.Object.GetResourceVersion())
+		_, ok := <-events
+		assert.False(t, ok)
+		resp.Stop()
+	})
+
+	t.Run("error event", func(t *testing.T) {
+		client, _ := getWatchTestSetup(t, func(writer http.ResponseWriter, r *http.Request, _ int) {
+			writeWatchEvent(t, writer, "ERROR", &metav1.Status{
+				TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   metav1.StatusFailure,
+				Message:  "too old resource version",
+				Reason:   metav1.StatusReasonExpired,
+				Code:     http.StatusGone,
+			})
+		})
+		resp, err := client.Watch(context.Background(), "ns", resource.WatchOptions{ResourceVersion: "5"})
+		require.Nil(t, err)
+		evt := <-resp.WatchEvents()
+		assert.Equal(t, "ERROR", evt.EventType)
+		require.NotNil(t, evt.Error)
+		assert.True(t, IsWatchExpired(evt.Error))
+		expired := &WatchExpiredError{}
+		require.ErrorAs(t, evt.Error, &expired)
+		assert.Equal(t, "5", expired.ResourceVersion)
+		resp.Stop()
+	})
+
+	t.Run("reconnect from bookmark", func(t *testing.T) {
+		client, requests := getWatchTestSetup(t, func(writer http.ResponseWriter, r *http.Request, call int) {
+			switch call {
+			case 0:
+				writeWatchEvent(t, writer, "ADDED", watchTestObject("1"))
+				writeWatchEvent(t, writer, "BOOKMARK", watchTestObject("3"))
+			case 1:
+				writeWatchEvent(t, writer, "MODIFIED", watchTestObject("4"))
+			default:
+				writeWatchHeaders(writer)
+				<-r.Context().Done()
+			}
+		})
+		resp, err := client.Watch(context.Background(), "ns", resource.WatchOptions{
+			AllowWatchBookmarks: true,
+			Reconnect:           true,
+		})
+		require.Nil(t, err)
+		events := resp.WatchEvents()
+		evt := <-events
+		assert.Equal(t, "ADDED", evt.EventType)
+		// The bookmark is not forwarded, the next event is from the reconnected watch
+		evt = <-events
+		assert.Equal(t, "MODIFIED", evt.EventType)
+		assert.Equal(t, "4", evt.Object.GetResourceVersion())
+		require.Eventually(t, func() bool {
+			return len(requests()) == 3
+		}, time.Second, 10*time.Millisecond)
+		resp.Stop()
+		_, ok := <-events
+		assert.False(t, ok)
+
+		reqs := requests()
+		assert.Equal(t, "true", reqs[0].Get("allowWatchBookmarks"))
+		assert.Equal(t, "", reqs[0].Get("resourceVersion"))
+		assert.Equal(t, "3", reqs[1].Get("resourceVersion"))
+		assert.Equal(t, "4", reqs[2].Get("resourceVersion"))
+	})
+
+	t.Run("reconnect expired", func(t *testing.T) {
+		client, _ := getWatchTestSetup(t, func(writer http.ResponseWriter, r *http.Request, call int) {
+			if call == 0 {
+				writeWatchEvent(t, writer, "ADDED", watchTestObject("1"))
+				return
+			}
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusGone)
+			json.NewEncoder(writer).Encode(&metav1.Status{
+				TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   metav1.StatusFailure,
+				Reason:   metav1.StatusReasonGone,
+				Code:     http.StatusGone,
+			})
+		})
+		resp, err := client.Watch(context.Background(), "ns", resource.WatchOptions{Reconnect: true})
+		require.Nil(t, err)
+		events := resp.WatchEvents()
+		evt := <-events
+		assert.Equal(t, "ADDED", evt.EventType)
+		evt = <-events
+		assert.Equal(t, "ERROR", evt.EventType)
+		assert.True(t, IsWatchExpired(evt.Error))
+		expired := &WatchExpiredError{}
+		require.ErrorAs(t, evt.Error, &expired)
+		assert.Equal(t, "1", expired.ResourceVersion)
+		_, ok := <-events
+		assert.False(t, ok)
+		resp.Stop()
+	})
+}
+
+func TestWatchResponse_KubernetesWatch(t *testing.T) {
+	client, _ := getWatchTestSetup(t, func(writer http.ResponseWriter, r *http.Request, _ int) {
+		writeWatchHeaders(writer)
+		<-r.Context().Done()
+	})
+	resp, err := client.Watch(context.Background(), "ns", resource.WatchOptions{})
+	require.Nil(t, err)
+	cast, ok := resp.(*WatchResponse)
+	require.True(t, ok)
+	resp.WatchEvents()
+	// Stopping the translation and then the whole watch must not block or panic
+	kw := cast.KubernetesWatch()
+	require.NotNil(t, kw)
+	resp.Stop()
+	_, ok = <-resp.WatchEvents()
+	assert.False(t, ok)
+}
+
+func TestIsWatchExpired(t *testing.T) {
+	assert.False(t, IsWatchExpired(nil))
+	assert.False(t, IsWatchExpired(fmt.Errorf("error")))
+	assert.True(t, IsWatchExpired(NewServerResponseError(fmt.Errorf("gone"), http.StatusGone)))
+	assert.True(t, IsWatchExpired(fmt.Errorf("wrapped: %w", &WatchExpiredError{err: fmt.Errorf("expired")})))
+	assert.False(t, IsWatchExpired(NewServerResponseError(fmt.Errorf("not found"), http.StatusNotFound)))
+}
+
+func watchTestObject(resourceVersion string) any {
+	obj := k8sResponseObject
+	obj.ResourceVersion = resourceVersion
+	return obj
+}
+
+func writeWatchHeaders(writer http.ResponseWriter) {
+	writer.WriteHeader(http.StatusOK)
+	if f, ok := writer.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func writeWatchEvent(t *testing.T, writer http.ResponseWriter, eventType string, obj any) {
+	raw, err := json.Marshal(obj)
+	require.Nil(t, err)
+	require.Nil(t, json.NewEncoder(writer).Encode(metav1.WatchEvent{
+		Type:   eventType,
+		Object: runtime.RawExtension{Raw: raw},
+	}))
+	if f, ok := writer.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// getWatchTestSetup returns a Client which makes requests to a test server using the GenericNegotiatedSerializer,
+// which is required to decode watch events. responseFunc is called with the number of preceding watch requests,
+// and a function returning the query of each request made is returned.
+func getWatchTestSetup(t *testing.T, responseFunc func(http.ResponseWriter, *http.Request, int)) (*Client, func() []url.Values) {
+	mux := sync.Mutex{}
+	requests := make([]url.Values, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		call := len(requests)
+		requests = append(requests, r.URL.Query())
+		mux.Unlock()
+		writer.Header().Set("Content-Type", "application/json")
+		responseFunc(writer, r, call)
+	}))
+	t.Cleanup(server.Close)
+	gv := schema.GroupVersion{Group: testKind.Group(), Version: testKind.Version()}
+	restClient := &mockRESTClient{
+		GetFunc: func() *rest.Request {
+			u, _ := url.Parse(server.URL)
+			return rest.NewRequestWithClient(u, "", rest.ClientContentConfig{
+				GroupVersion: gv,
+				ContentType:  "application/json",
+				Negotiator:   runtime.NewClientNegotiator(&GenericNegotiatedSerializer{}, gv),
+			}, server.Client()).Verb("GET")
+		},
+	}
+	return &Client{
+		client: &groupVersionClient{
+			client: restClient,
+		},
+		schema: testKind,
+		codec:  testKind.Codec(resource.KindEncodingJSON),
+	}, func() []url.Values {
+		mux.Lock()
+		defer mux.Unlock()
+		return append(make([]url.Values, 0, len(requests)), requests...)
+	}
+}
@@ -4,27 +4,43 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gomodules.xyz/jsonpatch/v2"
-	admission "k8s.io/api/admission/v1beta1"
+	admission "k8s.io/api/admission/v1"
 	conversion "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/grafana/grafana-app-sdk/logging"
+	"github.com/grafana/grafana-app-sdk/metrics"
 	"github.com/grafana/grafana-app-sdk/resource"
 )
 
 // WebhookServerConfig is the configuration object for a WebhookServer, used with NewWebhookServer.
 type WebhookServerConfig struct {
-	// The Port to run the HTTPS server on
+	// The Port to run the HTTPS server on. It is ignored if UnixSocketPath is set.
 	Port int
-	// TLSConfig contains cert information for running the HTTPS server
+	// TLSConfig contains cert information for running the HTTPS server. It is ignored if DisableTLS is true.
 	TLSConfig TLSConfig
+	// DisableTLS runs the server over plain HTTP instead of HTTPS. This should only be used when TLS is terminated
+	// before requests reach the server (such as by a sidecar proxy), or for local development and testing.
+	DisableTLS bool
+	// UnixSocketPath, if non-empty, makes the server listen on a unix socket at the provided path instead of on Port.
+	// Any existing socket at the path is removed when the server starts, but if any other kind of file exists at the path,
+	// the server fails to start.
+	UnixSocketPath string
 	// ValidatingControllers is a map of schemas to their corresponding ValidatingAdmissionController.
 	ValidatingControllers map[*resource.Kind]resource.ValidatingAdmissionController
 	// MutatingControllers is a map of schemas to their corresponding MutatingAdmissionController.
@@ -38,6 +54,9 @@ type WebhookServerConfig struct {
 	// DefaultMutatingController is called for any /validate requests received which don't have an entry in MutatingControllers.
 	// If left nil, an error will be returned to the caller instead.
 	DefaultMutatingController resource.MutatingAdmissionController
+	// MetricsConfig contains the configuration for the prometheus collectors exposed by the WebhookServer
+	// via PrometheusCollectors.
+	MetricsConfig metrics.Config
 }
 
 // TLSConfig describes a set of TLS files
@@ -50,6 +69,8 @@ type TLSConfig struct {
 
 // WebhookServer is a kubernetes webhook server, which exposes /validate and /mutate HTTPS endpoints.
 // It implements operator.Controller and can be run as a controller in an operator, or as a standalone process.
+// Both admission.k8s.io/v1 and admission.k8s.io/v1beta1 AdmissionReviews (and apiextensions.k8s.io/v1 and v1beta1 ConversionReviews)
+// are accepted, and responses are sent in the same version as the request.
 type WebhookServer struct {
 	// DefaultValidatingController is the default ValidatingAdmissionController to use if one is not defined for the schema in the request.
 	// If this is empty, the request will be rejected.
@@ -62,20 +83,27 @@ type WebhookServer struct {
 	converters                map[string]Converter
 	port                      int
 	tlsConfig                 TLSConfig
+	disableTLS                bool
+	unixSocketPath            string
+	requestDurations          *prometheus.HistogramVec
+	totalRequests             *prometheus.CounterVec
+	totalRejections           *prometheus.CounterVec
 }
 
 // NewWebhookServer creates a new WebhookServer using the provided configuration.
-// The only required parts of the config are the Port and TLSConfig, as all other parts
-// (default controllers, schema-specific controllers) can be set post-initialization.
+// The only required parts of the config are the Port (or UnixSocketPath) and TLSConfig (unless DisableTLS is true),
+// as all other parts (default controllers, schema-specific controllers) can be set post-initialization.
 func NewWebhookServer(config WebhookServerConfig) (*WebhookServer, error) {
-	if config.Port < 1 || config.Port > 65536 {
+	if config.UnixSocketPath == "" && (config.Port < 1 || config.Port > 65536) {
 		return nil, fmt.Errorf("config.Port must be a valid port number (between 1 and 65536)")
 	}
-	if config.TLSConfig.CertPath == "" {
-		return nil, fmt.Errorf("config.TLSConfig.CertPath is required")
-	}
-	if config.TLSConfig.KeyPath == "" {
-		return nil, fmt.Errorf("config.TLSConfig.KeyPath is required")
+	if !config.DisableTLS {
+		if config.TLSConfig.CertPath == "" {
+			return nil, fmt.Errorf("config.TLSConfig.CertPath is required")
+		}
+		if config.TLSConfig.KeyPath == "" {
+			return nil, fmt.Errorf("config.TLSConfig.KeyPath is required")
+		}
 	}
 
 	ws := WebhookServer{
@@ -86,6 +114,30 @@ func NewWebhookServer(config WebhookServerConfig) (*WebhookServer, error) {
 		converters:                  make(map[string]Converter),
 		port:                        config.Port,
 		tlsConfig:                   config.TLSConfig,
+		disableTLS:                  config.DisableTLS,
+		unixSocketPath:              config.UnixSocketPath,
+		requestDurations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:                       config.MetricsConfig.Namespace,
+			Subsystem:                       "webhook",
+			Name:                            "request_duration_seconds",
+			Help:                            "Time (in seconds) spent serving webhook requests.",
+			Buckets:                         metrics.LatencyBuckets,
+			NativeHistogramBucketFactor:     config.MetricsConfig.NativeHistogramBucketFactor,
+			NativeHistogramMaxBucketNumber:  config.MetricsConfig.NativeHistogramMaxBucketNumber,
+			NativeHistogramMinResetDuration: time.Hour,
+		}, []string{"handler", "kind", "operation"}),
+		totalRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.MetricsConfig.Namespace,
+			Subsystem: "webhook",
+			Name:      "requests_total",
+			Help:      "Total number of webhook requests",
+		}, []string{"handler", "kind", "operation", "status_code"}),
+		totalRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.MetricsConfig.Namespace,
+			Subsystem: "webhook",
+			Name:      "rejections_total",
+			Help:      "Total number of webhook requests which were rejected or failed",
+		}, []string{"handler", "kind", "operation", "reason"}),
 	}
 
 	for sch, controller := range config.ValidatingControllers {
@@ -145,22 +197,44 @@ func (w *WebhookServer) AddConverter(converter Converter, groupKind metav1.Group
 	w.converters[gk(groupKind.Group, groupKind.Kind)] = converter
 }
 
-// Run establishes an HTTPS server on the configured port and exposes `/validate` and `/mutate` paths for kubernetes
-// validating and mutating webhooks, respectively. It will block until either closeChan is closed (in which case it returns nil),
-// or the server encounters an unrecoverable error (in which case it returns the error).
-func (w *WebhookServer) Run(closeChan <-chan struct{}) error {
+// PrometheusCollectors returns the prometheus metric collectors used by the WebhookServer to allow for registration
+func (w *WebhookServer) PrometheusCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		w.totalRequests, w.requestDurations, w.totalRejections,
+	}
+}
+
+// Handler returns an http.Handler which serves the `/validate`, `/mutate`, and `/convert` paths.
+// It can be used to mount the webhook handlers in another server, or to test them with net/http/httptest.
+func (w *WebhookServer) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/validate", w.HandleValidateHTTP)
 	mux.HandleFunc("/mutate", w.HandleMutateHTTP)
 	mux.HandleFunc("/convert", w.HandleConvertHTTP)
+	return mux
+}
+
+// Run establishes an HTTPS server on the configured port and exposes `/validate` and `/mutate` paths for kubernetes
+// validating and mutating webhooks, respectively. If DisableTLS was set in the config, the server uses plain HTTP,
+// and if UnixSocketPath was set, the server listens on that unix socket instead of the port.
+// It will block until either closeChan is closed (in which case it returns nil),
+// or the server encounters an unrecoverable error (in which case it returns the error).
+func (w *WebhookServer) Run(closeChan <-chan struct{}) error {
+	listener, err := w.listen()
+	if err != nil {
+		return err
+	}
 	server := &http.Server{
-		Addr:              fmt.Sprintf(":%d", w.port),
-		Handler:           mux,
+		Handler:           w.Handler(),
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 	errCh := make(chan error, 1)
 	go func() {
-		errCh <- server.ListenAndServeTLS(w.tlsConfig.CertPath, w.tlsConfig.KeyPath)
+		if w.disableTLS {
+			errCh <- server.Serve(listener)
+		} else {
+			errCh <- server.ServeTLS(listener, w.tlsConfig.CertPath, w.tlsConfig.KeyPath)
+		}
 	}()
 	go func() {
 		for range closeChan {
@@ -171,13 +245,41 @@ func (w *WebhookServer) Run(closeChan <-chan struct{}) error {
 		defer cancelFunc()
 		errCh <- server.Shutdown(ctx)
 	}()
-	err := <-errCh
+	err = <-errCh
 	return err
 }
 
+func (w *WebhookServer) listen() (net.Listener, error) {
+	if w.unixSocketPath == "" {
+		return net.Listen("tcp", fmt.Sprintf(":%d", w.port))
+	}
+	// Remove a stale socket file left behind by a previous run, as it would otherwise prevent us from listening.
+	// Any other kind of file at the path is left alone.
+	info, err := os.Lstat(w.unixSocketPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("unable to check existing file at unix socket path '%s': %w", w.unixSocketPath, err)
+	case info.Mode()&os.ModeSocket == 0:
+		return nil, fmt.Errorf("unix socket path '%s' already exists and is not a socket", w.unixSocketPath)
+	default:
+		if err := os.Remove(w.unixSocketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("unable to remove existing unix socket '%s': %w", w.unixSocketPath, err)
+		}
+	}
+	return net.Listen("unix", w.unixSocketPath)
+}
+
 // HandleValidateHTTP is the HTTP HandlerFunc for a kubernetes validating webhook call
 // nolint:errcheck,revive,funlen
 func (w *WebhookServer) HandleValidateHTTP(writer http.ResponseWriter, req *http.Request) {
+	ctx, span := GetTracer().Start(req.Context(), "webhook-validate")
+	defer span.End()
+	req = req.WithContext(ctx)
+	rec := w.newRequestRecorder(writer, "validate", span)
+	defer rec.finish()
+	writer = rec
+
 	// Only POST is allowed
 	if req.Method != http.MethodPost {
 		writer.WriteHeader(http.StatusMethodNotAllowed)
@@ -215,49 +317,51 @@ func (w *WebhookServer) HandleValidateHTTP(writer http.ResponseWriter, req *http
 		controller = w.DefaultValidatingController
 	}
 
+	rec.setRequest(admRev.Request.RequestKind.Group, admRev.Request.RequestKind.Kind, string(admRev.Request.Operation))
+	adResp := admission.AdmissionResponse{
+		UID:     admRev.Request.UID,
+		Allowed: true,
+	}
+	defer rec.setAdmissionResponse(&adResp)
+
 	// If we didn't get a controller, return a failure
 	if controller == nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		writer.Write([]byte(fmt.Sprintf(errStringNoAdmissionControllerDefined, "validating", admRev.Request.RequestKind.Group, admRev.Request.RequestKind.Kind)))
+		err = NewAdmissionError(fmt.Errorf(errStringNoAdmissionControllerDefined, "validating", admRev.Request.RequestKind.Group, admRev.Request.RequestKind.Kind), http.StatusInternalServerError, string(metav1.StatusReasonInternalError))
 		logging.FromContext(req.Context()).Error("No controller", "error", err)
+		addAdmissionError(&adResp, admRev.Request, err)
+		writeAdmissionReview(req.Context(), writer, admissionReviewResponseTypeMeta(admRev.TypeMeta), &adResp)
 		return
 	}
 
 	// Translate the kubernetes admission request to one with a resource.Object in it, using the schema
 	admReq, err := translateKubernetesAdmissionRequest(admRev.Request, schema)
 	if err != nil {
-		// TODO: different error?
-		writer.WriteHeader(http.StatusBadRequest)
 		logging.FromContext(req.Context()).Error("Couldn't translate request", "error", err)
+		addAdmissionError(&adResp, admRev.Request, NewAdmissionError(err, http.StatusBadRequest, string(metav1.StatusReasonBadRequest)))
+		writeAdmissionReview(req.Context(), writer, admissionReviewResponseTypeMeta(admRev.TypeMeta), &adResp)
 		return
 	}
 
-	// Run the controller
-	err = controller.Validate(req.Context(), admReq)
-	adResp := admission.AdmissionResponse{
-		UID:     admRev.Request.UID,
-		Allowed: true,
-	}
+	// Run the controller, collecting any warnings and audit annotations it adds to the context
+	validateCtx, details := resource.WithAdmissionDetails(req.Context())
+	err = controller.Validate(validateCtx, admReq)
+	addAdmissionDetails(&adResp, details)
 	if err != nil {
-		addAdmissionError(&adResp, err)
+		addAdmissionError(&adResp, admRev.Request, err)
 	}
-	bytes, err := json.Marshal(&admission.AdmissionReview{
-		TypeMeta: admRev.TypeMeta,
-		Response: &adResp,
-	})
-	if err != nil {
-		// Bad news
-		writer.WriteHeader(http.StatusInternalServerError)
-		writer.Write([]byte(err.Error())) // TODO: better
-		return
-	}
-	writer.WriteHeader(http.StatusOK)
-	writer.Write(bytes)
+	writeAdmissionReview(req.Context(), writer, admissionReviewResponseTypeMeta(admRev.TypeMeta), &adResp)
 }
 
 // HandleMutateHTTP is the HTTP HandlerFunc for a kubernetes mutating webhook call
 // nolint:errcheck,revive,funlen
 func (w *WebhookServer) HandleMutateHTTP(writer http.ResponseWriter, req *http.Request) {
+	ctx, span := GetTracer().Start(req.Context(), "webhook-mutate")
+	defer span.End()
+	req = req.WithContext(ctx)
+	rec := w.newRequestRecorder(writer, "mutate", span)
+	defer rec.finish()
+	writer = rec
+
 	// Only POST is allowed
 	if req.Method != http.MethodPost {
 		writer.WriteHeader(http.StatusMethodNotAllowed)
@@ -292,53 +396,66 @@ func (w *WebhookServer) HandleMutateHTTP(writer http.ResponseWriter, req *http.R
 		controller = w.DefaultMutatingController
 	}
 
+	rec.setRequest(admRev.Request.RequestKind.Group, admRev.Request.RequestKind.Kind, string(admRev.Request.Operation))
+	adResp := admission.AdmissionResponse{
+		UID:     admRev.Request.UID,
+		Allowed: true,
+	}
+	defer rec.setAdmissionResponse(&adResp)
+
 	// If we didn't get a controller, return a failure
 	if controller == nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		writer.Write([]byte(fmt.Sprintf(errStringNoAdmissionControllerDefined, "mutating", admRev.Request.RequestKind.Group, admRev.Request.RequestKind.Kind)))
+		err = NewAdmissionError(fmt.Errorf(errStringNoAdmissionControllerDefined, "mutating", admRev.Request.RequestKind.Group, admRev.Request.RequestKind.Kind), http.StatusInternalServerError, string(metav1.StatusReasonInternalError))
+		logging.FromContext(req.Context()).Error("No controller", "error", err)
+		addAdmissionError(&adResp, admRev.Request, err)
+		writeAdmissionReview(req.Context(), writer, admissionReviewResponseTypeMeta(admRev.TypeMeta), &adResp)
 		return
 	}
 
 	// Translate the kubernetes admission request to one with a resource.Object in it, using the schema
 	admReq, err := translateKubernetesAdmissionRequest(admRev.Request, schema)
 	if err != nil {
-		// TODO: different error?
-		writer.WriteHeader(http.StatusBadRequest)
+		logging.FromContext(req.Context()).Error("Couldn't translate request", "error", err)
+		addAdmissionError(&adResp, admRev.Request, NewAdmissionError(err, http.StatusBadRequest, string(metav1.StatusReasonBadRequest)))
+		writeAdmissionReview(req.Context(), writer, admissionReviewResponseTypeMeta(admRev.TypeMeta), &adResp)
 		return
 	}
 
-	// Run the controller
-	mResp, err := controller.Mutate(req.Context(), admReq)
-	adResp := admission.AdmissionResponse{
-		UID:     admRev.Request.UID,
-		Allowed: true,
-	}
-	if err == nil && mResp != nil && mResp.UpdatedObject != nil {
-		pt := admission.PatchTypeJSONPatch
-		adResp.PatchType = &pt
-		// Re-use `err` here, we handle it below
-		adResp.Patch, err = w.generatePatch(admRev, mResp.UpdatedObject, schema.Codec(resource.KindEncodingJSON))
-	}
-	if err != nil {
-		addAdmissionError(&adResp, err)
+	// Run the controller, collecting any warnings and audit annotations it adds to the context
+	mutateCtx, details := resource.WithAdmissionDetails(req.Context())
+	mResp, err := controller.Mutate(mutateCtx, admReq)
+	addAdmissionDetails(&adResp, details)
+	if err == nil && mResp != nil {
+		adResp.Warnings = append(adResp.Warnings, mResp.Warnings...)
+		for k, v := range mResp.AuditAnnotations {
+			if adResp.AuditAnnotations == nil {
+				adResp.AuditAnnotations = make(map[string]string, len(mResp.AuditAnnotations))
+			}
+			adResp.AuditAnnotations[k] = v
+		}
+		if mResp.UpdatedObject != nil {
+			pt := admission.PatchTypeJSONPatch
+			adResp.PatchType = &pt
+			// Re-use `err` here, we handle it below
+			adResp.Patch, err = w.generatePatch(admRev, mResp.UpdatedObject, schema.Codec(resource.KindEncodingJSON))
+		}
 	}
-	bytes, err := json.Marshal(&admission.AdmissionReview{
-		TypeMeta: admRev.TypeMeta,
-		Response: &adResp,
-	})
 	if err != nil {
-		// Bad news
-		writer.WriteHeader(http.StatusInternalServerError)
-		writer.Write([]byte(err.Error())) // TODO: better
-		return
+		addAdmissionError(&adResp, admRev.Request, err)
 	}
-	writer.WriteHeader(http.StatusOK)
-	writer.Write(bytes)
+	writeAdmissionReview(req.Context(), writer, admissionReviewResponseTypeMeta(admRev.TypeMeta), &adResp)
 }
 
 // HandleConvertHTTP is the HTTP HandlerFunc for a kubernetes CRD conversion webhook call
 // nolint:errcheck,revive,funlen
 func (w *WebhookServer) HandleConvertHTTP(writer http.ResponseWriter, req *http.Request) {
+	ctx, span := GetTracer().Start(req.Context(), "webhook-convert")
+	defer span.End()
+	req = req.WithContext(ctx)
+	rec := w.newRequestRecorder(writer, "convert", span)
+	defer rec.finish()
+	writer = rec
+
 	// Only POST is allowed
 	if req.Method != http.MethodPost {
 		writer.WriteHeader(http.StatusMethodNotAllowed)
@@ -354,10 +471,10 @@ func (w *WebhookServer) HandleConvertHTTP(writer http.ResponseWriter, req *http.
 	}
 
 	// Unmarshal the ConversionReview
-	rev := conversion.ConversionReview{}
-	err = json.Unmarshal(body, &rev)
+	rev, err := unmarshalKubernetesConversionReview(body, resource.WireFormatJSON)
 	if err != nil {
 		writer.WriteHeader(http.StatusBadRequest)
+		logging.FromContext(req.Context()).Error("Couldn't unmarshal", "error", err)
 		return
 	}
 
@@ -384,6 +501,7 @@ func (w *WebhookServer) HandleConvertHTTP(writer http.ResponseWriter, req *http.
 			logging.FromContext(req.Context()).Error("Error unmarshaling basic type data from object for conversion", "error", err.Error())
 			break
 		}
+		rec.setRequest(tm.GroupVersionKind().Group, tm.Kind, "CONVERT")
 		// Get the associated converter for this kind
 		conv, ok := w.converters[gk(tm.GroupVersionKind().Group, tm.Kind)]
 		if !ok {
@@ -415,11 +533,13 @@ func (w *WebhookServer) HandleConvertHTTP(writer http.ResponseWriter, req *http.
 			Raw: res,
 		})
 	}
+	rec.setConversionResult(&rev.Response.Result)
 	resp, err := json.Marshal(rev)
 	if err != nil {
 		writer.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	writer.Header().Set("Content-Type", "application/json")
 	writer.Write(resp)
 }
 
@@ -457,18 +577,164 @@ func gvk(kind *metav1.GroupVersionKind) string {
 	return kind.String()
 }
 
+// writeAdmissionReview writes an AdmissionReview with the provided TypeMeta and AdmissionResponse to the writer.
+// nolint:errcheck
+func writeAdmissionReview(ctx context.Context, writer http.ResponseWriter, typeMeta metav1.TypeMeta, resp *admission.AdmissionResponse) {
+	bytes, err := json.Marshal(&admission.AdmissionReview{
+		TypeMeta: typeMeta,
+		Response: resp,
+	})
+	if err != nil {
+		// Bad news
+		logging.FromContext(ctx).Error("Couldn't marshal AdmissionReview response", "error", err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		writer.Write([]byte(http.StatusText(http.StatusInternalServerError)))
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(bytes)
+}
+
 //nolint:gosec
-func addAdmissionError(resp *admission.AdmissionResponse, err error) {
+func addAdmissionError(resp *admission.AdmissionResponse, req *admission.AdmissionRequest, err error) {
 	if err == nil || resp == nil {
 		return
 	}
 	resp.Allowed = false
 	resp.Result = &metav1.Status{
-		Status:  "Failure",
+		Status:  metav1.StatusFailure,
 		Message: err.Error(),
 	}
-	if cast, ok := err.(resource.AdmissionError); ok {
-		resp.Result.Code = int32(cast.StatusCode())
-		resp.Result.Reason = metav1.StatusReason(cast.Reason())
+	cast, ok := err.(resource.AdmissionError)
+	if !ok {
+		return
+	}
+	resp.Result.Code = int32(cast.StatusCode())
+	resp.Result.Reason = metav1.StatusReason(cast.Reason())
+	detailed, ok := cast.(resource.DetailedAdmissionError)
+	if !ok {
+		return
+	}
+	if causes := detailed.Causes(); len(causes) > 0 {
+		resp.Result.Details = &metav1.StatusDetails{
+			Causes: causes,
+		}
+		if req != nil {
+			resp.Result.Details.Name = req.Name
+			resp.Result.Details.Group = req.Kind.Group
+			resp.Result.Details.Kind = req.Kind.Kind
+		}
+	}
+	if warnings := detailed.Warnings(); len(warnings) > 0 {
+		resp.Warnings = append(resp.Warnings, warnings...)
+	}
+	if annotations := detailed.AuditAnnotations(); len(annotations) > 0 {
+		if resp.AuditAnnotations == nil {
+			resp.AuditAnnotations = make(map[string]string, len(annotations))
+		}
+		for k, v := range annotations {
+			resp.AuditAnnotations[k] = v
+		}
+	}
+}
+
+// addAdmissionDetails adds the warnings and audit annotations collected by details to resp
+func addAdmissionDetails(resp *admission.AdmissionResponse, details func() ([]string, map[string]string)) {
+	warnings, annotations := details()
+	resp.Warnings = append(resp.Warnings, warnings...)
+	if len(annotations) > 0 {
+		if resp.AuditAnnotations == nil {
+			resp.AuditAnnotations = make(map[string]string, len(annotations))
+		}
+		for k, v := range annotations {
+			resp.AuditAnnotations[k] = v
+		}
+	}
+}
+
+// webhookRequestRecorder wraps an http.ResponseWriter to record the status code of the response,
+// and records metrics and span attributes for a webhook request when finish is called.
+type webhookRequestRecorder struct {
+	http.ResponseWriter
+	server     *WebhookServer
+	span       trace.Span
+	start      time.Time
+	handler    string
+	group      string
+	kind       string
+	operation  string
+	statusCode int
+	rejected   bool
+	reason     string
+}
+
+func (w *WebhookServer) newRequestRecorder(writer http.ResponseWriter, handler string, span trace.Span) *webhookRequestRecorder {
+	return &webhookRequestRecorder{
+		ResponseWriter: writer,
+		server:         w,
+		span:           span,
+		start:          time.Now(),
+		handler:        handler,
+		statusCode:     http.StatusOK,
+	}
+}
+
+// WriteHeader records the status code before writing it to the underlying http.ResponseWriter
+func (r *webhookRequestRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *webhookRequestRecorder) setRequest(group, kind, operation string) {
+	r.group = group
+	r.kind = kind
+	r.operation = operation
+}
+
+func (r *webhookRequestRecorder) setAdmissionResponse(resp *admission.AdmissionResponse) {
+	if resp.Allowed {
+		return
+	}
+	r.rejected = true
+	if resp.Result != nil {
+		r.reason = string(resp.Result.Reason)
+	}
+}
+
+func (r *webhookRequestRecorder) setConversionResult(result *metav1.Status) {
+	if result.Status != metav1.StatusFailure {
+		return
+	}
+	r.rejected = true
+	r.reason = string(result.Reason)
+	if r.reason == "" {
+		r.reason = http.StatusText(int(result.Code))
+	}
+}
+
+func (r *webhookRequestRecorder) finish() {
+	if r.statusCode >= http.StatusBadRequest {
+		// The request failed before it could be admitted or converted
+		r.rejected = true
+		r.reason = http.StatusText(r.statusCode)
+	}
+	r.server.requestDurations.WithLabelValues(r.handler, r.kind, r.operation).Observe(time.Since(r.start).Seconds())
+	r.server.totalRequests.WithLabelValues(r.handler, r.kind, r.operation, strconv.Itoa(r.statusCode)).Inc()
+	if r.rejected {
+		r.server.totalRejections.WithLabelValues(r.handler, r.kind, r.operation, r.reason).Inc()
+	}
+	r.span.SetAttributes(
+		attribute.Int("http.response.status_code", r.statusCode),
+		attribute.String("kubernetes.group", r.group),
+		attribute.String("kubernetes.kind", r.kind),
+		attribute.String("kubernetes.operation", r.operation),
+		attribute.Bool("webhook.rejected", r.rejected),
+	)
+	if r.rejected {
+		r.span.SetAttributes(attribute.String("webhook.rejection_reason", r.reason))
+	}
+	if r.statusCode >= http.StatusBadRequest {
+		r.span.SetStatus(codes.Error, r.reason)
 	}
 }
@@ -4,11 +4,17 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana-app-sdk/resource"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -56,6 +62,24 @@ func TestNewWebhookServer(t *testing.T) {
 		}, srv.tlsConfig)
 	})
 
+	t.Run("TLS disabled", func(t *testing.T) {
+		srv, err := NewWebhookServer(WebhookServerConfig{
+			Port:       1234,
+			DisableTLS: true,
+		})
+		assert.Nil(t, err)
+		assert.True(t, srv.disableTLS)
+	})
+
+	t.Run("unix socket without port", func(t *testing.T) {
+		srv, err := NewWebhookServer(WebhookServerConfig{
+			UnixSocketPath: "/tmp/webhook.sock",
+			DisableTLS:     true,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, "/tmp/webhook.sock", srv.unixSocketPath)
+	})
+
 	t.Run("set controllers", func(t *testing.T) {
 		defVal := &testValidatingAdmissionController{}
 		defMut := &testMutatingAdmissionController{}
@@ -226,6 +250,50 @@ var admissionRequestBytesNoDefaults = []byte(`{
 	}
 }`)
 
+var admissionRequestBytesV1 = []byte(`{
+	"apiVersion": "admission.k8s.io/v1",
+	"kind": "AdmissionReview",
+	"request": {
+		"uid": "foo",
+		"kind": {
+			"group": "foo",
+			"version": "v1",
+			"kind": "bar"
+		},
+		"requestKind": {
+			"group": "foo",
+			"version": "v1",
+			"kind": "bar"
+		},
+		"object": ` + admissionRequestObjectBytes.String() + `
+	}
+}`)
+var admissionRequestBytesV1beta1NoRequestKind = []byte(`{
+	"apiVersion": "admission.k8s.io/v1beta1",
+	"kind": "AdmissionReview",
+	"request": {
+		"uid": "foo",
+		"kind": {
+			"group": "foo",
+			"version": "v1",
+			"kind": "bar"
+		},
+		"object": ` + admissionRequestObjectBytes.String() + `
+	}
+}`)
+var admissionRequestBytesUnknownVersion = []byte(`{
+	"apiVersion": "admission.k8s.io/v2",
+	"kind": "AdmissionReview",
+	"request": {
+		"uid": "foo",
+		"requestKind": {
+			"group": "foo",
+			"version": "v1",
+			"kind": "bar"
+		}
+	}
+}`)
+
 func TestWebhookServer_HandleMutateHTTP(t *testing.T) {
 	tests := []struct {
 		name               string
@@ -260,8 +328,8 @@ func TestWebhookServer_HandleMutateHTTP(t *testing.T) {
 			serverConfig:       WebhookServerConfig{},
 			reqMethod:          http.MethodPost,
 			payload:            admissionRequestBytes,
-			expectedResponse:   []byte(`no mutating admission controller defined for group 'foo' and kind 'bar'`),
-			expectedStatusCode: http.StatusInternalServerError,
+			expectedResponse:   []byte(`{"response":{"uid":"foo","allowed":false,"status":{"metadata":{},"status":"Failure","message":"no mutating admission controller defined for group 'foo' and kind 'bar'","reason":"InternalError","code":500}}}`),
+			expectedStatusCode: http.StatusOK,
 		},
 		{
 			name: "use default",
@@ -346,6 +414,23 @@ func TestWebhookServer_HandleMutateHTTP(t *testing.T) {
 			expectedResponse:   []byte(`{"response":{"uid":"foo","allowed":true,"patchType":"JSONPatch","patch":"W3sib3AiOiJyZXBsYWNlIiwicGF0aCI6Ii9zcGVjL3N0cmluZ0ZpZWxkIiwidmFsdWUiOiJmb29iYXIifV0="}}`),
 			expectedStatusCode: http.StatusOK,
 		},
+		{
+			name: "warnings and audit annotations",
+			serverConfig: WebhookServerConfig{
+				DefaultMutatingController: &testMutatingAdmissionController{
+					MutateFunc: func(ctx context.Context, request *resource.AdmissionRequest) (*resource.MutatingResponse, error) {
+						return &resource.MutatingResponse{
+							Warnings:         []string{"spec.foo is deprecated"},
+							AuditAnnotations: map[string]string{"foo": "bar"},
+						}, nil
+					},
+				},
+			},
+			reqMethod:          http.MethodPost,
+			payload:            admissionRequestBytes,
+			expectedResponse:   []byte(`{"response":{"uid":"foo","allowed":true,"auditAnnotations":{"foo":"bar"},"warnings":["spec.foo is deprecated"]}}`),
+			expectedStatusCode: http.StatusOK,
+		},
 		{
 			name:               "malformed request body: bad JSON",
 			reqMethod:          http.MethodPost,
@@ -412,8 +497,8 @@ func TestWebhookServer_HandleValidateHTTP(t *testing.T) {
 			serverConfig:       WebhookServerConfig{},
 			reqMethod:          http.MethodPost,
 			payload:            admissionRequestBytes,
-			expectedResponse:   []byte(`no validating admission controller defined for group 'foo' and kind 'bar'`),
-			expectedStatusCode: http.StatusInternalServerError,
+			expectedResponse:   []byte(`{"response":{"uid":"foo","allowed":false,"status":{"metadata":{},"status":"Failure","message":"no validating admission controller defined for group 'foo' and kind 'bar'","reason":"InternalError","code":500}}}`),
+			expectedStatusCode: http.StatusOK,
 		},
 		{
 			name: "use default",
@@ -453,6 +538,72 @@ func TestWebhookServer_HandleValidateHTTP(t *testing.T) {
 			expectedResponse:   []byte(`{"response":{"uid":"foo","allowed":true}}`),
 			expectedStatusCode: http.StatusOK,
 		},
+		{
+			name: "admission.k8s.io/v1 request",
+			serverConfig: WebhookServerConfig{
+				DefaultValidatingController: &testValidatingAdmissionController{},
+			},
+			reqMethod:          http.MethodPost,
+			payload:            admissionRequestBytesV1,
+			expectedResponse:   []byte(`{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","response":{"uid":"foo","allowed":true}}`),
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "admission.k8s.io/v1beta1 request without requestKind",
+			serverConfig: WebhookServerConfig{
+				DefaultValidatingController: &testValidatingAdmissionController{
+					ValidateFunc: func(ctx context.Context, request *resource.AdmissionRequest) error {
+						return NewAdmissionError(fmt.Errorf("%s.%s", request.Kind, request.Group), http.StatusConflict, "err_reason")
+					},
+				},
+			},
+			reqMethod:          http.MethodPost,
+			payload:            admissionRequestBytesV1beta1NoRequestKind,
+			expectedResponse:   []byte(`{"apiVersion":"admission.k8s.io/v1beta1","kind":"AdmissionReview","response":{"uid":"foo","allowed":false,"status":{"metadata":{},"status":"Failure","message":"bar.foo","reason":"err_reason","code":409}}}`),
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "unsupported AdmissionReview version",
+			serverConfig: WebhookServerConfig{
+				DefaultValidatingController: &testValidatingAdmissionController{},
+			},
+			reqMethod:          http.MethodPost,
+			payload:            admissionRequestBytesUnknownVersion,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "detailed error",
+			serverConfig: WebhookServerConfig{
+				DefaultValidatingController: &testValidatingAdmissionController{
+					ValidateFunc: func(ctx context.Context, request *resource.AdmissionRequest) error {
+						return NewAdmissionError(fmt.Errorf("invalid spec"), http.StatusUnprocessableEntity, string(metav1.StatusReasonInvalid)).
+							WithCauses(NewFieldCause("spec.foo", metav1.CauseTypeFieldValueInvalid, "must not be empty")).
+							WithWarnings("spec.bar is deprecated").
+							WithAuditAnnotation("reason", "empty-foo")
+					},
+				},
+			},
+			reqMethod:          http.MethodPost,
+			payload:            admissionRequestBytes,
+			expectedResponse:   []byte(`{"response":{"uid":"foo","allowed":false,"status":{"metadata":{},"status":"Failure","message":"invalid spec","reason":"Invalid","details":{"causes":[{"reason":"FieldValueInvalid","message":"must not be empty","field":"spec.foo"}]},"code":422},"auditAnnotations":{"reason":"empty-foo"},"warnings":["spec.bar is deprecated"]}}`),
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "allowed with warnings",
+			serverConfig: WebhookServerConfig{
+				DefaultValidatingController: &testValidatingAdmissionController{
+					ValidateFunc: func(ctx context.Context, request *resource.AdmissionRequest) error {
+						resource.AddAdmissionWarnings(ctx, "spec.bar is deprecated")
+						resource.AddAdmissionAuditAnnotations(ctx, map[string]string{"deprecated": "spec.bar"})
+						return nil
+					},
+				},
+			},
+			reqMethod:          http.MethodPost,
+			payload:            admissionRequestBytes,
+			expectedResponse:   []byte(`{"response":{"uid":"foo","allowed":true,"auditAnnotations":{"deprecated":"spec.bar"},"warnings":["spec.bar is deprecated"]}}`),
+			expectedStatusCode: http.StatusOK,
+		},
 		{
 			name:               "malformed request body: bad JSON",
 			reqMethod:          http.MethodPost,
@@ -485,6 +636,223 @@ func TestWebhookServer_HandleValidateHTTP(t *testing.T) {
 	}
 }
 
+func TestWebhookServer_HandleConvertHTTP(t *testing.T) {
+	converter := &testConverter{
+		ConvertFunc: func(obj RawKind, targetAPIVersion string) ([]byte, error) {
+			return []byte(fmt.Sprintf(`{"kind":"%s","apiVersion":"%s"}`, obj.Kind, targetAPIVersion)), nil
+		},
+	}
+	tests := []struct {
+		name               string
+		reqMethod          string
+		payload            []byte
+		expectedResponse   []byte
+		expectedStatusCode int
+	}{
+		{
+			name:               "HTTP GET",
+			reqMethod:          http.MethodGet,
+			expectedStatusCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:               "malformed request body: bad JSON",
+			reqMethod:          http.MethodPost,
+			payload:            []byte("{"),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "unsupported ConversionReview version",
+			reqMethod:          http.MethodPost,
+			payload:            []byte(`{"apiVersion":"apiextensions.k8s.io/v2","kind":"ConversionReview","request":{"uid":"foo","desiredAPIVersion":"foo/v2","objects":[]}}`),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "no request",
+			reqMethod:          http.MethodPost,
+			payload:            []byte(`{"apiVersion":"apiextensions.k8s.io/v1","kind":"ConversionReview"}`),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "apiextensions.k8s.io/v1 request",
+			reqMethod:          http.MethodPost,
+			payload:            []byte(`{"apiVersion":"apiextensions.k8s.io/v1","kind":"ConversionReview","request":{"uid":"foo","desiredAPIVersion":"foo/v2","objects":[{"kind":"bar","apiVersion":"foo/v1"}]}}`),
+			expectedResponse:   []byte(`{"apiVersion":"apiextensions.k8s.io/v1","kind":"ConversionReview","request":{"uid":"foo","desiredAPIVersion":"foo/v2","objects":[{"kind":"bar","apiVersion":"foo/v1"}]},"response":{"uid":"foo","convertedObjects":[{"kind":"bar","apiVersion":"foo/v2"}],"result":{"metadata":{},"status":"Success","code":200}}}`),
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "apiextensions.k8s.io/v1beta1 request",
+			reqMethod:          http.MethodPost,
+			payload:            []byte(`{"apiVersion":"apiextensions.k8s.io/v1beta1","kind":"ConversionReview","request":{"uid":"foo","desiredAPIVersion":"foo/v2","objects":[{"kind":"bar","apiVersion":"foo/v1"}]}}`),
+			expectedResponse:   []byte(`{"apiVersion":"apiextensions.k8s.io/v1beta1","kind":"ConversionReview","request":{"uid":"foo","desiredAPIVersion":"foo/v2","objects":[{"kind":"bar","apiVersion":"foo/v1"}]},"response":{"uid":"foo","convertedObjects":[{"kind":"bar","apiVersion":"foo/v2"}],"result":{"metadata":{},"status":"Success","code":200}}}`),
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv, err := NewWebhookServer(WebhookServerConfig{
+				Port: 8443,
+				TLSConfig: TLSConfig{
+					CertPath: "foo",
+					KeyPath:  "bar",
+				},
+				KindConverters: map[metav1.GroupKind]Converter{
+					{Group: "foo", Kind: "bar"}: converter,
+				},
+			})
+			require.Nil(t, err)
+			req := httptest.NewRequest(test.reqMethod, "http://localhost/convert", bytes.NewBuffer(test.payload))
+			resp := httptest.NewRecorder()
+			srv.HandleConvertHTTP(resp, req)
+
+			if test.expectedStatusCode == http.StatusOK {
+				assert.JSONEq(t, string(test.expectedResponse), resp.Body.String())
+			} else {
+				assert.Equal(t, test.expectedResponse, resp.Body.Bytes())
+			}
+			assert.Equal(t, test.expectedStatusCode, resp.Code)
+		})
+	}
+}
+
+func TestWebhookServer_Handler(t *testing.T) {
+	srv, err := NewWebhookServer(WebhookServerConfig{
+		Port:       8443,
+		DisableTLS: true,
+		DefaultValidatingController: &testValidatingAdmissionController{
+			ValidateFunc: func(ctx context.Context, request *resource.AdmissionRequest) error {
+				return nil
+			},
+		},
+	})
+	require.Nil(t, err)
+	server := httptest.NewServer(srv.Handler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/validate", "application/json", bytes.NewReader(admissionRequestBytesV1))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","response":{"uid":"foo","allowed":true}}`, string(body))
+
+	resp2, err := http.Get(server.URL + "/foo")
+	require.Nil(t, err)
+	defer resp2.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp2.StatusCode)
+}
+
+func TestWebhookServer_Run(t *testing.T) {
+	t.Run("unix socket", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "webhook.sock")
+		srv, err := NewWebhookServer(WebhookServerConfig{
+			UnixSocketPath:              socketPath,
+			DisableTLS:                  true,
+			DefaultValidatingController: &testValidatingAdmissionController{},
+		})
+		require.Nil(t, err)
+		closeCh := make(chan struct{})
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- srv.Run(closeCh)
+		}()
+
+		client := &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+				},
+			},
+		}
+		var resp *http.Response
+		assert.Eventually(t, func() bool {
+			resp, err = client.Post("http://webhook/validate", "application/json", bytes.NewReader(admissionRequestBytesV1))
+			return err == nil
+		}, time.Second*5, time.Millisecond*50)
+		require.NotNil(t, resp)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		close(closeCh)
+		select {
+		case <-errCh:
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out waiting for Run to return")
+		}
+	})
+
+	t.Run("stale unix socket", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "webhook.sock")
+		stale, err := net.Listen("unix", socketPath)
+		require.NoError(t, err)
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		require.NoError(t, stale.Close())
+		srv, err := NewWebhookServer(WebhookServerConfig{
+			UnixSocketPath: socketPath,
+			DisableTLS:     true,
+		})
+		require.Nil(t, err)
+		listener, err := srv.listen()
+		require.NoError(t, err)
+		assert.NoError(t, listener.Close())
+	})
+
+	t.Run("unix socket path is not a socket", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "webhook.sock")
+		require.NoError(t, os.WriteFile(socketPath, []byte("data"), 0600))
+		srv, err := NewWebhookServer(WebhookServerConfig{
+			UnixSocketPath: socketPath,
+			DisableTLS:     true,
+		})
+		require.Nil(t, err)
+		_, err = srv.listen()
+		assert.Error(t, err)
+		contents, err := os.ReadFile(socketPath)
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(contents))
+	})
+}
+
+func TestWebhookServer_PrometheusCollectors(t *testing.T) {
+	srv, err := NewWebhookServer(WebhookServerConfig{
+		Port: 8443,
+		TLSConfig: TLSConfig{
+			CertPath: "foo",
+			KeyPath:  "bar",
+		},
+		DefaultValidatingController: &testValidatingAdmissionController{
+			ValidateFunc: func(ctx context.Context, request *resource.AdmissionRequest) error {
+				return NewAdmissionError(fmt.Errorf("I AM ERROR"), http.StatusConflict, "err_reason")
+			},
+		},
+	})
+	require.Nil(t, err)
+	assert.Len(t, srv.PrometheusCollectors(), 3)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/validate", bytes.NewBuffer(admissionRequestBytesV1))
+	srv.HandleValidateHTTP(httptest.NewRecorder(), req)
+	req = httptest.NewRequest(http.MethodGet, "http://localhost/validate", nil)
+	srv.HandleValidateHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(srv.totalRequests.WithLabelValues("validate", "bar", "", "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(srv.totalRequests.WithLabelValues("validate", "", "", "405")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(srv.totalRejections.WithLabelValues("validate", "bar", "", "err_reason")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(srv.totalRejections.WithLabelValues("validate", "", "", "Method Not Allowed")))
+	assert.Equal(t, 2, testutil.CollectAndCount(srv.requestDurations))
+}
+
+type testConverter struct {
+	ConvertFunc func(RawKind, string) ([]byte, error)
+}
+
+func (tc *testConverter) Convert(obj RawKind, targetAPIVersion string) ([]byte, error) {
+	if tc.ConvertFunc != nil {
+		return tc.ConvertFunc(obj, targetAPIVersion)
+	}
+	return obj.Raw, nil
+}
+
 type testValidatingAdmissionController struct {
 	ValidateFunc func(context.Context, *resource.AdmissionRequest) error
 }
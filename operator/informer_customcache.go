@@ -205,6 +205,32 @@ func (c *CustomCacheInformer) errorHandler(ctx context.Context, err error) {
 // NewListerWatcher returns a cache.ListerWatcher for the provided resource.Schema that uses the given ListWatchClient.
 // The List and Watch requests will always use the provided namespace and labelFilters.
 func NewListerWatcher(client ListWatchClient, sch resource.Schema, filterOptions ListWatchOptions) cache.ListerWatcher {
+	return newListerWatcher(sch, filterOptions,
+		func(ctx context.Context, namespace string, options resource.ListOptions) (runtime.Object, error) {
+			resp := resource.UntypedList{}
+			if err := client.ListInto(ctx, namespace, options, &resp); err != nil {
+				return nil, err
+			}
+			return &resp, nil
+		}, client.Watch)
+}
+
+// NewMetadataListerWatcher returns a cache.ListerWatcher for the provided resource.Schema which uses the given
+// MetadataListWatchClient to only list and watch the metadata of resources.
+// The List and Watch requests will always use the provided namespace and labelFilters.
+func NewMetadataListerWatcher(client MetadataListWatchClient, sch resource.Schema, filterOptions ListWatchOptions) cache.ListerWatcher {
+	return newListerWatcher(sch, filterOptions,
+		func(ctx context.Context, namespace string, options resource.ListOptions) (runtime.Object, error) {
+			return client.ListMetadata(ctx, namespace, options)
+		}, client.WatchMetadata)
+}
+
+//nolint:funlen
+func newListerWatcher(
+	sch resource.Schema, filterOptions ListWatchOptions,
+	listFunc func(context.Context, string, resource.ListOptions) (runtime.Object, error),
+	watchFunc func(context.Context, string, resource.WatchOptions) (resource.WatchResponse, error),
+) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
 			ctx, span := GetTracer().Start(context.Background(), "informer-list")
@@ -215,18 +241,13 @@ func NewListerWatcher(client ListWatchClient, sch resource.Schema, filterOptions
 				attribute.String("kind.version", sch.Version()),
 				attribute.String("namespace", filterOptions.Namespace),
 			)
-			resp := resource.UntypedList{}
-			err := client.ListInto(ctx, filterOptions.Namespace, resource.ListOptions{
+			return listFunc(ctx, filterOptions.Namespace, resource.ListOptions{
 				LabelFilters:    filterOptions.LabelFilters,
 				FieldSelectors:  filterOptions.FieldSelectors,
 				Continue:        options.Continue,
 				Limit:           int(options.Limit),
 				ResourceVersion: options.ResourceVersion,
-			}, &resp)
-			if err != nil {
-				return nil, err
-			}
-			return &resp, nil
+			})
 		},
 		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
 			ctx, span := GetTracer().Start(context.Background(), "informer-watch")
@@ -250,7 +271,7 @@ func NewListerWatcher(client ListWatchClient, sch resource.Schema, filterOptions
 				timeout := time.Duration(*options.TimeoutSeconds) * time.Second
 				ctx, cancel = context.WithTimeout(ctx, timeout)
 			}*/
-			watchResp, err := client.Watch(ctx, filterOptions.Namespace, opts)
+			watchResp, err := watchFunc(ctx, filterOptions.Namespace, opts)
 			if err != nil {
 				return nil, err
 			}
@@ -30,6 +30,11 @@ type KubernetesBasedInformerOptions struct {
 	// This is distinct from a full resync, as no information is fetched from the API server.
 	// An empty value will disable cache resyncs.
 	CacheResyncInterval time.Duration
+	// MetadataOnly will make the informer only list and watch the metadata of resources (as PartialObjectMetadata),
+	// rather than the full objects. Objects in the cache and delivered to event handlers will have only their metadata set.
+	// This is useful for kinds with large objects where only metadata (such as labels or finalizers) is needed.
+	// The client provided to NewKubernetesBasedInformer must implement MetadataListWatchClient if MetadataOnly is true.
+	MetadataOnly bool
 }
 
 // NewKubernetesBasedInformer creates a new KubernetesBasedInformer for the provided kind and options,
@@ -39,12 +44,20 @@ func NewKubernetesBasedInformer(sch resource.Kind, client ListWatchClient, optio
 	if client == nil {
 		return nil, fmt.Errorf("client cannot be nil")
 	}
+	lw := NewListerWatcher(client, sch, options.ListWatchOptions)
+	if options.MetadataOnly {
+		mdClient, ok := client.(MetadataListWatchClient)
+		if !ok {
+			return nil, fmt.Errorf("client must implement MetadataListWatchClient when MetadataOnly is true")
+		}
+		lw = NewMetadataListerWatcher(mdClient, sch, options.ListWatchOptions)
+	}
 
 	return &KubernetesBasedInformer{
 		schema:       sch,
 		ErrorHandler: DefaultErrorHandler,
 		SharedIndexInformer: cache.NewSharedIndexInformer(
-			NewListerWatcher(client, sch, options.ListWatchOptions),
+			lw,
 			nil,
 			options.CacheResyncInterval,
 			cache.Indexers{
@@ -148,6 +161,12 @@ type ListWatchClient interface {
 	Watch(ctx context.Context, namespace string, options resource.WatchOptions) (resource.WatchResponse, error)
 }
 
+// MetadataListWatchClient describes a client which can do metadata-only List and Watch requests, such as k8s.Client.
+type MetadataListWatchClient interface {
+	ListMetadata(ctx context.Context, namespace string, options resource.ListOptions) (resource.ListObject, error)
+	WatchMetadata(ctx context.Context, namespace string, options resource.WatchOptions) (resource.WatchResponse, error)
+}
+
 type watchWrapper struct {
 	watch resource.WatchResponse
 	ch    chan watch.Event
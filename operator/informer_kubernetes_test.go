@@ -0,0 +1,87 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+func TestNewKubernetesBasedInformer_MetadataOnly(t *testing.T) {
+	t.Run("client without metadata support", func(t *testing.T) {
+		_, err := NewKubernetesBasedInformer(untypedKind, &mockListWatchClient{}, KubernetesBasedInformerOptions{
+			MetadataOnly: true,
+		})
+		assert.EqualError(t, err, "client must implement MetadataListWatchClient when MetadataOnly is true")
+	})
+
+	t.Run("uses metadata requests", func(t *testing.T) {
+		client := &mockMetadataListWatchClient{
+			ListMetadataFunc: func(ctx context.Context, namespace string, options resource.ListOptions) (resource.ListObject, error) {
+				assert.Equal(t, "ns", namespace)
+				assert.Equal(t, []string{"a=b"}, options.LabelFilters)
+				return &resource.UntypedList{
+					ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+					Items:    []resource.Object{&resource.UntypedObject{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "foo"}}},
+				}, nil
+			},
+			WatchMetadataFunc: func(ctx context.Context, namespace string, options resource.WatchOptions) (resource.WatchResponse, error) {
+				assert.Equal(t, "ns", namespace)
+				return &mockWatchResponse{ch: make(chan resource.WatchEvent)}, nil
+			},
+		}
+		inf, err := NewKubernetesBasedInformer(untypedKind, client, KubernetesBasedInformerOptions{
+			ListWatchOptions: ListWatchOptions{Namespace: "ns", LabelFilters: []string{"a=b"}},
+			MetadataOnly:     true,
+		})
+		require.Nil(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go inf.Run(ctx)
+		require.True(t, cache.WaitForCacheSync(ctx.Done(), inf.SharedIndexInformer.HasSynced))
+		assert.Equal(t, []string{"ns/foo"}, inf.SharedIndexInformer.GetStore().ListKeys())
+		assert.Equal(t, 0, client.listIntoCalls)
+	})
+}
+
+type mockListWatchClient struct {
+	listIntoCalls int
+}
+
+func (c *mockListWatchClient) ListInto(context.Context, string, resource.ListOptions, resource.ListObject) error {
+	c.listIntoCalls++
+	return nil
+}
+
+func (*mockListWatchClient) Watch(context.Context, string, resource.WatchOptions) (resource.WatchResponse, error) {
+	return nil, nil
+}
+
+type mockMetadataListWatchClient struct {
+	mockListWatchClient
+	ListMetadataFunc  func(context.Context, string, resource.ListOptions) (resource.ListObject, error)
+	WatchMetadataFunc func(context.Context, string, resource.WatchOptions) (resource.WatchResponse, error)
+}
+
+func (c *mockMetadataListWatchClient) ListMetadata(ctx context.Context, namespace string, options resource.ListOptions) (resource.ListObject, error) {
+	return c.ListMetadataFunc(ctx, namespace, options)
+}
+
+func (c *mockMetadataListWatchClient) WatchMetadata(ctx context.Context, namespace string, options resource.WatchOptions) (resource.WatchResponse, error) {
+	return c.WatchMetadataFunc(ctx, namespace, options)
+}
+
+type mockWatchResponse struct {
+	ch chan resource.WatchEvent
+}
+
+func (w *mockWatchResponse) Stop() {}
+
+func (w *mockWatchResponse) WatchEvents() <-chan resource.WatchEvent {
+	return w.ch
+}
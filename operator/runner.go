@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"reflect"
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -47,13 +48,16 @@ func NewRunner(cfg RunnerConfig) (*Runner, error) {
 		config: cfg,
 	}
 
-	if cfg.WebhookConfig.TLSConfig.CertPath != "" {
+	if cfg.WebhookConfig.TLSConfig.CertPath != "" || cfg.WebhookConfig.DisableTLS {
 		ws, err := k8s.NewWebhookServer(k8s.WebhookServerConfig{
 			Port: cfg.WebhookConfig.Port,
 			TLSConfig: k8s.TLSConfig{
 				CertPath: cfg.WebhookConfig.TLSConfig.CertPath,
 				KeyPath:  cfg.WebhookConfig.TLSConfig.KeyPath,
 			},
+			DisableTLS:     cfg.WebhookConfig.DisableTLS,
+			UnixSocketPath: cfg.WebhookConfig.UnixSocketPath,
+			MetricsConfig:  metrics.DefaultConfig(cfg.MetricsConfig.Namespace),
 		})
 		if err != nil {
 			return nil, err
@@ -92,6 +96,11 @@ type RunnerWebhookConfig struct {
 	Port int
 	// TLSConfig is the TLS Cert and Key to use for the HTTPS endpoints exposed for webhooks
 	TLSConfig k8s.TLSConfig
+	// DisableTLS exposes the webhooks over plain HTTP instead of HTTPS, in which case TLSConfig is not required.
+	// This should only be used when TLS is terminated by a proxy in front of the webhook server, or for local development.
+	DisableTLS bool
+	// UnixSocketPath, if non-empty, exposes the webhooks on a unix socket at the provided path instead of on Port
+	UnixSocketPath string
 }
 
 type capabilities struct {
@@ -175,7 +184,7 @@ func (s *Runner) Run(ctx context.Context, provider app.Provider) error {
 	}
 	if anyWebhooks {
 		if s.webhookServer == nil {
-			return errors.New("app has capabilities that require webhooks, but webhook server was not provided TLS config")
+			return errors.New("app has capabilities that require webhooks, but webhook server was not provided TLS config and DisableTLS is false")
 		}
 		for _, kind := range a.ManagedKinds() {
 			c, ok := vkCapabilities[fmt.Sprintf("%s/%s", kind.Kind(), kind.Version())]
@@ -321,6 +330,11 @@ func (s *webhookServerRunner) Run(ctx context.Context) error {
 	return s.runner.Run(ctx)
 }
 
+// PrometheusCollectors implements metrics.Provider by returning the prometheus collectors used by the webhook server
+func (s *webhookServerRunner) PrometheusCollectors() []prometheus.Collector {
+	return s.server.PrometheusCollectors()
+}
+
 func (s *webhookServerRunner) AddValidatingAdmissionController(controller resource.ValidatingAdmissionController, kind resource.Kind) {
 	s.server.AddValidatingAdmissionController(controller, kind)
 }
@@ -351,8 +365,27 @@ func (m *metricsServerRunner) Run(ctx context.Context) error {
 	return m.runner.Run(ctx)
 }
 
+// RegisterCollectors registers the provided collectors with the exporter.
+// Registering the same collector more than once (such as those shared between multiple Run calls, like the webhook server's)
+// is ignored, but a different collector with the same descriptors as a registered one is still an error.
 func (m *metricsServerRunner) RegisterCollectors(collectors ...prometheus.Collector) error {
-	return m.server.RegisterCollectors(collectors...)
+	for _, collector := range collectors {
+		err := m.server.RegisterCollectors(collector)
+		are := prometheus.AlreadyRegisteredError{}
+		if err != nil && !(errors.As(err, &are) && sameCollector(are.ExistingCollector, collector)) {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameCollector returns true if a and b are the same collector.
+// Collectors with dynamic types which can't be compared are never the same.
+func sameCollector(a, b prometheus.Collector) bool {
+	if a == nil || b == nil || !reflect.TypeOf(a).Comparable() || !reflect.TypeOf(b).Comparable() {
+		return false
+	}
+	return a == b
 }
 
 type k8sRunner interface {
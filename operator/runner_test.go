@@ -0,0 +1,27 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana-app-sdk/metrics"
+)
+
+func TestMetricsServerRunner_RegisterCollectors(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	runner := newMetricsServerRunner(&metrics.Exporter{
+		Registerer: registry,
+		Gatherer:   registry,
+	})
+	collector := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total"})
+
+	require.NoError(t, runner.RegisterCollectors(collector))
+	// Registering the same collector again is ignored
+	assert.NoError(t, runner.RegisterCollectors(collector))
+	// Registering a different collector with the same descriptors is an error
+	err := runner.RegisterCollectors(prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total"}))
+	assert.ErrorAs(t, err, &prometheus.AlreadyRegisteredError{})
+}
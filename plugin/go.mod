@@ -8,6 +8,7 @@ retract (
 )
 
 require (
+	github.com/getkin/kin-openapi v0.131.0
 	github.com/grafana/grafana-app-sdk v0.34.0
 	github.com/grafana/grafana-app-sdk/logging v0.34.0
 	github.com/grafana/grafana-plugin-sdk-go v0.274.0
@@ -33,7 +34,6 @@ require (
 	github.com/emicklei/go-restful/v3 v3.11.0 // indirect
 	github.com/fatih/color v1.15.0 // indirect
 	github.com/fxamacker/cbor/v2 v2.7.0 // indirect
-	github.com/getkin/kin-openapi v0.131.0 // indirect
 	github.com/go-logr/logr v1.4.2 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/go-openapi/jsonpointer v0.21.0 // indirect
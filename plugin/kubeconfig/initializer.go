@@ -15,10 +15,9 @@ type Initializer[T any] func(cfg NamespacedConfig) (T, error)
 //
 // Only one value is cached at any given time. Passing a different Config will replace cached value,
 // i.e. with calls like `ini(config1), ini(config2), ini(config1)` the third call will not be cached.
+// To cache a value for each of multiple configs, use a Pool instead.
 func CachingInitializer[T any](ini Initializer[T]) Initializer[T] {
 	var (
-		// TODO: we could cache multiple values with a map and CRC of the config.
-		// Is it worth it?
 		hasCached bool
 		cachedCfg NamespacedConfig
 		cachedVal T
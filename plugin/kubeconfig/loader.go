@@ -173,8 +173,15 @@ func (c *CachingLoader) Load(config, namespace string, dst *NamespacedConfig) er
 }
 
 // LoadFromSettings loads the config from the AppInstanceSettings.
+// If the underlying loader is a RawConfigLoader, its RawConfig method is used to read the settings,
+// otherwise LoadRawConfig is used.
 func (c *CachingLoader) LoadFromSettings(set backend.AppInstanceSettings, dst *NamespacedConfig) error {
-	cf, ns, err := LoadRawConfig(set.DecryptedSecureJSONData)
+	raw := LoadRawConfig
+	if l, ok := c.load.(RawConfigLoader); ok {
+		raw = l.RawConfig
+	}
+
+	cf, ns, err := raw(set.DecryptedSecureJSONData)
 	if err != nil {
 		return err
 	}
@@ -487,7 +487,7 @@ func (f *FakeLoader) Load(config, namespace string, dst *kubeconfig.NamespacedCo
 }
 
 func (f *FakeLoader) LoadFromSettings(set backend.AppInstanceSettings, dst *kubeconfig.NamespacedConfig) error {
-	if f.loadFn != nil {
+	if f.loadSetFn != nil {
 		return f.loadSetFn(set, dst)
 	}
 
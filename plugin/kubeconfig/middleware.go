@@ -13,7 +13,8 @@ import (
 // LoadingMiddleware returns a new middleware that can be used on a router for
 // automatically extracting, loading and storing a Config into the context.
 // The middleware does not return an error if Config cannot be loaded.
-// The middleware uses a default loader.
+// The middleware uses a default loader, which only loads serialized kubeconfigs (see NewCachingLoader).
+// To allow other config sources, use LoadingMiddlewareWithLoader with a SourceLoader.
 func LoadingMiddleware() router.MiddlewareFunc {
 	return LoadingMiddlewareWithLoader(NewCachingLoader())
 }
@@ -41,7 +42,8 @@ func LoadingMiddlewareWithLoader(loader ConfigLoader) router.MiddlewareFunc {
 // MustLoadMiddleware returns a new middleware that can be used on a router for
 // automatically extracting, loading and storing a Config into the context.
 // The middleware will return an error response if it fails to extract and load a Config from request.
-// The middleware uses a default loader.
+// The middleware uses a default loader, which only loads serialized kubeconfigs (see NewCachingLoader).
+// To allow other config sources, use MustLoadMiddlewareWithLoader with a SourceLoader.
 func MustLoadMiddleware() router.MiddlewareFunc {
 	return MustLoadMiddlewareWithLoader(NewCachingLoader())
 }
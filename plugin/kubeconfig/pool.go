@@ -0,0 +1,361 @@
+package kubeconfig
+
+import (
+	"container/list"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/rest"
+
+	"github.com/grafana/grafana-app-sdk/metrics"
+)
+
+// DefaultPoolMaxSize is the maximum number of values in a Pool if PoolConfig.MaxSize is not set.
+const DefaultPoolMaxSize = 100
+
+// EvictionReason is the reason a value was removed from a Pool.
+type EvictionReason string
+
+const (
+	// EvictionReasonCapacity is the reason for a value removed because the pool was full
+	// and it was the least recently used value, or because it was replaced by the value
+	// for another config with the same CRC32.
+	EvictionReasonCapacity EvictionReason = "capacity"
+	// EvictionReasonExpired is the reason for a value removed because it was older than the pool's TTL.
+	EvictionReasonExpired EvictionReason = "expired"
+	// EvictionReasonRemoved is the reason for a value removed with Pool.Remove or Pool.Close.
+	EvictionReasonRemoved EvictionReason = "removed"
+)
+
+// EvictFunc is called with each value removed from a Pool, and the NamespacedConfig it was initialized with.
+// The value may still be in use by callers which got it from the pool before it was evicted.
+type EvictFunc[T any] func(cfg NamespacedConfig, val T, reason EvictionReason)
+
+// PoolConfig is the configuration for a Pool.
+type PoolConfig[T any] struct {
+	// Name is the name of the pool, used as the "pool" label of its metrics.
+	Name string
+	// MaxSize is the maximum number of values in the pool.
+	// When a value is added to a full pool, the least recently used value is evicted.
+	// Defaults to DefaultPoolMaxSize.
+	MaxSize int
+	// TTL is how long values are kept after they are initialized, after which they are evicted and re-initialized
+	// the next time they are requested. If 0, values don't expire.
+	TTL time.Duration
+	// OnEvict is called with each value evicted from the pool. If nil, evicted values are dropped.
+	// As the pool doesn't track callers using the values it returns, a value may still be in use when it's evicted,
+	// so OnEvict must not release resources which callers may still need (such as by closing the value),
+	// unless callers only use values for a short time after getting them, and MaxSize and TTL leave them enough time.
+	OnEvict EvictFunc[T]
+	// Metrics is metrics configuration
+	Metrics metrics.Config
+}
+
+// Pool is a bounded, expiring cache of values initialized from NamespacedConfig,
+// such as per-tenant clients or stores in multi-org Grafana deployments.
+// Unlike CachingInitializer, which caches a single value, a Pool caches a value for each config,
+// evicting the least recently used value when it's full, and values which are older than its TTL.
+// Values are looked up by the config's CRC32, and are only returned for configs with the same namespace and rest config
+// as the config the value was initialized with, so a value is never returned for another config with the same CRC32.
+// Evicted values are not closed (see PoolConfig.OnEvict).
+//
+// Pool is safe for concurrent use. Concurrent calls to Get with the same config only initialize one value.
+type Pool[T any] struct {
+	ini     Initializer[T]
+	maxSize int
+	ttl     time.Duration
+	onEvict EvictFunc[T]
+	entries map[uint32]*list.Element
+	order   *list.List
+	calls   map[uint32]*poolCall[T]
+	hits    uint64
+	misses  uint64
+	mux     sync.Mutex
+
+	sizeGauge      *prometheus.GaugeVec
+	totalRequests  *prometheus.CounterVec
+	totalEvictions *prometheus.CounterVec
+	name           string
+}
+
+type poolEntry[T any] struct {
+	cfg     NamespacedConfig
+	val     T
+	expires time.Time
+}
+
+// poolCall is an in-flight initialization of a value
+type poolCall[T any] struct {
+	cfg  NamespacedConfig
+	done chan struct{}
+	val  T
+	err  error
+}
+
+type poolEviction[T any] struct {
+	entry  *poolEntry[T]
+	reason EvictionReason
+}
+
+// NewPool returns a new, empty Pool which initializes values with ini.
+func NewPool[T any](ini Initializer[T], cfg PoolConfig[T]) *Pool[T] {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = DefaultPoolMaxSize
+	}
+	if cfg.OnEvict == nil {
+		cfg.OnEvict = func(NamespacedConfig, T, EvictionReason) {}
+	}
+	return &Pool[T]{
+		ini:     ini,
+		maxSize: cfg.MaxSize,
+		ttl:     cfg.TTL,
+		onEvict: cfg.OnEvict,
+		entries: make(map[uint32]*list.Element),
+		order:   list.New(),
+		calls:   make(map[uint32]*poolCall[T]),
+		name:    cfg.Name,
+		sizeGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: cfg.Metrics.Namespace,
+			Subsystem: "kubeconfig_pool",
+			Name:      "size",
+			Help:      "Number of values in the pool",
+		}, []string{"pool"}),
+		totalRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Metrics.Namespace,
+			Subsystem: "kubeconfig_pool",
+			Name:      "requests_total",
+			Help:      "Number of requests for values from the pool, by whether the value was already in the pool (hit) or not (miss)",
+		}, []string{"pool", "result"}),
+		totalEvictions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Metrics.Namespace,
+			Subsystem: "kubeconfig_pool",
+			Name:      "evictions_total",
+			Help:      "Number of values evicted from the pool, by reason",
+		}, []string{"pool", "reason"}),
+	}
+}
+
+// Get returns the value for cfg, initializing it if it isn't in the pool (or has expired).
+// Errors from initialization are returned, and the value is not added to the pool.
+// Get can be used as an Initializer.
+func (p *Pool[T]) Get(cfg NamespacedConfig) (T, error) {
+	p.mux.Lock()
+	if elem, ok := p.entries[cfg.CRC32]; ok {
+		entry := elem.Value.(*poolEntry[T])
+		if sameConfig(entry.cfg, cfg) && (p.ttl <= 0 || time.Now().Before(entry.expires)) {
+			p.order.MoveToFront(elem)
+			p.hits++
+			p.mux.Unlock()
+			p.totalRequests.WithLabelValues(p.name, "hit").Inc()
+			return entry.val, nil
+		}
+	}
+	p.misses++
+	p.totalRequests.WithLabelValues(p.name, "miss").Inc()
+	existing, ok := p.calls[cfg.CRC32]
+	if ok && sameConfig(existing.cfg, cfg) {
+		// Another caller is already initializing the value
+		p.mux.Unlock()
+		<-existing.done
+		return existing.val, existing.err
+	}
+	// If another config with the same CRC32 is being initialized, this value is initialized separately
+	call := &poolCall[T]{cfg: cfg, done: make(chan struct{})}
+	if !ok {
+		p.calls[cfg.CRC32] = call
+	}
+	p.mux.Unlock()
+
+	call.val, call.err = p.ini(cfg)
+
+	p.mux.Lock()
+	if p.calls[cfg.CRC32] == call {
+		delete(p.calls, cfg.CRC32)
+	}
+	evicted := make([]poolEviction[T], 0)
+	if call.err == nil {
+		evicted = p.add(cfg, call.val)
+	}
+	p.updateSize()
+	p.mux.Unlock()
+	close(call.done)
+
+	p.evict(evicted)
+	return call.val, call.err
+}
+
+// Initializer returns the pool's Get method as an Initializer.
+func (p *Pool[T]) Initializer() Initializer[T] {
+	return p.Get
+}
+
+// Remove evicts the value for cfg from the pool, if there is one.
+func (p *Pool[T]) Remove(cfg NamespacedConfig) {
+	p.mux.Lock()
+	evicted := make([]poolEviction[T], 0, 1)
+	if elem, ok := p.entries[cfg.CRC32]; ok && sameConfig(elem.Value.(*poolEntry[T]).cfg, cfg) {
+		evicted = append(evicted, p.remove(elem, EvictionReasonRemoved))
+	}
+	p.updateSize()
+	p.mux.Unlock()
+	p.evict(evicted)
+}
+
+// Prune evicts all expired values from the pool.
+// Expired values are otherwise only evicted when they are requested, or when the pool is full.
+func (p *Pool[T]) Prune() {
+	p.mux.Lock()
+	evicted := p.prune()
+	p.updateSize()
+	p.mux.Unlock()
+	p.evict(evicted)
+}
+
+// Close evicts all values from the pool.
+func (p *Pool[T]) Close() {
+	p.mux.Lock()
+	evicted := make([]poolEviction[T], 0, p.order.Len())
+	for p.order.Len() > 0 {
+		evicted = append(evicted, p.remove(p.order.Back(), EvictionReasonRemoved))
+	}
+	p.updateSize()
+	p.mux.Unlock()
+	p.evict(evicted)
+}
+
+// Len returns the number of values in the pool, including expired values which have not yet been evicted.
+func (p *Pool[T]) Len() int {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.order.Len()
+}
+
+// PoolStats are statistics of a Pool.
+type PoolStats struct {
+	// Size is the number of values in the pool.
+	Size int
+	// Hits is the number of calls to Get which returned a value already in the pool.
+	Hits uint64
+	// Misses is the number of calls to Get which initialized a value (or waited for another call to initialize it).
+	Misses uint64
+}
+
+// HitRate returns the ratio of hits to all calls to Get, or 0 if Get hasn't been called.
+func (s PoolStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Stats returns the current statistics of the pool.
+func (p *Pool[T]) Stats() PoolStats {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return PoolStats{
+		Size:   p.order.Len(),
+		Hits:   p.hits,
+		Misses: p.misses,
+	}
+}
+
+// PrometheusCollectors returns the prometheus metric collectors used by the Pool to allow for registration
+func (p *Pool[T]) PrometheusCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		p.sizeGauge, p.totalRequests, p.totalEvictions,
+	}
+}
+
+// add adds the value to the pool, replacing any existing value for the config's CRC32,
+// and returns the entries which must be evicted. It must be called with the lock held.
+func (p *Pool[T]) add(cfg NamespacedConfig, val T) []poolEviction[T] {
+	evicted := make([]poolEviction[T], 0)
+	if elem, ok := p.entries[cfg.CRC32]; ok {
+		// The existing value is either expired, or for another config with the same CRC32,
+		// in which case only one of them can be kept
+		reason := EvictionReasonExpired
+		if !sameConfig(elem.Value.(*poolEntry[T]).cfg, cfg) {
+			reason = EvictionReasonCapacity
+		}
+		evicted = append(evicted, p.remove(elem, reason))
+	}
+	entry := &poolEntry[T]{
+		cfg: cfg,
+		val: val,
+	}
+	if p.ttl > 0 {
+		entry.expires = time.Now().Add(p.ttl)
+	}
+	p.entries[cfg.CRC32] = p.order.PushFront(entry)
+	if p.order.Len() > p.maxSize {
+		// Prefer evicting expired values over the least recently used ones
+		evicted = append(evicted, p.prune()...)
+	}
+	for p.order.Len() > p.maxSize {
+		evicted = append(evicted, p.remove(p.order.Back(), EvictionReasonCapacity))
+	}
+	return evicted
+}
+
+// prune removes all expired entries. It must be called with the lock held.
+func (p *Pool[T]) prune() []poolEviction[T] {
+	evicted := make([]poolEviction[T], 0)
+	if p.ttl <= 0 {
+		return evicted
+	}
+	now := time.Now()
+	for elem := p.order.Front(); elem != nil; {
+		next := elem.Next()
+		if !now.Before(elem.Value.(*poolEntry[T]).expires) {
+			evicted = append(evicted, p.remove(elem, EvictionReasonExpired))
+		}
+		elem = next
+	}
+	return evicted
+}
+
+// remove removes the entry from the pool. It must be called with the lock held.
+func (p *Pool[T]) remove(elem *list.Element, reason EvictionReason) poolEviction[T] {
+	entry := p.order.Remove(elem).(*poolEntry[T])
+	delete(p.entries, entry.cfg.CRC32)
+	return poolEviction[T]{
+		entry:  entry,
+		reason: reason,
+	}
+}
+
+// updateSize updates the size metric. It must be called with the lock held.
+func (p *Pool[T]) updateSize() {
+	p.sizeGauge.WithLabelValues(p.name).Set(float64(p.order.Len()))
+}
+
+// evict calls the eviction callback for each evicted entry. It must be called without the lock held,
+// so callbacks which take a long time (such as closing connections) don't block the pool.
+func (p *Pool[T]) evict(evicted []poolEviction[T]) {
+	for _, e := range evicted {
+		p.totalEvictions.WithLabelValues(p.name, string(e.reason)).Inc()
+		p.onEvict(e.entry.cfg, e.entry.val, e.reason)
+	}
+}
+
+// sameConfig returns true if a and b are the same config.
+// Configs with different values are never the same, even if their CRC32 is.
+// Function fields of the rest config (such as WrapTransport) are ignored, as they can't be compared,
+// and aren't part of the serialized config the CRC32 is computed from.
+func sameConfig(a, b NamespacedConfig) bool {
+	return a.CRC32 == b.CRC32 && a.Namespace == b.Namespace &&
+		reflect.DeepEqual(withoutFuncs(a.RestConfig), withoutFuncs(b.RestConfig))
+}
+
+// withoutFuncs returns a copy of cfg with all function fields set to nil
+func withoutFuncs(cfg rest.Config) rest.Config {
+	v := reflect.ValueOf(&cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if field := v.Field(i); field.Kind() == reflect.Func && field.CanSet() {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+	return cfg
+}
@@ -0,0 +1,225 @@
+package kubeconfig_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana-app-sdk/metrics"
+	"github.com/grafana/grafana-app-sdk/plugin/kubeconfig"
+)
+
+type poolValue struct {
+	ns     string
+	closed bool
+}
+
+func (v *poolValue) Close() error {
+	v.closed = true
+	return nil
+}
+
+func TestPool(t *testing.T) {
+	cfg := func(crc uint32) kubeconfig.NamespacedConfig {
+		return kubeconfig.NamespacedConfig{CRC32: crc, Namespace: "ns"}
+	}
+
+	t.Run("should evict least recently used values when full", func(t *testing.T) {
+		var numCalls int
+		evicted := make([]uint32, 0)
+		pool := kubeconfig.NewPool(func(cfg kubeconfig.NamespacedConfig) (*poolValue, error) {
+			numCalls++
+			return &poolValue{ns: cfg.Namespace}, nil
+		}, kubeconfig.PoolConfig[*poolValue]{
+			MaxSize: 2,
+			OnEvict: func(cfg kubeconfig.NamespacedConfig, _ *poolValue, reason kubeconfig.EvictionReason) {
+				assert.Equal(t, kubeconfig.EvictionReasonCapacity, reason)
+				evicted = append(evicted, cfg.CRC32)
+			},
+		})
+
+		first, err := pool.Get(cfg(1))
+		require.NoError(t, err)
+		_, _ = pool.Get(cfg(2))
+		again, _ := pool.Get(cfg(1))
+		assert.Same(t, first, again)
+		_, _ = pool.Get(cfg(3))
+
+		assert.Equal(t, 3, numCalls)
+		assert.Equal(t, []uint32{2}, evicted)
+		assert.Equal(t, 2, pool.Len())
+		assert.Equal(t, kubeconfig.PoolStats{Size: 2, Hits: 1, Misses: 3}, pool.Stats())
+		assert.Equal(t, 0.25, pool.Stats().HitRate())
+	})
+
+	t.Run("should re-initialize expired values", func(t *testing.T) {
+		var numCalls int
+		pool := kubeconfig.NewPool(func(cfg kubeconfig.NamespacedConfig) (*poolValue, error) {
+			numCalls++
+			return &poolValue{ns: cfg.Namespace}, nil
+		}, kubeconfig.PoolConfig[*poolValue]{
+			TTL: 10 * time.Millisecond,
+			OnEvict: func(_ kubeconfig.NamespacedConfig, val *poolValue, reason kubeconfig.EvictionReason) {
+				assert.Equal(t, kubeconfig.EvictionReasonExpired, reason)
+				_ = val.Close()
+			},
+		})
+
+		first, _ := pool.Get(cfg(1))
+		_, _ = pool.Get(cfg(1))
+		assert.Equal(t, 1, numCalls)
+
+		time.Sleep(20 * time.Millisecond)
+		second, _ := pool.Get(cfg(1))
+		assert.Equal(t, 2, numCalls)
+		assert.NotSame(t, first, second)
+		assert.True(t, first.closed)
+		assert.False(t, second.closed)
+
+		_, _ = pool.Get(cfg(2))
+		time.Sleep(20 * time.Millisecond)
+		pool.Prune()
+		assert.Equal(t, 0, pool.Len())
+		assert.True(t, second.closed)
+	})
+
+	t.Run("should not cache errors", func(t *testing.T) {
+		var numCalls int
+		pool := kubeconfig.NewPool(func(cfg kubeconfig.NamespacedConfig) (*poolValue, error) {
+			numCalls++
+			return nil, errors.New("failed")
+		}, kubeconfig.PoolConfig[*poolValue]{})
+
+		_, err := pool.Get(cfg(1))
+		assert.Error(t, err)
+		_, err = pool.Get(cfg(1))
+		assert.Error(t, err)
+		assert.Equal(t, 2, numCalls)
+		assert.Equal(t, 0, pool.Len())
+	})
+
+	t.Run("should only initialize once for concurrent calls", func(t *testing.T) {
+		var numCalls atomic.Int32
+		release := make(chan struct{})
+		pool := kubeconfig.NewPool(func(cfg kubeconfig.NamespacedConfig) (*poolValue, error) {
+			numCalls.Add(1)
+			<-release
+			return &poolValue{ns: cfg.Namespace}, nil
+		}, kubeconfig.PoolConfig[*poolValue]{})
+
+		wg := sync.WaitGroup{}
+		results := make([]*poolValue, 5)
+		for i := range results {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], _ = pool.Get(cfg(1))
+			}()
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), numCalls.Load())
+		for _, res := range results {
+			assert.Same(t, results[0], res)
+		}
+	})
+
+	t.Run("should evict all values", func(t *testing.T) {
+		pool := kubeconfig.NewPool(func(cfg kubeconfig.NamespacedConfig) (*poolValue, error) {
+			return &poolValue{ns: cfg.Namespace}, nil
+		}, kubeconfig.PoolConfig[*poolValue]{
+			OnEvict: func(_ kubeconfig.NamespacedConfig, val *poolValue, reason kubeconfig.EvictionReason) {
+				assert.Equal(t, kubeconfig.EvictionReasonRemoved, reason)
+				_ = val.Close()
+			},
+		})
+
+		a, _ := pool.Get(cfg(1))
+		b, _ := pool.Get(cfg(2))
+		pool.Remove(cfg(1))
+		assert.True(t, a.closed)
+		assert.False(t, b.closed)
+		pool.Close()
+		assert.True(t, b.closed)
+		assert.Equal(t, 0, pool.Len())
+	})
+
+	t.Run("should not close evicted values by default", func(t *testing.T) {
+		pool := kubeconfig.NewPool(func(cfg kubeconfig.NamespacedConfig) (*poolValue, error) {
+			return &poolValue{ns: cfg.Namespace}, nil
+		}, kubeconfig.PoolConfig[*poolValue]{MaxSize: 1})
+
+		a, _ := pool.Get(cfg(1))
+		_, _ = pool.Get(cfg(2))
+		pool.Close()
+		assert.False(t, a.closed)
+		assert.Equal(t, 0, pool.Len())
+	})
+
+	t.Run("should not return values for other configs with the same CRC32", func(t *testing.T) {
+		pool := kubeconfig.NewPool(func(cfg kubeconfig.NamespacedConfig) (*poolValue, error) {
+			return &poolValue{ns: cfg.Namespace}, nil
+		}, kubeconfig.PoolConfig[*poolValue]{})
+		other := kubeconfig.NamespacedConfig{CRC32: 1, Namespace: "other"}
+		otherHost := cfg(1)
+		otherHost.RestConfig.Host = "https://other"
+
+		a, _ := pool.Get(cfg(1))
+		b, _ := pool.Get(other)
+		assert.NotSame(t, a, b)
+		assert.Equal(t, "other", b.ns)
+		c, _ := pool.Get(otherHost)
+		assert.NotSame(t, b, c)
+		assert.Equal(t, "ns", c.ns)
+		again, _ := pool.Get(otherHost)
+		assert.Same(t, c, again)
+
+		// Removing a config with the same CRC32 doesn't remove the value
+		pool.Remove(cfg(1))
+		assert.Equal(t, 1, pool.Len())
+		pool.Remove(otherHost)
+		assert.Equal(t, 0, pool.Len())
+	})
+
+	t.Run("should record metrics", func(t *testing.T) {
+		pool := kubeconfig.NewPool(func(cfg kubeconfig.NamespacedConfig) (*poolValue, error) {
+			return &poolValue{ns: cfg.Namespace}, nil
+		}, kubeconfig.PoolConfig[*poolValue]{
+			Name:    "stores",
+			MaxSize: 1,
+			Metrics: metrics.DefaultConfig("test"),
+		})
+
+		_, _ = pool.Get(cfg(1))
+		_, _ = pool.Get(cfg(1))
+		_, _ = pool.Get(cfg(2))
+
+		collectors := pool.PrometheusCollectors()
+		require.Len(t, collectors, 3)
+		assert.NoError(t, testutil.CollectAndCompare(collectors[0], strings.NewReader(`
+# HELP test_kubeconfig_pool_size Number of values in the pool
+# TYPE test_kubeconfig_pool_size gauge
+test_kubeconfig_pool_size{pool="stores"} 1
+`)))
+		assert.NoError(t, testutil.CollectAndCompare(collectors[1], strings.NewReader(`
+# HELP test_kubeconfig_pool_requests_total Number of requests for values from the pool, by whether the value was already in the pool (hit) or not (miss)
+# TYPE test_kubeconfig_pool_requests_total counter
+test_kubeconfig_pool_requests_total{pool="stores",result="hit"} 1
+test_kubeconfig_pool_requests_total{pool="stores",result="miss"} 2
+`)))
+		assert.NoError(t, testutil.CollectAndCompare(collectors[2], strings.NewReader(`
+# HELP test_kubeconfig_pool_evictions_total Number of values evicted from the pool, by reason
+# TYPE test_kubeconfig_pool_evictions_total counter
+test_kubeconfig_pool_evictions_total{pool="stores",reason="capacity"} 1
+`)))
+	})
+}
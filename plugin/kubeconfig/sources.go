@@ -0,0 +1,481 @@
+package kubeconfig
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/grafana/grafana-app-sdk/k8s"
+)
+
+const (
+	// KeyConfigSource is the key in secureJsonData used for looking up the source of the kubeconfig,
+	// which determines the loader used by SourceLoader. It is one of the ConfigSource* values,
+	// and defaults to ConfigSourceKubeconfig if missing.
+	KeyConfigSource = "kubeconfigsource"
+
+	// KeyHost is the key in secureJsonData used for looking up the API server host,
+	// for the token file and exec config sources.
+	KeyHost = "kubehost"
+
+	// KeyCAFile is the key in secureJsonData used for looking up the path of the API server's CA certificate file,
+	// for the token file and exec config sources.
+	KeyCAFile = "kubecafile"
+
+	// KeyTokenFile is the key in secureJsonData used for looking up the path of the bearer token file,
+	// for the token file config source.
+	KeyTokenFile = "kubetokenfile"
+
+	// KeyExecCommand is the key in secureJsonData used for looking up the command to run for credentials,
+	// for the exec config source.
+	KeyExecCommand = "kubeexeccommand"
+
+	// KeyExecArgs is the key in secureJsonData used for looking up the arguments of the exec command,
+	// as a JSON array of strings, for the exec config source.
+	KeyExecArgs = "kubeexecargs"
+
+	// KeyExecEnv is the key in secureJsonData used for looking up additional environment variables
+	// for the exec command, as a JSON object of strings, for the exec config source.
+	KeyExecEnv = "kubeexecenv"
+)
+
+const (
+	// ConfigSourceKubeconfig is the config source for a serialized kubeconfig (see Loader).
+	ConfigSourceKubeconfig = "kubeconfig"
+	// ConfigSourceTokenFile is the config source for a service account token file (see TokenFileLoader).
+	ConfigSourceTokenFile = "tokenfile"
+	// ConfigSourceInCluster is the config source for the in-cluster config (see InClusterLoader).
+	ConfigSourceInCluster = "incluster"
+	// ConfigSourceExec is the config source for exec-based credentials (see ExecLoader).
+	ConfigSourceExec = "exec"
+)
+
+var (
+	// ErrUnknownConfigSource is the error returned by SourceLoader
+	// when secureJsonData contains a config source without a loader.
+	ErrUnknownConfigSource = errors.New("unknown config source")
+)
+
+// RawConfigLoader is a ConfigLoader which uses its own format for serialized config values,
+// and loads them from secureJsonData with RawConfig instead of LoadRawConfig.
+// CachingLoader uses RawConfig when the loader it wraps implements it.
+type RawConfigLoader interface {
+	RawConfig(src map[string]string) (cfg string, ns string, err error)
+}
+
+// SourceLoader is a ConfigLoader which loads NamespacedConfig with a different ConfigLoader
+// depending on the config source (see KeyConfigSource) of each app instance.
+// Only the sources it has a loader for can be used, so the sources which aren't serialized kubeconfigs
+// must be explicitly allowed (see NewSourceLoader and NewCustomSourceLoader).
+//
+// The loader is safe for concurrent use.
+type SourceLoader struct {
+	loaders map[string]ConfigLoader
+}
+
+// NewSourceLoader returns a new SourceLoader with a CachingLoader for ConfigSourceKubeconfig,
+// and for each of the allowed ConfigSource* sources.
+// An error is returned if an allowed source isn't one of the ConfigSource* sources.
+//
+// Sources other than ConfigSourceKubeconfig give whoever can edit an app instance's secureJsonData access
+// to the plugin's host, such as running commands or reading files as the plugin (see ExecLoader and TokenFileLoader),
+// or using the pod's identity (see InClusterLoader), so only allow the sources which are required,
+// and only if app instance settings are editable exclusively by trusted users.
+func NewSourceLoader(allowed ...string) (*SourceLoader, error) {
+	loaders := map[string]ConfigLoader{
+		ConfigSourceKubeconfig: NewCachingLoader(),
+	}
+	for _, source := range allowed {
+		switch source {
+		case ConfigSourceKubeconfig:
+		case ConfigSourceTokenFile:
+			loaders[source] = NewCustomCachingLoader(NewTokenFileLoader())
+		case ConfigSourceInCluster:
+			loaders[source] = NewCustomCachingLoader(NewInClusterLoader())
+		case ConfigSourceExec:
+			loaders[source] = NewCustomCachingLoader(NewExecLoader())
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnknownConfigSource, source)
+		}
+	}
+	return NewCustomSourceLoader(loaders), nil
+}
+
+// NewCustomSourceLoader returns a new SourceLoader that uses loaders for loading configs, keyed by config source.
+// The keys of loaders are the allowed sources: the loader for ConfigSourceKubeconfig is used when no source is set,
+// and an app instance using a source without a loader will fail to load with ErrUnknownConfigSource.
+// See NewSourceLoader for the implications of allowing each source.
+func NewCustomSourceLoader(loaders map[string]ConfigLoader) *SourceLoader {
+	return &SourceLoader{
+		loaders: loaders,
+	}
+}
+
+// Load loads the NamespacedConfig into dst using the loader for ConfigSourceKubeconfig.
+// Load IS NOT guaranteed to clear dst - the caller is responsible for that.
+func (s *SourceLoader) Load(config, namespace string, dst *NamespacedConfig) error {
+	loader, err := s.loader(ConfigSourceKubeconfig)
+	if err != nil {
+		return err
+	}
+
+	return loader.Load(config, namespace, dst)
+}
+
+// LoadFromSettings loads the config from the AppInstanceSettings, using the loader for its config source.
+func (s *SourceLoader) LoadFromSettings(set backend.AppInstanceSettings, dst *NamespacedConfig) error {
+	source := strings.TrimSpace(set.DecryptedSecureJSONData[KeyConfigSource])
+	if source == "" {
+		source = ConfigSourceKubeconfig
+	}
+
+	loader, err := s.loader(source)
+	if err != nil {
+		return err
+	}
+
+	return loader.LoadFromSettings(set, dst)
+}
+
+func (s *SourceLoader) loader(source string) (ConfigLoader, error) {
+	loader, ok := s.loaders[source]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownConfigSource, source)
+	}
+
+	return loader, nil
+}
+
+// TokenFileConfig is the serialized config used by TokenFileLoader.
+type TokenFileConfig struct {
+	// Host is the URL of the API server.
+	Host string `json:"host"`
+	// CAFile is the path of the API server's CA certificate file. If empty, the system's root CAs are used.
+	CAFile string `json:"caFile,omitempty"`
+	// TokenFile is the path of the file containing the bearer token.
+	TokenFile string `json:"tokenFile"`
+}
+
+// TokenFileLoader is a ConfigLoader that loads NamespacedConfig which authenticate with a bearer token file,
+// such as a projected service account token.
+// The token file is periodically re-read by clients created from the config, so token rotation is handled transparently.
+//
+// Serialized config values are JSON-encoded TokenFileConfig values.
+// In secureJsonData, the values are read from KeyHost, KeyCAFile and KeyTokenFile.
+//
+// The token file is read by the plugin, and the token is sent to the host, so anyone who can set the config
+// can send the contents of any file readable by the plugin to a host of their choice.
+// Only use TokenFileLoader for configs set by users trusted with the plugin's file system,
+// and never with configs from app instance settings which other users can edit.
+type TokenFileLoader struct{}
+
+// NewTokenFileLoader returns a new TokenFileLoader.
+func NewTokenFileLoader() *TokenFileLoader {
+	return &TokenFileLoader{}
+}
+
+// Load loads the NamespacedConfig into dst.
+// An error will be returned upon any failures (e.g. missing or malformed data, or an unreadable token file).
+// Load IS NOT guaranteed to clear dst - the caller is responsible for that.
+func (*TokenFileLoader) Load(config, namespace string, dst *NamespacedConfig) error {
+	var cfg TokenFileConfig
+	if err := json.Unmarshal([]byte(config), &cfg); err != nil {
+		return err
+	}
+
+	if cfg.Host == "" || cfg.TokenFile == "" {
+		return ErrConfigMissing
+	}
+
+	if err := k8s.ValidateNamespace(namespace); err != nil {
+		return err
+	}
+
+	// Fail early if the token file can't be read, rather than on the first request.
+	if _, err := os.ReadFile(cfg.TokenFile); err != nil {
+		return fmt.Errorf("unable to read token file: %w", err)
+	}
+
+	dst.CRC32 = checksum(config, namespace)
+	dst.Namespace = namespace
+	dst.RestConfig = rest.Config{
+		Host:            cfg.Host,
+		APIPath:         "/apis",
+		BearerTokenFile: cfg.TokenFile,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAFile: cfg.CAFile,
+		},
+	}
+
+	return nil
+}
+
+// LoadFromSettings loads the config from the AppInstanceSettings.
+func (l *TokenFileLoader) LoadFromSettings(set backend.AppInstanceSettings, dst *NamespacedConfig) error {
+	return loadFromSettings(l, set, dst)
+}
+
+// RawConfig loads raw config data from decrypted secureJsonData.
+func (*TokenFileLoader) RawConfig(src map[string]string) (string, string, error) {
+	ns, err := loadRawNamespace(src)
+	if err != nil {
+		return "", "", err
+	}
+
+	return marshalRawConfig(TokenFileConfig{
+		Host:      src[KeyHost],
+		CAFile:    src[KeyCAFile],
+		TokenFile: src[KeyTokenFile],
+	}, ns)
+}
+
+// CRC32 returns the CRC 32 value of config and namespace strings.
+func (*TokenFileLoader) CRC32(config, namespace string) (uint32, error) {
+	return checksum(config, namespace), nil
+}
+
+// DefaultNamespaceFile is the file containing the namespace of the pod's service account,
+// used by InClusterLoader if no namespace is provided.
+const DefaultNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// InClusterLoader is a ConfigLoader that loads the in-cluster NamespacedConfig of the pod the plugin is running in,
+// using the pod's service account. The service account token is periodically re-read by clients created from the config,
+// so token rotation is handled transparently.
+//
+// Serialized config values are ignored. In secureJsonData, the namespace is read from KeyNamespace,
+// and if it's missing, the service account's namespace is used.
+//
+// Configs loaded by InClusterLoader have all the permissions of the pod's service account,
+// regardless of which app instance they are loaded for. Only use InClusterLoader if every app instance
+// is trusted with those permissions.
+type InClusterLoader struct {
+	// NamespaceFile is the file the service account's namespace is read from. Defaults to DefaultNamespaceFile.
+	NamespaceFile string
+}
+
+// NewInClusterLoader returns a new InClusterLoader.
+func NewInClusterLoader() *InClusterLoader {
+	return &InClusterLoader{
+		NamespaceFile: DefaultNamespaceFile,
+	}
+}
+
+// Load loads the NamespacedConfig into dst.
+// An error will be returned upon any failures (e.g. when not running in a cluster).
+// Load IS NOT guaranteed to clear dst - the caller is responsible for that.
+func (*InClusterLoader) Load(config, namespace string, dst *NamespacedConfig) error {
+	if err := k8s.ValidateNamespace(namespace); err != nil {
+		return err
+	}
+
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		return err
+	}
+
+	cfg.APIPath = "/apis"
+
+	dst.CRC32 = checksum(config, namespace)
+	dst.Namespace = namespace
+	dst.RestConfig = *cfg
+
+	return nil
+}
+
+// LoadFromSettings loads the config from the AppInstanceSettings.
+func (l *InClusterLoader) LoadFromSettings(set backend.AppInstanceSettings, dst *NamespacedConfig) error {
+	return loadFromSettings(l, set, dst)
+}
+
+// RawConfig loads raw config data from decrypted secureJsonData.
+// The config is always empty, and the namespace is the service account's namespace if it's missing.
+func (l *InClusterLoader) RawConfig(src map[string]string) (string, string, error) {
+	ns, err := loadRawNamespace(src)
+	if err == nil {
+		return "", ns, nil
+	}
+
+	file := l.NamespaceFile
+	if file == "" {
+		file = DefaultNamespaceFile
+	}
+
+	data, ferr := os.ReadFile(file)
+	if ferr != nil {
+		return "", "", fmt.Errorf("%w, and unable to read service account namespace: %w", err, ferr)
+	}
+
+	return "", strings.TrimSpace(string(data)), nil
+}
+
+// CRC32 returns the CRC 32 value of config and namespace strings.
+func (*InClusterLoader) CRC32(config, namespace string) (uint32, error) {
+	return checksum(config, namespace), nil
+}
+
+// ExecConfig is the serialized config used by ExecLoader.
+type ExecConfig struct {
+	// Host is the URL of the API server.
+	Host string `json:"host"`
+	// CAFile is the path of the API server's CA certificate file. If empty, the system's root CAs are used.
+	CAFile string `json:"caFile,omitempty"`
+	// Command is the command to run for credentials.
+	Command string `json:"command"`
+	// Args are the arguments of the command.
+	Args []string `json:"args,omitempty"`
+	// Env are additional environment variables for the command.
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// ExecLoader is a ConfigLoader that loads NamespacedConfig which get credentials by running a command,
+// using the client.authentication.k8s.io/v1 exec credential plugin protocol (as used by kubectl).
+// The command is run again by clients created from the config when the credentials expire.
+//
+// Serialized config values are JSON-encoded ExecConfig values.
+// In secureJsonData, the values are read from KeyHost, KeyCAFile, KeyExecCommand, KeyExecArgs and KeyExecEnv.
+//
+// The command is run by clients as the plugin's process, with the plugin's environment,
+// so anyone who can set the config can run arbitrary commands on the plugin's host.
+// Only use ExecLoader for configs set by users trusted with the plugin's host,
+// and never with configs from app instance settings which other users can edit.
+type ExecLoader struct{}
+
+// NewExecLoader returns a new ExecLoader.
+func NewExecLoader() *ExecLoader {
+	return &ExecLoader{}
+}
+
+// Load loads the NamespacedConfig into dst.
+// An error will be returned upon any failures (e.g. missing or malformed data).
+// Load IS NOT guaranteed to clear dst - the caller is responsible for that.
+func (*ExecLoader) Load(config, namespace string, dst *NamespacedConfig) error {
+	var cfg ExecConfig
+	if err := json.Unmarshal([]byte(config), &cfg); err != nil {
+		return err
+	}
+
+	if cfg.Host == "" || cfg.Command == "" {
+		return ErrConfigMissing
+	}
+
+	if err := k8s.ValidateNamespace(namespace); err != nil {
+		return err
+	}
+
+	env := make([]clientcmdapi.ExecEnvVar, 0, len(cfg.Env))
+	for name, value := range cfg.Env {
+		env = append(env, clientcmdapi.ExecEnvVar{Name: name, Value: value})
+	}
+	sort.Slice(env, func(i, j int) bool {
+		return env[i].Name < env[j].Name
+	})
+
+	dst.CRC32 = checksum(config, namespace)
+	dst.Namespace = namespace
+	dst.RestConfig = rest.Config{
+		Host:    cfg.Host,
+		APIPath: "/apis",
+		TLSClientConfig: rest.TLSClientConfig{
+			CAFile: cfg.CAFile,
+		},
+		ExecProvider: &clientcmdapi.ExecConfig{
+			Command:         cfg.Command,
+			Args:            cfg.Args,
+			Env:             env,
+			APIVersion:      "client.authentication.k8s.io/v1",
+			InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+		},
+	}
+
+	return nil
+}
+
+// LoadFromSettings loads the config from the AppInstanceSettings.
+func (l *ExecLoader) LoadFromSettings(set backend.AppInstanceSettings, dst *NamespacedConfig) error {
+	return loadFromSettings(l, set, dst)
+}
+
+// RawConfig loads raw config data from decrypted secureJsonData.
+func (*ExecLoader) RawConfig(src map[string]string) (string, string, error) {
+	ns, err := loadRawNamespace(src)
+	if err != nil {
+		return "", "", err
+	}
+
+	cfg := ExecConfig{
+		Host:    src[KeyHost],
+		CAFile:  src[KeyCAFile],
+		Command: src[KeyExecCommand],
+	}
+
+	if args := src[KeyExecArgs]; args != "" {
+		if err := json.Unmarshal([]byte(args), &cfg.Args); err != nil {
+			return "", "", fmt.Errorf("invalid %s value: %w", KeyExecArgs, err)
+		}
+	}
+
+	if env := src[KeyExecEnv]; env != "" {
+		if err := json.Unmarshal([]byte(env), &cfg.Env); err != nil {
+			return "", "", fmt.Errorf("invalid %s value: %w", KeyExecEnv, err)
+		}
+	}
+
+	return marshalRawConfig(cfg, ns)
+}
+
+// CRC32 returns the CRC 32 value of config and namespace strings.
+func (*ExecLoader) CRC32(config, namespace string) (uint32, error) {
+	return checksum(config, namespace), nil
+}
+
+type rawLoader interface {
+	ConfigLoader
+	RawConfigLoader
+}
+
+func loadFromSettings(loader rawLoader, set backend.AppInstanceSettings, dst *NamespacedConfig) error {
+	cf, ns, err := loader.RawConfig(set.DecryptedSecureJSONData)
+	if err != nil {
+		return err
+	}
+
+	return loader.Load(cf, ns, dst)
+}
+
+// loadRawNamespace loads the namespace from decrypted secureJsonData, decoding it in the same way as LoadRawConfig.
+func loadRawNamespace(src map[string]string) (string, error) {
+	nval, ok := src[KeyNamespace]
+	if !ok {
+		return "", ErrConfigMissing
+	}
+
+	if dec, err := base64.StdEncoding.DecodeString(nval); err == nil {
+		nval = strings.TrimSpace(string(dec))
+	}
+
+	return nval, nil
+}
+
+func marshalRawConfig(cfg any, ns string) (string, string, error) {
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(raw), ns, nil
+}
+
+// checksum returns the same CRC 32 value as Loader.CRC32.
+func checksum(config, namespace string) uint32 {
+	crc := crc32.Update(0, crc32.IEEETable, []byte(config))
+	return crc32.Update(crc, crc32.IEEETable, []byte(namespace))
+}
@@ -0,0 +1,214 @@
+package kubeconfig_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/grafana/grafana-app-sdk/plugin/kubeconfig"
+)
+
+func TestTokenFileLoader_LoadFromSettings(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("token"), 0600))
+
+	t.Run("when valid settings are passed", func(t *testing.T) {
+		var res kubeconfig.NamespacedConfig
+		require.NoError(t, kubeconfig.NewTokenFileLoader().LoadFromSettings(backend.AppInstanceSettings{
+			DecryptedSecureJSONData: map[string]string{
+				kubeconfig.KeyNamespace: "Y3VzdG9t",
+				kubeconfig.KeyHost:      "https://localhost:6443",
+				kubeconfig.KeyCAFile:    "/etc/ca.crt",
+				kubeconfig.KeyTokenFile: tokenFile,
+			},
+		}, &res))
+
+		assert.NotZero(t, res.CRC32)
+		assert.Equal(t, "custom", res.Namespace)
+		assert.Equal(t, rest.Config{
+			Host:            "https://localhost:6443",
+			APIPath:         "/apis",
+			BearerTokenFile: tokenFile,
+			TLSClientConfig: rest.TLSClientConfig{
+				CAFile: "/etc/ca.crt",
+			},
+		}, res.RestConfig)
+	})
+
+	t.Run("when the token file doesn't exist", func(t *testing.T) {
+		assert.Error(t, kubeconfig.NewTokenFileLoader().LoadFromSettings(backend.AppInstanceSettings{
+			DecryptedSecureJSONData: map[string]string{
+				kubeconfig.KeyNamespace: "custom",
+				kubeconfig.KeyHost:      "https://localhost:6443",
+				kubeconfig.KeyTokenFile: filepath.Join(t.TempDir(), "missing"),
+			},
+		}, &kubeconfig.NamespacedConfig{}))
+	})
+
+	t.Run("when the host is missing", func(t *testing.T) {
+		assert.ErrorIs(t, kubeconfig.NewTokenFileLoader().LoadFromSettings(backend.AppInstanceSettings{
+			DecryptedSecureJSONData: map[string]string{
+				kubeconfig.KeyNamespace: "custom",
+				kubeconfig.KeyTokenFile: tokenFile,
+			},
+		}, &kubeconfig.NamespacedConfig{}), kubeconfig.ErrConfigMissing)
+	})
+}
+
+func TestInClusterLoader_RawConfig(t *testing.T) {
+	nsFile := filepath.Join(t.TempDir(), "namespace")
+	require.NoError(t, os.WriteFile(nsFile, []byte("pod-namespace\n"), 0600))
+	l := &kubeconfig.InClusterLoader{NamespaceFile: nsFile}
+
+	_, ns, err := l.RawConfig(map[string]string{kubeconfig.KeyNamespace: "custom"})
+	require.NoError(t, err)
+	assert.Equal(t, "custom", ns)
+
+	_, ns, err = l.RawConfig(map[string]string{})
+	require.NoError(t, err)
+	assert.Equal(t, "pod-namespace", ns)
+
+	l.NamespaceFile = filepath.Join(t.TempDir(), "missing")
+	_, _, err = l.RawConfig(map[string]string{})
+	assert.ErrorIs(t, err, kubeconfig.ErrConfigMissing)
+}
+
+func TestInClusterLoader_Load(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "")
+	assert.ErrorIs(t, kubeconfig.NewInClusterLoader().Load("", "default", &kubeconfig.NamespacedConfig{}), rest.ErrNotInCluster)
+}
+
+func TestExecLoader_LoadFromSettings(t *testing.T) {
+	t.Run("when valid settings are passed", func(t *testing.T) {
+		var res kubeconfig.NamespacedConfig
+		require.NoError(t, kubeconfig.NewExecLoader().LoadFromSettings(backend.AppInstanceSettings{
+			DecryptedSecureJSONData: map[string]string{
+				kubeconfig.KeyNamespace:   "custom",
+				kubeconfig.KeyHost:        "https://localhost:6443",
+				kubeconfig.KeyExecCommand: "get-token",
+				kubeconfig.KeyExecArgs:    `["--cluster", "test"]`,
+				kubeconfig.KeyExecEnv:     `{"B": "2", "A": "1"}`,
+			},
+		}, &res))
+
+		assert.Equal(t, "custom", res.Namespace)
+		assert.Equal(t, "https://localhost:6443", res.RestConfig.Host)
+		assert.Equal(t, &clientcmdapi.ExecConfig{
+			Command:         "get-token",
+			Args:            []string{"--cluster", "test"},
+			Env:             []clientcmdapi.ExecEnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}},
+			APIVersion:      "client.authentication.k8s.io/v1",
+			InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+		}, res.RestConfig.ExecProvider)
+	})
+
+	t.Run("when args are malformed", func(t *testing.T) {
+		assert.Error(t, kubeconfig.NewExecLoader().LoadFromSettings(backend.AppInstanceSettings{
+			DecryptedSecureJSONData: map[string]string{
+				kubeconfig.KeyNamespace:   "custom",
+				kubeconfig.KeyHost:        "https://localhost:6443",
+				kubeconfig.KeyExecCommand: "get-token",
+				kubeconfig.KeyExecArgs:    "--cluster test",
+			},
+		}, &kubeconfig.NamespacedConfig{}))
+	})
+
+	t.Run("when the command is missing", func(t *testing.T) {
+		assert.ErrorIs(t, kubeconfig.NewExecLoader().LoadFromSettings(backend.AppInstanceSettings{
+			DecryptedSecureJSONData: map[string]string{
+				kubeconfig.KeyNamespace: "custom",
+				kubeconfig.KeyHost:      "https://localhost:6443",
+			},
+		}, &kubeconfig.NamespacedConfig{}), kubeconfig.ErrConfigMissing)
+	})
+}
+
+func TestSourceLoader_LoadFromSettings(t *testing.T) {
+	var loaded string
+	fake := func(source string) kubeconfig.ConfigLoader {
+		return &FakeLoader{
+			loadSetFn: func(_ backend.AppInstanceSettings, _ *kubeconfig.NamespacedConfig) error {
+				loaded = source
+				return nil
+			},
+		}
+	}
+	l := kubeconfig.NewCustomSourceLoader(map[string]kubeconfig.ConfigLoader{
+		kubeconfig.ConfigSourceKubeconfig: fake(kubeconfig.ConfigSourceKubeconfig),
+		kubeconfig.ConfigSourceExec:       fake(kubeconfig.ConfigSourceExec),
+	})
+
+	require.NoError(t, l.LoadFromSettings(backend.AppInstanceSettings{
+		DecryptedSecureJSONData: map[string]string{},
+	}, &kubeconfig.NamespacedConfig{}))
+	assert.Equal(t, kubeconfig.ConfigSourceKubeconfig, loaded)
+
+	require.NoError(t, l.LoadFromSettings(backend.AppInstanceSettings{
+		DecryptedSecureJSONData: map[string]string{kubeconfig.KeyConfigSource: kubeconfig.ConfigSourceExec},
+	}, &kubeconfig.NamespacedConfig{}))
+	assert.Equal(t, kubeconfig.ConfigSourceExec, loaded)
+
+	assert.ErrorIs(t, l.LoadFromSettings(backend.AppInstanceSettings{
+		DecryptedSecureJSONData: map[string]string{kubeconfig.KeyConfigSource: kubeconfig.ConfigSourceTokenFile},
+	}, &kubeconfig.NamespacedConfig{}), kubeconfig.ErrUnknownConfigSource)
+}
+
+func TestNewSourceLoader(t *testing.T) {
+	execSettings := backend.AppInstanceSettings{
+		DecryptedSecureJSONData: map[string]string{
+			kubeconfig.KeyConfigSource: kubeconfig.ConfigSourceExec,
+			kubeconfig.KeyNamespace:    "custom",
+			kubeconfig.KeyHost:         "https://localhost:6443",
+			kubeconfig.KeyExecCommand:  "get-token",
+		},
+	}
+
+	t.Run("only kubeconfig by default", func(t *testing.T) {
+		l, err := kubeconfig.NewSourceLoader()
+		require.NoError(t, err)
+		assert.ErrorIs(t, l.LoadFromSettings(execSettings, &kubeconfig.NamespacedConfig{}), kubeconfig.ErrUnknownConfigSource)
+	})
+
+	t.Run("allowed source", func(t *testing.T) {
+		l, err := kubeconfig.NewSourceLoader(kubeconfig.ConfigSourceExec)
+		require.NoError(t, err)
+		var res kubeconfig.NamespacedConfig
+		require.NoError(t, l.LoadFromSettings(execSettings, &res))
+		assert.Equal(t, "get-token", res.RestConfig.ExecProvider.Command)
+	})
+
+	t.Run("unknown source", func(t *testing.T) {
+		_, err := kubeconfig.NewSourceLoader("ftp")
+		assert.ErrorIs(t, err, kubeconfig.ErrUnknownConfigSource)
+	})
+}
+
+func TestCachingLoader_LoadFromSettings_RawConfigLoader(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("token"), 0600))
+	settings := backend.AppInstanceSettings{
+		DecryptedSecureJSONData: map[string]string{
+			kubeconfig.KeyNamespace: "custom",
+			kubeconfig.KeyHost:      "https://localhost:6443",
+			kubeconfig.KeyTokenFile: tokenFile,
+		},
+	}
+
+	var res kubeconfig.NamespacedConfig
+	require.NoError(t, kubeconfig.NewCustomCachingLoader(kubeconfig.NewTokenFileLoader()).LoadFromSettings(settings, &res))
+	assert.Equal(t, tokenFile, res.RestConfig.BearerTokenFile)
+
+	// The checksum matches the one computed by Loader for the same values
+	cfg, ns, err := kubeconfig.NewTokenFileLoader().RawConfig(settings.DecryptedSecureJSONData)
+	require.NoError(t, err)
+	crc, err := kubeconfig.NewLoader().CRC32(cfg, ns)
+	require.NoError(t, err)
+	assert.Equal(t, crc, res.CRC32)
+}
@@ -0,0 +1,139 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/grafana-app-sdk/logging"
+	"github.com/grafana/grafana-app-sdk/plugin"
+)
+
+// Grafana organization roles, in ascending order of privilege
+const (
+	RoleNone   = "None"
+	RoleViewer = "Viewer"
+	RoleEditor = "Editor"
+	RoleAdmin  = "Admin"
+)
+
+var roleRanks = map[string]int{
+	RoleNone:   0,
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// AccessCheckFunc checks whether the user may call a route, returning nil if they may, or an error describing why not.
+// The user is nil if the request has no user.
+// If the error is a plugin.Error, its code is used for the response, otherwise the response is a 403 Forbidden.
+type AccessCheckFunc func(ctx context.Context, user *backend.User, req *backend.CallResourceRequest) error
+
+// HasRole returns true if the user has role, or a role with more privileges than role (for example, an Admin has the Editor role).
+// A nil user, or a user with an unknown role, has no role.
+func HasRole(user *backend.User, role string) bool {
+	if user == nil {
+		return false
+	}
+	userRank, ok := roleRanks[user.Role]
+	if !ok {
+		return false
+	}
+	required, ok := roleRanks[role]
+	return ok && userRank >= required
+}
+
+// RequireRole sets the minimum Grafana role a user must have to call the route (see HasRole).
+// Requirements are always enforced by the router before the route's handler is called.
+// Use NewAuthorizationMiddleware to enforce them before other middlewares instead.
+func (h *RouteHandler) RequireRole(role string) *RouteHandler {
+	h.requiredRole = role
+	return h
+}
+
+// RequireAccess adds a check which a user must pass to call the route, in addition to any required role.
+// Checks are called in the order they are added, after the required role is checked.
+// Requirements are always enforced by the router before the route's handler is called.
+// Use NewAuthorizationMiddleware to enforce them before other middlewares instead.
+func (h *RouteHandler) RequireAccess(check AccessCheckFunc) *RouteHandler {
+	h.accessChecks = append(h.accessChecks, check)
+	return h
+}
+
+// NewAuthorizationMiddleware returns a MiddlewareFunc which enforces the requirements set on each route
+// with RequireRole and RequireAccess, using the user from the request's PluginContext.
+// If the user doesn't meet the requirements, the handler isn't called, and a JSONErrorResponse is sent
+// with a 403 Forbidden status code (or the code of a plugin.Error returned by an AccessCheckFunc).
+// Routes without requirements are not affected.
+//
+// The router enforces route requirements itself, immediately before calling the route's handler,
+// so this middleware is not required. It can be used to reject unauthorized requests before
+// the middlewares added after it are called (such as ones which read the request body).
+// Requirements checked by the middleware are not checked again by the router.
+func NewAuthorizationMiddleware() MiddlewareFunc {
+	return func(handler HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+			route, _ := ctx.Value(ctxMatchedRouteHandlerKey{}).(*RouteHandler)
+			if route == nil || !route.hasRequirements() {
+				handler(ctx, req, sender)
+				return
+			}
+			if err := authorize(ctx, route, req); err != nil {
+				sendAuthorizationError(ctx, sender, req, err)
+				return
+			}
+			handler(context.WithValue(ctx, ctxAuthorizedRouteKey{}, route), req, sender)
+		}
+	}
+}
+
+type ctxAuthorizedRouteKey struct{}
+
+// authorizedHandler wraps the handler of route so that the route's requirements are enforced
+// before it's called, unless they were already checked by the middleware returned by NewAuthorizationMiddleware.
+// Requirements are read when the request is handled, so they may be set after the route is registered.
+func authorizedHandler(route *RouteHandler, handler HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+		if authorized, _ := ctx.Value(ctxAuthorizedRouteKey{}).(*RouteHandler); authorized != route && route.hasRequirements() {
+			if err := authorize(ctx, route, req); err != nil {
+				sendAuthorizationError(ctx, sender, req, err)
+				return
+			}
+		}
+		handler(ctx, req, sender)
+	}
+}
+
+func (h *RouteHandler) hasRequirements() bool {
+	return h.requiredRole != "" || len(h.accessChecks) > 0
+}
+
+func authorize(ctx context.Context, route *RouteHandler, req *backend.CallResourceRequest) error {
+	user := req.PluginContext.User
+	if route.requiredRole != "" && !HasRole(user, route.requiredRole) {
+		return fmt.Errorf("user must have the %s role", route.requiredRole)
+	}
+	for _, check := range route.accessChecks {
+		if err := check(ctx, user, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sendAuthorizationError(ctx context.Context, sender backend.CallResourceResponseSender, req *backend.CallResourceRequest, err error) {
+	code := http.StatusForbidden
+	var cast plugin.Error
+	if errors.As(err, &cast) {
+		code = cast.Code
+	}
+	login := ""
+	if req.PluginContext.User != nil {
+		login = req.PluginContext.User.Login
+	}
+	logging.FromContext(ctx).Info("request not authorized", "request.user", login, "error", err.Error())
+	sendJSONError(ctx, sender, plugin.WrapError(code, err))
+}
@@ -0,0 +1,161 @@
+package router_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana-app-sdk/plugin"
+	"github.com/grafana/grafana-app-sdk/plugin/router"
+)
+
+func TestHasRole(t *testing.T) {
+	assert.True(t, router.HasRole(&backend.User{Role: router.RoleAdmin}, router.RoleEditor))
+	assert.True(t, router.HasRole(&backend.User{Role: router.RoleEditor}, router.RoleEditor))
+	assert.False(t, router.HasRole(&backend.User{Role: router.RoleViewer}, router.RoleEditor))
+	assert.False(t, router.HasRole(&backend.User{Role: "Unknown"}, router.RoleNone))
+	assert.False(t, router.HasRole(&backend.User{Role: router.RoleAdmin}, "Unknown"))
+	assert.False(t, router.HasRole(nil, router.RoleNone))
+}
+
+func TestAuthorizationMiddleware(t *testing.T) {
+	ok := func(_ context.Context, _ *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+		_ = sender.Send(&backend.CallResourceResponse{Status: http.StatusOK})
+	}
+	r := router.NewRouter()
+	r.Use(router.NewAuthorizationMiddleware())
+	r.Handle("/public", ok)
+	r.Handle("/editor", ok).RequireRole(router.RoleEditor)
+	r.Handle("/team", ok).RequireRole(router.RoleViewer).RequireAccess(
+		func(_ context.Context, user *backend.User, _ *backend.CallResourceRequest) error {
+			if user.Login == "error" {
+				return plugin.NewError(http.StatusInternalServerError, "check failed")
+			}
+			if user.Login != "alice" {
+				return errors.New("user is not a member of the team")
+			}
+			return nil
+		})
+
+	tests := []struct {
+		name       string
+		path       string
+		user       *backend.User
+		wantStatus int
+		wantErr    string
+	}{{
+		name:       "no requirements",
+		path:       "/public",
+		wantStatus: http.StatusOK,
+	}, {
+		name:       "required role",
+		path:       "/editor",
+		user:       &backend.User{Login: "bob", Role: router.RoleAdmin},
+		wantStatus: http.StatusOK,
+	}, {
+		name:       "missing role",
+		path:       "/editor",
+		user:       &backend.User{Login: "bob", Role: router.RoleViewer},
+		wantStatus: http.StatusForbidden,
+		wantErr:    "user must have the Editor role",
+	}, {
+		name:       "no user",
+		path:       "/editor",
+		wantStatus: http.StatusForbidden,
+		wantErr:    "user must have the Editor role",
+	}, {
+		name:       "access check passes",
+		path:       "/team",
+		user:       &backend.User{Login: "alice", Role: router.RoleViewer},
+		wantStatus: http.StatusOK,
+	}, {
+		name:       "access check fails",
+		path:       "/team",
+		user:       &backend.User{Login: "bob", Role: router.RoleViewer},
+		wantStatus: http.StatusForbidden,
+		wantErr:    "user is not a member of the team",
+	}, {
+		name:       "access check plugin error",
+		path:       "/team",
+		user:       &backend.User{Login: "error", Role: router.RoleViewer},
+		wantStatus: http.StatusInternalServerError,
+		wantErr:    "internal server error",
+	}, {
+		name:       "role checked before access checks",
+		path:       "/team",
+		user:       &backend.User{Login: "alice", Role: router.RoleNone},
+		wantStatus: http.StatusForbidden,
+		wantErr:    "user must have the Viewer role",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := callResource(t, r, &backend.CallResourceRequest{
+				Path:   test.path,
+				Method: http.MethodGet,
+				PluginContext: backend.PluginContext{
+					User: test.user,
+				},
+			})
+			assert.Equal(t, test.wantStatus, resp.Status)
+			if test.wantErr == "" {
+				return
+			}
+			assert.Equal(t, []string{router.ContentTypeJSON}, resp.Headers[router.HeaderContentType])
+			got := router.JSONErrorResponse{}
+			require.NoError(t, json.Unmarshal(resp.Body, &got))
+			assert.Equal(t, router.JSONErrorResponse{Code: test.wantStatus, Error: test.wantErr}, got)
+		})
+	}
+}
+
+func TestRouter_Authorization(t *testing.T) {
+	checks := 0
+	ok := func(_ context.Context, _ *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+		_ = sender.Send(&backend.CallResourceResponse{Status: http.StatusOK})
+	}
+	route := func(r *router.Router) {
+		r.Handle("/editor", ok).RequireRole(router.RoleEditor).RequireAccess(
+			func(_ context.Context, _ *backend.User, _ *backend.CallResourceRequest) error {
+				checks++
+				return nil
+			})
+	}
+	call := func(r *router.Router, role string) int {
+		return callResource(t, r, &backend.CallResourceRequest{
+			Path:          "/editor",
+			Method:        http.MethodGet,
+			PluginContext: backend.PluginContext{User: &backend.User{Login: "bob", Role: role}},
+		}).Status
+	}
+
+	t.Run("enforced without middleware", func(t *testing.T) {
+		checks = 0
+		r := router.NewRouter()
+		route(r)
+		sub := r.Subrouter("/sub")
+		route(&sub.Router)
+		assert.Equal(t, http.StatusForbidden, call(r, router.RoleViewer))
+		assert.Equal(t, http.StatusOK, call(r, router.RoleEditor))
+		assert.Equal(t, http.StatusForbidden, callResource(t, r, &backend.CallResourceRequest{
+			Path:   "/sub/editor",
+			Method: http.MethodGet,
+		}).Status)
+		assert.Equal(t, 1, checks)
+	})
+
+	t.Run("checked once with middleware", func(t *testing.T) {
+		checks = 0
+		r := router.NewRouter()
+		r.Use(router.NewAuthorizationMiddleware())
+		route(r)
+		assert.Equal(t, http.StatusForbidden, call(r, router.RoleViewer))
+		assert.Equal(t, http.StatusOK, call(r, router.RoleEditor))
+		assert.Equal(t, 1, checks)
+	})
+}
@@ -0,0 +1,395 @@
+package router
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/grafana-app-sdk/logging"
+)
+
+// CacheKey is the key of a cached response.
+type CacheKey struct {
+	// Group is the group of responses the response belongs to, which are invalidated together,
+	// such as all responses for the same path in an org.
+	Group string
+	// Variant identifies the response within its group, such as the user and query the response is for.
+	Variant string
+}
+
+// CachedResponse is a response stored in a ResponseCache.
+type CachedResponse struct {
+	Status  int
+	Headers map[string][]string
+	Body    []byte
+	ETag    string
+}
+
+// ResponseCache is a cache backend for the middleware returned by NewCachingMiddleware.
+// Implementations must be safe for concurrent use.
+type ResponseCache interface {
+	// Get returns the response for the key, and false if there is no unexpired response for the key.
+	Get(ctx context.Context, key CacheKey) (CachedResponse, bool, error)
+	// Set stores the response for the key, expiring after ttl.
+	Set(ctx context.Context, key CacheKey, response CachedResponse, ttl time.Duration) error
+	// Invalidate removes all responses in the group.
+	Invalidate(ctx context.Context, group string) error
+}
+
+// CachingMiddlewareConfig is the configuration for NewCachingMiddleware.
+type CachingMiddlewareConfig struct {
+	// TTL is how long responses are cached for.
+	TTL time.Duration
+	// Cache is the cache backend. If nil, an LRUResponseCache with a capacity of DefaultResponseCacheSize is used.
+	Cache ResponseCache
+	// KeyFunc returns the key for a GET request's response. If nil, DefaultCacheKey is used.
+	KeyFunc func(ctx context.Context, req *backend.CallResourceRequest) CacheKey
+	// InvalidateFunc returns the groups to invalidate after a successful (2xx) request with one of InvalidateMethods.
+	// If nil, DefaultInvalidateGroups is used.
+	InvalidateFunc func(ctx context.Context, req *backend.CallResourceRequest) []string
+	// InvalidateMethods are the methods of requests which invalidate cached responses when successful.
+	// If empty, DefaultInvalidateMethods is used.
+	InvalidateMethods []string
+}
+
+// DefaultInvalidateMethods are the methods of requests which invalidate cached responses
+// if CachingMiddlewareConfig.InvalidateMethods is empty.
+var DefaultInvalidateMethods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// DefaultResponseCacheSize is the capacity of the LRUResponseCache used by NewCachingMiddleware if no Cache is provided.
+const DefaultResponseCacheSize = 1000
+
+// DefaultCacheKey returns a CacheKey with the org ID and request path as the group,
+// and a hash of the user and the request's query string as the variant,
+// so responses are cached separately for each user, org, route and vars.
+// As the request's user has no ID, users are identified by their login, email, name and role,
+// so that a response is never shared between users, or reused after the user's role changes.
+func DefaultCacheKey(_ context.Context, req *backend.CallResourceRequest) CacheKey {
+	query := ""
+	if _, q, ok := strings.Cut(req.URL, "?"); ok {
+		query = q
+	}
+	// Marshaling can't fail, as the variant only contains strings
+	variant, _ := json.Marshal(struct {
+		User  *backend.User `json:"user"`
+		Query string        `json:"query"`
+	}{
+		User:  req.PluginContext.User,
+		Query: query,
+	})
+	sum := sha256.Sum256(variant)
+	return CacheKey{
+		Group:   cacheGroup(req.PluginContext.OrgID, req.Path),
+		Variant: hex.EncodeToString(sum[:]),
+	}
+}
+
+// DefaultInvalidateGroups returns the groups of DefaultCacheKey for the request's path and each of its parent paths,
+// so that, for example, a PUT request to "/widgets/foo" invalidates the cached responses for "/widgets/foo" and "/widgets".
+func DefaultInvalidateGroups(_ context.Context, req *backend.CallResourceRequest) []string {
+	groups := make([]string, 0)
+	p := req.Path
+	for {
+		groups = append(groups, cacheGroup(req.PluginContext.OrgID, p))
+		parent := path.Dir(strings.TrimSuffix(p, "/"))
+		if parent == p || parent == "." || parent == "/" || parent == "" {
+			break
+		}
+		p = parent
+	}
+	return groups
+}
+
+func cacheGroup(orgID int64, p string) string {
+	return strconv.FormatInt(orgID, 10) + ":" + strings.TrimSuffix(p, "/")
+}
+
+// NewCachingMiddleware returns a MiddlewareFunc which caches the responses of GET requests with a 200 status code.
+// Cached responses are sent without calling the downstream handler, until they expire after config.TTL,
+// or are invalidated by a successful request with one of config.InvalidateMethods (see CachingMiddlewareConfig.InvalidateFunc).
+// Requests with other methods, such as HEAD and OPTIONS, neither use nor invalidate the cache.
+//
+// Each response has an ETag header, and a 304 Not Modified response is sent if the request's If-None-Match header
+// matches it. Requests with a "Cache-Control: no-cache" header skip the cache (but still update it).
+// Responses which aren't cached are sent as soon as the handler sends them, and are not buffered:
+// responses with a status code other than 200, a "Cache-Control: no-store" or "no-cache" header,
+// or a streaming content type (ContentTypeNDJSON or ContentTypeEventStream), such as watch responses,
+// as well as any responses after the first (for handlers which call Send more than once).
+// Responses are also not cached if the request's context is done when the handler returns.
+func NewCachingMiddleware(config CachingMiddlewareConfig) MiddlewareFunc {
+	if config.Cache == nil {
+		config.Cache = NewLRUResponseCache(DefaultResponseCacheSize)
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = DefaultCacheKey
+	}
+	if config.InvalidateFunc == nil {
+		config.InvalidateFunc = DefaultInvalidateGroups
+	}
+	if len(config.InvalidateMethods) == 0 {
+		config.InvalidateMethods = DefaultInvalidateMethods
+	}
+	invalidateMethods := make(map[string]struct{}, len(config.InvalidateMethods))
+	for _, method := range config.InvalidateMethods {
+		invalidateMethods[strings.ToUpper(method)] = struct{}{}
+	}
+	return func(handler HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+			if _, ok := invalidateMethods[strings.ToUpper(req.Method)]; ok {
+				status := &statusRecordingSender{CallResourceResponseSender: sender}
+				handler(ctx, req, status)
+				if status.status >= 200 && status.status < 300 {
+					for _, group := range config.InvalidateFunc(ctx, req) {
+						if err := config.Cache.Invalidate(ctx, group); err != nil {
+							logging.FromContext(ctx).Error("error invalidating cached responses", "group", group, "error", err)
+						}
+					}
+				}
+				return
+			}
+			if !strings.EqualFold(req.Method, http.MethodGet) {
+				handler(ctx, req, sender)
+				return
+			}
+
+			headers := http.Header(req.Headers)
+			key := config.KeyFunc(ctx, req)
+			if !strings.Contains(headers.Get("Cache-Control"), "no-cache") {
+				cached, ok, err := config.Cache.Get(ctx, key)
+				if err != nil {
+					logging.FromContext(ctx).Error("error getting cached response", "error", err)
+				} else if ok {
+					sendCachedResponse(ctx, sender, cached, headers.Get("If-None-Match"))
+					return
+				}
+			}
+
+			buf := &bufferingSender{sender: sender}
+			handler(ctx, req, buf)
+			if buf.passthrough || buf.response == nil {
+				return
+			}
+			resp := buf.response
+			if ctx.Err() != nil {
+				// The response may be incomplete, such as a handler which returned early when the request was canceled
+				if err := sender.Send(resp); err != nil {
+					logging.FromContext(ctx).Error("error sending backend response", "error", err)
+				}
+				return
+			}
+			sum := sha256.Sum256(resp.Body)
+			cached := CachedResponse{
+				Status:  resp.Status,
+				Headers: copyHeaders(resp.Headers),
+				Body:    resp.Body,
+				ETag:    `"` + hex.EncodeToString(sum[:16]) + `"`,
+			}
+			if err := config.Cache.Set(ctx, key, cached, config.TTL); err != nil {
+				logging.FromContext(ctx).Error("error caching response", "error", err)
+			}
+			sendCachedResponse(ctx, sender, cached, headers.Get("If-None-Match"))
+		}
+	}
+}
+
+func sendCachedResponse(ctx context.Context, sender backend.CallResourceResponseSender, cached CachedResponse, ifNoneMatch string) {
+	headers := copyHeaders(cached.Headers)
+	headers["ETag"] = []string{cached.ETag}
+	resp := &backend.CallResourceResponse{
+		Status:  cached.Status,
+		Headers: headers,
+		Body:    cached.Body,
+	}
+	if etagMatches(ifNoneMatch, cached.ETag) {
+		resp.Status = http.StatusNotModified
+		resp.Body = nil
+	}
+	if err := sender.Send(resp); err != nil {
+		logging.FromContext(ctx).Error("error sending backend response", "error", err)
+	}
+}
+
+// etagMatches returns true if the If-None-Match header value matches the etag
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func copyHeaders(headers map[string][]string) map[string][]string {
+	res := make(map[string][]string, len(headers)+1)
+	for k, v := range headers {
+		res[k] = append([]string(nil), v...)
+	}
+	return res
+}
+
+// bufferingSender holds the first response sent if it can be cached, and otherwise sends it to the underlying sender.
+// If a second response is sent, it sends both to the underlying sender, as well as any subsequent responses.
+type bufferingSender struct {
+	sender      backend.CallResourceResponseSender
+	response    *backend.CallResourceResponse
+	passthrough bool
+}
+
+func (b *bufferingSender) Send(res *backend.CallResourceResponse) error {
+	if b.passthrough {
+		return b.sender.Send(res)
+	}
+	if b.response == nil {
+		if !cacheable(res) {
+			b.passthrough = true
+			return b.sender.Send(res)
+		}
+		b.response = res
+		return nil
+	}
+	b.passthrough = true
+	if err := b.sender.Send(b.response); err != nil {
+		return err
+	}
+	return b.sender.Send(res)
+}
+
+// cacheable returns true if the response may be cached by the caching middleware
+func cacheable(res *backend.CallResourceResponse) bool {
+	if res.Status != http.StatusOK {
+		return false
+	}
+	headers := http.Header(res.Headers)
+	cacheControl := headers.Get("Cache-Control")
+	if strings.Contains(cacheControl, "no-store") || strings.Contains(cacheControl, "no-cache") {
+		return false
+	}
+	contentType := headers.Get(HeaderContentType)
+	return !strings.HasPrefix(contentType, ContentTypeNDJSON) && !strings.HasPrefix(contentType, ContentTypeEventStream)
+}
+
+// statusRecordingSender records the status of the first response sent
+type statusRecordingSender struct {
+	backend.CallResourceResponseSender
+	status int
+}
+
+func (s *statusRecordingSender) Send(res *backend.CallResourceResponse) error {
+	if s.status == 0 {
+		s.status = res.Status
+	}
+	return s.CallResourceResponseSender.Send(res)
+}
+
+var _ ResponseCache = &LRUResponseCache{}
+
+// LRUResponseCache is an in-memory ResponseCache which holds up to a fixed number of responses,
+// removing the least recently used response when it is full.
+type LRUResponseCache struct {
+	capacity int
+	entries  map[CacheKey]*list.Element
+	groups   map[string]map[CacheKey]struct{}
+	order    *list.List
+	mux      sync.Mutex
+}
+
+type lruCacheEntry struct {
+	key      CacheKey
+	response CachedResponse
+	expires  time.Time
+}
+
+// NewLRUResponseCache returns a new LRUResponseCache which holds up to capacity responses.
+// If capacity is <= 0, DefaultResponseCacheSize is used.
+func NewLRUResponseCache(capacity int) *LRUResponseCache {
+	if capacity <= 0 {
+		capacity = DefaultResponseCacheSize
+	}
+	return &LRUResponseCache{
+		capacity: capacity,
+		entries:  make(map[CacheKey]*list.Element),
+		groups:   make(map[string]map[CacheKey]struct{}),
+		order:    list.New(),
+	}
+}
+
+// Get returns the response for the key, and false if there is no unexpired response for the key.
+func (c *LRUResponseCache) Get(_ context.Context, key CacheKey) (CachedResponse, bool, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return CachedResponse{}, false, nil
+	}
+	entry := elem.Value.(*lruCacheEntry)
+	if !time.Now().Before(entry.expires) {
+		c.remove(elem)
+		return CachedResponse{}, false, nil
+	}
+	c.order.MoveToFront(elem)
+	return entry.response, true, nil
+}
+
+// Set stores the response for the key, expiring after ttl, and removes the least recently used response if the cache is full.
+func (c *LRUResponseCache) Set(_ context.Context, key CacheKey, response CachedResponse, ttl time.Duration) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	c.entries[key] = c.order.PushFront(&lruCacheEntry{
+		key:      key,
+		response: response,
+		expires:  time.Now().Add(ttl),
+	})
+	if _, ok := c.groups[key.Group]; !ok {
+		c.groups[key.Group] = make(map[CacheKey]struct{})
+	}
+	c.groups[key.Group][key] = struct{}{}
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+// Invalidate removes all responses in the group.
+func (c *LRUResponseCache) Invalidate(_ context.Context, group string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for key := range c.groups[group] {
+		c.remove(c.entries[key])
+	}
+	return nil
+}
+
+// Len returns the number of responses in the cache, including expired responses which have not yet been removed.
+func (c *LRUResponseCache) Len() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRUResponseCache) remove(elem *list.Element) {
+	entry := c.order.Remove(elem).(*lruCacheEntry)
+	delete(c.entries, entry.key)
+	if group, ok := c.groups[entry.key.Group]; ok {
+		delete(group, entry.key)
+		if len(group) == 0 {
+			delete(c.groups, entry.key.Group)
+		}
+	}
+}
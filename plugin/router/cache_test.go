@@ -0,0 +1,274 @@
+package router_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana-app-sdk/plugin/router"
+)
+
+func TestCachingMiddleware(t *testing.T) {
+	calls := 0
+	status := http.StatusOK
+	r := router.NewRouter()
+	r.Use(router.NewCachingMiddleware(router.CachingMiddlewareConfig{
+		TTL: time.Minute,
+	}))
+	r.Handle("/widgets/{name}", func(_ context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+		calls++
+		_ = sender.Send(&backend.CallResourceResponse{
+			Status: status,
+			Body:   []byte(fmt.Sprintf("%s %d", req.Path, calls)),
+		})
+	}, http.MethodGet, http.MethodPut, http.MethodHead)
+	r.Handle("/stream", func(_ context.Context, _ *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+		calls++
+		_ = sender.Send(&backend.CallResourceResponse{Status: http.StatusOK})
+		_ = sender.Send(&backend.CallResourceResponse{Body: []byte("chunk")})
+	})
+	get := func(path string, user string, headers map[string][]string) *backend.CallResourceResponse {
+		return callResource(t, r, &backend.CallResourceRequest{
+			Path:    path,
+			URL:     path,
+			Method:  http.MethodGet,
+			Headers: headers,
+			PluginContext: backend.PluginContext{
+				OrgID: 1,
+				User:  &backend.User{Login: user},
+			},
+		})
+	}
+
+	first := get("/widgets/foo", "alice", nil)
+	assert.Equal(t, http.StatusOK, first.Status)
+	assert.Equal(t, "/widgets/foo 1", string(first.Body))
+	etag := first.Headers["ETag"]
+	require.Len(t, etag, 1)
+
+	t.Run("cached", func(t *testing.T) {
+		resp := get("/widgets/foo", "alice", nil)
+		assert.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, "/widgets/foo 1", string(resp.Body))
+		assert.Equal(t, etag, resp.Headers["ETag"])
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		resp := get("/widgets/foo", "alice", map[string][]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusNotModified, resp.Status)
+		assert.Empty(t, resp.Body)
+		assert.Equal(t, etag, resp.Headers["ETag"])
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("per user", func(t *testing.T) {
+		resp := get("/widgets/foo", "bob", nil)
+		assert.Equal(t, "/widgets/foo 2", string(resp.Body))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("no-cache", func(t *testing.T) {
+		resp := get("/widgets/foo", "alice", map[string][]string{"Cache-Control": {"no-cache"}})
+		assert.Equal(t, "/widgets/foo 3", string(resp.Body))
+		resp = get("/widgets/foo", "alice", nil)
+		assert.Equal(t, "/widgets/foo 3", string(resp.Body))
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("invalidated by update", func(t *testing.T) {
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:          "/widgets/foo",
+			Method:        http.MethodPut,
+			PluginContext: backend.PluginContext{OrgID: 1},
+		})
+		assert.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, 4, calls)
+		resp = get("/widgets/foo", "alice", nil)
+		assert.Equal(t, "/widgets/foo 5", string(resp.Body))
+		resp = get("/widgets/foo", "bob", nil)
+		assert.Equal(t, "/widgets/foo 6", string(resp.Body))
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		defer func() { status = http.StatusOK }()
+		resp := get("/widgets/bar", "alice", nil)
+		assert.Equal(t, http.StatusInternalServerError, resp.Status)
+		assert.Empty(t, resp.Headers["ETag"])
+		status = http.StatusOK
+		resp = get("/widgets/bar", "alice", nil)
+		assert.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, "/widgets/bar 8", string(resp.Body))
+	})
+
+	t.Run("streaming responses are not cached", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			responses := make([]*backend.CallResourceResponse, 0)
+			require.NoError(t, r.CallResource(context.Background(), &backend.CallResourceRequest{
+				Path:   "/stream",
+				Method: http.MethodGet,
+			}, fakeSender{sendFunc: func(res *backend.CallResourceResponse) error {
+				responses = append(responses, res)
+				return nil
+			}}))
+			require.Len(t, responses, 2)
+			assert.Equal(t, http.StatusOK, responses[0].Status)
+			assert.Equal(t, "chunk", string(responses[1].Body))
+		}
+		assert.Equal(t, 10, calls)
+	})
+
+	t.Run("not invalidated by head", func(t *testing.T) {
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:          "/widgets/foo",
+			Method:        http.MethodHead,
+			PluginContext: backend.PluginContext{OrgID: 1},
+		})
+		assert.Equal(t, http.StatusOK, resp.Status)
+		assert.Empty(t, resp.Headers["ETag"])
+		assert.Equal(t, 11, calls)
+		resp = get("/widgets/foo", "alice", nil)
+		assert.Equal(t, "/widgets/foo 5", string(resp.Body))
+		assert.Equal(t, 11, calls)
+	})
+}
+
+func TestCachingMiddleware_Passthrough(t *testing.T) {
+	r := router.NewRouter()
+	r.Use(router.NewCachingMiddleware(router.CachingMiddlewareConfig{
+		TTL: time.Minute,
+	}))
+	sent := make(chan struct{})
+	r.Handle("/watch", func(_ context.Context, _ *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+		_ = sender.Send(&backend.CallResourceResponse{
+			Status:  http.StatusOK,
+			Headers: map[string][]string{router.HeaderContentType: {router.ContentTypeNDJSON}},
+		})
+		// The headers must be sent before the first event
+		<-sent
+	})
+	calls := 0
+	r.Handle("/canceled", func(ctx context.Context, _ *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+		calls++
+		<-ctx.Done()
+		_ = sender.Send(&backend.CallResourceResponse{Status: http.StatusOK, Body: []byte("partial")})
+	})
+	r.Handle("/no-cache", func(_ context.Context, _ *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+		calls++
+		_ = sender.Send(&backend.CallResourceResponse{
+			Status:  http.StatusOK,
+			Headers: map[string][]string{"Cache-Control": {"no-cache"}},
+		})
+	})
+
+	t.Run("streaming content type", func(t *testing.T) {
+		done := make(chan error)
+		go func() {
+			done <- r.CallResource(context.Background(), &backend.CallResourceRequest{
+				Path:   "/watch",
+				Method: http.MethodGet,
+			}, fakeSender{sendFunc: func(res *backend.CallResourceResponse) error {
+				assert.Equal(t, http.StatusOK, res.Status)
+				assert.Empty(t, res.Headers["ETag"])
+				close(sent)
+				return nil
+			}})
+		}()
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("headers were not sent before the handler returned")
+		}
+	})
+
+	t.Run("canceled request", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			var resp *backend.CallResourceResponse
+			require.NoError(t, r.CallResource(ctx, &backend.CallResourceRequest{
+				Path:   "/canceled",
+				Method: http.MethodGet,
+			}, fakeSender{sendFunc: func(res *backend.CallResourceResponse) error {
+				resp = res
+				return nil
+			}}))
+			require.NotNil(t, resp)
+			assert.Equal(t, "partial", string(resp.Body))
+			assert.Empty(t, resp.Headers["ETag"])
+		}
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("no-cache response", func(t *testing.T) {
+		calls = 0
+		for i := 0; i < 2; i++ {
+			resp := callResource(t, r, &backend.CallResourceRequest{
+				Path:   "/no-cache",
+				Method: http.MethodGet,
+			})
+			assert.Equal(t, http.StatusOK, resp.Status)
+			assert.Empty(t, resp.Headers["ETag"])
+		}
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func TestDefaultCacheKey(t *testing.T) {
+	key := func(user *backend.User, url string) router.CacheKey {
+		return router.DefaultCacheKey(context.Background(), &backend.CallResourceRequest{
+			Path:          "/widgets",
+			URL:           url,
+			PluginContext: backend.PluginContext{OrgID: 1, User: user},
+		})
+	}
+	assert.Equal(t, key(&backend.User{Login: "alice"}, "/widgets?a=b"), key(&backend.User{Login: "alice"}, "/widgets?a=b"))
+	// The user and query can't be confused with each other
+	assert.NotEqual(t, key(&backend.User{Login: "alice?a=b"}, "/widgets?c=d"), key(&backend.User{Login: "alice"}, "/widgets?a=b?c=d"))
+	assert.NotEqual(t, key(nil, "/widgets"), key(&backend.User{}, "/widgets"))
+	// Users with the same login but a different role don't share responses
+	assert.NotEqual(t, key(&backend.User{Login: "alice", Role: "Viewer"}, "/widgets"), key(&backend.User{Login: "alice", Role: "Admin"}, "/widgets"))
+}
+
+func TestLRUResponseCache(t *testing.T) {
+	ctx := context.Background()
+	cache := router.NewLRUResponseCache(2)
+	a := router.CacheKey{Group: "1:/a", Variant: "alice"}
+	b := router.CacheKey{Group: "1:/b", Variant: "alice"}
+	c := router.CacheKey{Group: "1:/a", Variant: "bob"}
+
+	require.NoError(t, cache.Set(ctx, a, router.CachedResponse{Body: []byte("a")}, time.Minute))
+	require.NoError(t, cache.Set(ctx, b, router.CachedResponse{Body: []byte("b")}, time.Minute))
+	// Use a, so b is the least recently used
+	_, ok, err := cache.Get(ctx, a)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.NoError(t, cache.Set(ctx, c, router.CachedResponse{Body: []byte("c")}, time.Minute))
+	assert.Equal(t, 2, cache.Len())
+	_, ok, _ = cache.Get(ctx, b)
+	assert.False(t, ok)
+
+	require.NoError(t, cache.Invalidate(ctx, "1:/a"))
+	assert.Equal(t, 0, cache.Len())
+
+	require.NoError(t, cache.Set(ctx, a, router.CachedResponse{Body: []byte("a")}, time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	_, ok, _ = cache.Get(ctx, a)
+	assert.False(t, ok)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestDefaultInvalidateGroups(t *testing.T) {
+	assert.Equal(t, []string{"2:/a/b/c", "2:/a/b", "2:/a"}, router.DefaultInvalidateGroups(context.Background(), &backend.CallResourceRequest{
+		Path:          "/a/b/c/",
+		PluginContext: backend.PluginContext{OrgID: 2},
+	}))
+}
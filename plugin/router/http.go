@@ -0,0 +1,126 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+
+	"github.com/grafana/grafana-app-sdk/logging"
+	"github.com/grafana/grafana-app-sdk/plugin"
+)
+
+// DefaultHTTPMaxBodySize is the maximum size in bytes of request bodies read by the http.Handler returned by NewHTTPHandler.
+const DefaultHTTPMaxBodySize int64 = 10 << 20
+
+// NewHTTPHandler returns an http.Handler which serves HTTP requests with a backend.CallResourceHandler,
+// such as a Router or JSONRouter, so the same routes can be served by a standalone HTTP server, or tested with httptest.
+//
+// The backend.CallResourceRequest passed to the handler is built from the HTTP request,
+// with the PluginContext from the request's context (see backend.WithPluginContext), if there is one.
+// As the request body is read into memory before the handler is called, requests with a body larger than
+// DefaultHTTPMaxBodySize are rejected with a 413 Request Entity Too Large response.
+// Use NewHTTPHandlerWithMaxBodySize to use a different maximum.
+// Responses sent after the first are written to the body as they are sent, allowing streaming responses.
+func NewHTTPHandler(handler backend.CallResourceHandler) http.Handler {
+	return NewHTTPHandlerWithMaxBodySize(handler, DefaultHTTPMaxBodySize)
+}
+
+// NewHTTPHandlerWithMaxBodySize returns an http.Handler like NewHTTPHandler, which rejects requests
+// with a body larger than maxBodySize bytes. If maxBodySize is <= 0, the body size isn't limited.
+func NewHTTPHandlerWithMaxBodySize(handler backend.CallResourceHandler, maxBodySize int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		reqBody := req.Body
+		if maxBodySize > 0 {
+			reqBody = http.MaxBytesReader(w, req.Body, maxBodySize)
+		}
+		body, err := io.ReadAll(reqBody)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			logging.FromContext(ctx).Error("error reading request body", "error", err)
+			http.Error(w, "unable to read request body", http.StatusBadRequest)
+			return
+		}
+		sender := &responseWriterSender{writer: w}
+		err = handler.CallResource(ctx, &backend.CallResourceRequest{
+			PluginContext: backend.PluginConfigFromContext(ctx),
+			Path:          req.URL.Path,
+			Method:        req.Method,
+			URL:           req.URL.RequestURI(),
+			Headers:       req.Header,
+			Body:          body,
+		}, sender)
+		if err != nil {
+			logging.FromContext(ctx).Error("error handling request", "error", err)
+			if !sender.sent {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}
+	})
+}
+
+// ServeHTTP implements http.Handler, allowing the Router to serve HTTP requests (see NewHTTPHandler).
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	NewHTTPHandler(r).ServeHTTP(w, req)
+}
+
+// HTTPHandlerFunc returns a HandlerFunc which calls an http.Handler, allowing an http.Handler to be mounted as a route.
+// The http.Request's path is the path of the resource request, and its context is the context passed to the HandlerFunc,
+// so path vars can be retrieved with VarsFromCtx, and the plugin context with backend.PluginConfigFromContext.
+func HTTPHandlerFunc(handler http.Handler) HandlerFunc {
+	adapter := httpadapter.New(handler)
+	return func(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+		tracking := &sentTrackingSender{sender: sender}
+		if err := adapter.CallResource(ctx, req, tracking); err != nil {
+			logging.FromContext(ctx).Error("error calling http handler", "error", err)
+			// An error response can't be sent if the handler has already sent a response
+			if !tracking.sent {
+				sendJSONError(ctx, sender, plugin.WrapError(http.StatusInternalServerError, err))
+			}
+		}
+	}
+}
+
+// HandleHTTP registers an http.Handler to a given path and method(s), like Handle (see HTTPHandlerFunc).
+func (r *Router) HandleHTTP(path string, handler http.Handler, methods ...string) *RouteHandler {
+	return r.Handle(path, HTTPHandlerFunc(handler), methods...)
+}
+
+// responseWriterSender is a backend.CallResourceResponseSender which writes responses to an http.ResponseWriter.
+// The status and headers of the first response are written, and the body of every response.
+type responseWriterSender struct {
+	writer http.ResponseWriter
+	sent   bool
+}
+
+func (s *responseWriterSender) Send(res *backend.CallResourceResponse) error {
+	if !s.sent {
+		headers := s.writer.Header()
+		for k, v := range res.Headers {
+			headers[k] = v
+		}
+		status := res.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		s.writer.WriteHeader(status)
+		s.sent = true
+	}
+	if len(res.Body) > 0 {
+		if _, err := s.writer.Write(res.Body); err != nil {
+			return err
+		}
+	}
+	if flusher, ok := s.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
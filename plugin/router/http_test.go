@@ -0,0 +1,139 @@
+package router_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana-app-sdk/plugin/router"
+)
+
+func TestRouter_ServeHTTP(t *testing.T) {
+	r := router.NewRouter()
+	r.Handle("/widgets/{name}", func(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+		_ = sender.Send(&backend.CallResourceResponse{
+			Status:  http.StatusCreated,
+			Headers: map[string][]string{"X-Name": {router.VarsFromCtx(ctx)["name"]}},
+			Body:    []byte(req.URL + " " + string(req.Body) + " " + req.PluginContext.User.Login),
+		})
+	}, http.MethodPost)
+	r.Handle("/stream", func(_ context.Context, _ *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+		_ = sender.Send(&backend.CallResourceResponse{Status: http.StatusOK, Body: []byte("a")})
+		_ = sender.Send(&backend.CallResourceResponse{Body: []byte("b")})
+	})
+
+	t.Run("route", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/widgets/foo?a=b", strings.NewReader("body"))
+		req = req.WithContext(backend.WithPluginContext(req.Context(), backend.PluginContext{
+			User: &backend.User{Login: "alice"},
+		}))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "foo", rec.Header().Get("X-Name"))
+		assert.Equal(t, "/widgets/foo?a=b body alice", rec.Body.String())
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "ab", rec.Body.String())
+		assert.True(t, rec.Flushed)
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/widgets/foo", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/foo", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestJSONRouter_ServeHTTP(t *testing.T) {
+	r := router.NewJSONRouter()
+	r.Subroute("/v1").Handle("/widgets/{name}", func(_ context.Context, req router.JSONRequest) (router.JSONResponse, error) {
+		return map[string]string{"name": req.Vars["name"]}, nil
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/v1/widgets/foo")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, router.ContentTypeJSON, resp.Header.Get(router.HeaderContentType))
+	got := make(map[string]string)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	assert.Equal(t, map[string]string{"name": "foo"}, got)
+}
+
+func TestRouter_HandleHTTP(t *testing.T) {
+	r := router.NewRouter()
+	r.HandleHTTP("/files/{path...}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		w.Header().Set("X-Org", "1")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(req.URL.Path + " " + router.VarsFromCtx(req.Context())["path"] + " " + string(body)))
+	}), http.MethodPut)
+
+	resp := callResource(t, r, &backend.CallResourceRequest{
+		Path:   "/files/a/b.txt",
+		URL:    "/files/a/b.txt",
+		Method: http.MethodPut,
+		Body:   []byte("content"),
+	})
+	assert.Equal(t, http.StatusAccepted, resp.Status)
+	assert.Equal(t, []string{"1"}, resp.Headers["X-Org"])
+	assert.Equal(t, "/files/a/b.txt a/b.txt content", string(resp.Body))
+}
+
+func TestRouter_HandleHTTP_Error(t *testing.T) {
+	r := router.NewRouter()
+	r.HandleHTTP("/files", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// An invalid URL can't be converted to an http.Request
+	resp := callResource(t, r, &backend.CallResourceRequest{
+		Path:   "/files",
+		URL:    "/files?%zz\x7f",
+		Method: http.MethodGet,
+	})
+	assert.Equal(t, http.StatusInternalServerError, resp.Status)
+}
+
+func TestNewHTTPHandlerWithMaxBodySize(t *testing.T) {
+	r := router.NewRouter()
+	r.Handle("/upload", func(_ context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+		_ = sender.Send(&backend.CallResourceResponse{Status: http.StatusOK, Body: req.Body})
+	}, http.MethodPost)
+	handler := router.NewHTTPHandlerWithMaxBodySize(r, 4)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("1234")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1234", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("12345")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	rec = httptest.NewRecorder()
+	router.NewHTTPHandlerWithMaxBodySize(r, 0).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("12345")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
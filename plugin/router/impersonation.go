@@ -0,0 +1,41 @@
+package router
+
+import (
+	"context"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/grafana-app-sdk/k8s"
+)
+
+// ImpersonationFromUser returns an Impersonation of the Grafana user, using the user's login as the username.
+// It returns false if user is nil or has no login.
+func ImpersonationFromUser(user *backend.User) (k8s.Impersonation, bool) {
+	if user == nil || user.Login == "" {
+		return k8s.Impersonation{}, false
+	}
+	return k8s.Impersonation{
+		UserName: user.Login,
+	}, true
+}
+
+// NewImpersonationMiddleware returns a MiddlewareFunc which sets the identity returned by mapper for the user
+// of the request's PluginContext as the identity to impersonate in the request context (see k8s.WithImpersonation).
+// If mapper is nil, ImpersonationFromUser is used.
+//
+// Requests made with the context by clients with k8s.ImpersonationFromContext as their ImpersonationProvider
+// will impersonate the user. If mapper returns false, no identity is set, and such requests use the client's own
+// identity unless k8s.ClientConfig.RequireImpersonation is set, in which case they fail.
+func NewImpersonationMiddleware(mapper func(user *backend.User) (k8s.Impersonation, bool)) MiddlewareFunc {
+	if mapper == nil {
+		mapper = ImpersonationFromUser
+	}
+	return func(handler HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+			if imp, ok := mapper(req.PluginContext.User); ok {
+				ctx = k8s.WithImpersonation(ctx, imp)
+			}
+			handler(ctx, req, sender)
+		}
+	}
+}
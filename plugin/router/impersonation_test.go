@@ -0,0 +1,65 @@
+package router_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/grafana-app-sdk/k8s"
+	"github.com/grafana/grafana-app-sdk/plugin/router"
+)
+
+func TestImpersonationMiddleware(t *testing.T) {
+	var (
+		imp k8s.Impersonation
+		ok  bool
+	)
+	handler := func(ctx context.Context, _ *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+		imp, ok = k8s.ImpersonationFromContext(ctx)
+		_ = sender.Send(&backend.CallResourceResponse{Status: http.StatusOK})
+	}
+
+	t.Run("default mapper", func(t *testing.T) {
+		r := router.NewRouter()
+		r.Use(router.NewImpersonationMiddleware(nil))
+		r.Handle("/foo", handler)
+
+		callResource(t, r, &backend.CallResourceRequest{
+			Path: "/foo", Method: http.MethodGet,
+			PluginContext: backend.PluginContext{User: &backend.User{Login: "alice", Role: router.RoleEditor}},
+		})
+		assert.True(t, ok)
+		assert.Equal(t, k8s.Impersonation{UserName: "alice"}, imp)
+
+		callResource(t, r, &backend.CallResourceRequest{Path: "/foo", Method: http.MethodGet})
+		assert.False(t, ok)
+	})
+
+	t.Run("custom mapper", func(t *testing.T) {
+		r := router.NewRouter()
+		r.Use(router.NewImpersonationMiddleware(func(user *backend.User) (k8s.Impersonation, bool) {
+			return k8s.Impersonation{UserName: "grafana:" + user.Login, Groups: []string{user.Role}}, true
+		}))
+		r.Handle("/foo", handler)
+
+		callResource(t, r, &backend.CallResourceRequest{
+			Path: "/foo", Method: http.MethodGet,
+			PluginContext: backend.PluginContext{User: &backend.User{Login: "alice", Role: router.RoleEditor}},
+		})
+		assert.True(t, ok)
+		assert.Equal(t, k8s.Impersonation{UserName: "grafana:alice", Groups: []string{router.RoleEditor}}, imp)
+	})
+}
+
+func TestImpersonationFromUser(t *testing.T) {
+	_, ok := router.ImpersonationFromUser(nil)
+	assert.False(t, ok)
+	_, ok = router.ImpersonationFromUser(&backend.User{Email: "alice@example.com"})
+	assert.False(t, ok)
+	imp, ok := router.ImpersonationFromUser(&backend.User{Login: "alice"})
+	assert.True(t, ok)
+	assert.Equal(t, "alice", imp.UserName)
+}
@@ -0,0 +1,357 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+
+	"github.com/grafana/grafana-app-sdk/plugin"
+)
+
+// JSONBodyHandlerFunc is a JSON request handler which is passed the request body decoded into Req,
+// and returns a response of type Resp or an error. See HandleWithBody.
+type JSONBodyHandlerFunc[Req, Resp any] func(ctx context.Context, req JSONRequest, body Req) (Resp, error)
+
+// HandleWithBody attaches a new handler to the route with path and methods methods (GET if no methods are specified),
+// which returns okCode for successful requests.
+//
+// Before the handler is called, the request body is validated against a JSON schema generated from Req,
+// and then decoded into a Req. If the body isn't valid JSON or doesn't match the schema, a 400 error is returned.
+// In the generated schema, struct fields are required unless their json tag has the omitempty option,
+// and pointer, slice and map fields may be null. An empty body is treated as an empty JSON object.
+// The handler's response is encoded as JSON, and a nil response results in a 204 No Content response.
+//
+// Errors returned by the handler which are a plugin.Error keep their code, errors which are a
+// resource.APIServerResponseError use its status code, context deadline errors are a 504 Gateway Timeout,
+// and all other errors are a 500 Internal Server Error.
+//
+// Req and Resp are used to describe the route in the document returned by JSONRouter.OpenAPI.
+func HandleWithBody[Req, Resp any](
+	j *JSONRouter, path string, handler JSONBodyHandlerFunc[Req, Resp], okCode int, methods ...string,
+) *RouteHandler {
+	return handleWithBody(j, path, handler, okCode, nil, methods...)
+}
+
+// handleWithBody registers a handler for HandleWithBody, with varTypes as the types of the route's path vars,
+// if they are known
+func handleWithBody[Req, Resp any](
+	j *JSONRouter, path string, handler JSONBodyHandlerFunc[Req, Resp], okCode int, varTypes map[string]reflect.Type,
+	methods ...string,
+) *RouteHandler {
+	reqType := reflect.TypeFor[Req]()
+	schema, schemaErr := generateSchema(reqType, nil)
+	wrapped := j.WrapHandlerFunc(func(ctx context.Context, req JSONRequest) (JSONResponse, error) {
+		if schemaErr != nil {
+			return nil, fmt.Errorf("unable to generate request body schema: %w", schemaErr)
+		}
+		body, err := decodeBody[Req](req.Body, schema.Value)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := handler(ctx, req, body)
+		if err != nil {
+			return nil, typedHandlerError(err)
+		}
+		if isNil(resp) {
+			return nil, nil
+		}
+		return resp, nil
+	}, okCode)
+	return j.handle(path, wrapped, jsonRoute{
+		okCode:   okCode,
+		request:  reqType,
+		response: reflect.TypeFor[Resp](),
+		varTypes: varTypes,
+	}, methods...)
+}
+
+// OpenAPI returns an OpenAPI 3 document describing all routes registered with the JSONRouter,
+// and with any JSONRouter created from it with Subroute or SubrouteWithErrorHandler.
+// Each route's path vars are described as path parameters, and routes registered with HandleWithBody
+// have their request body and successful response described by schemas generated from their types.
+// Routes registered with Handle and HandleWithCode have an untyped JSON response.
+// If a route has a name, it is used as the operation ID. Named routes with multiple methods
+// have the method appended to the name in each operation ID, such as "createWidgetPut".
+func (j *JSONRouter) OpenAPI(info openapi3.Info) (*openapi3.T, error) {
+	doc := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info:    &info,
+		Paths:   openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas: make(openapi3.Schemas),
+		},
+	}
+	if j.routes == nil {
+		return doc, nil
+	}
+	errorSchema, err := generateSchema(reflect.TypeFor[JSONErrorResponse](), doc.Components.Schemas)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, route := range j.routes.list() {
+		path, params, err := openAPIPath(route.path, route.varTypes)
+		if err != nil {
+			return nil, err
+		}
+		item := doc.Paths.Value(path)
+		if item == nil {
+			item = &openapi3.PathItem{}
+			doc.Paths.Set(path, item)
+		}
+
+		methods := make([]string, 0, len(route.handler.methods))
+		for method := range route.handler.methods {
+			methods = append(methods, method)
+		}
+		slices.Sort(methods)
+		for _, method := range methods {
+			op := openapi3.NewOperation()
+			op.OperationID = route.handler.name
+			if op.OperationID != "" && len(methods) > 1 {
+				op.OperationID += method[:1] + strings.ToLower(method[1:])
+			}
+			op.Parameters = params
+
+			if route.request != nil {
+				schema, err := generateSchema(route.request, doc.Components.Schemas)
+				if err != nil {
+					return nil, fmt.Errorf("unable to generate request schema for %s %s: %w", method, path, err)
+				}
+				op.RequestBody = &openapi3.RequestBodyRef{
+					Value: openapi3.NewRequestBody().WithRequired(true).WithJSONSchemaRef(schema),
+				}
+			}
+
+			resp := openapi3.NewResponse().WithDescription(http.StatusText(route.okCode))
+			switch {
+			case route.okCode == http.StatusNoContent:
+			case route.response != nil:
+				schema, err := generateSchema(route.response, doc.Components.Schemas)
+				if err != nil {
+					return nil, fmt.Errorf("unable to generate response schema for %s %s: %w", method, path, err)
+				}
+				resp = resp.WithJSONSchemaRef(schema)
+			default:
+				resp = resp.WithJSONSchema(&openapi3.Schema{})
+			}
+			op.Responses = openapi3.NewResponses(
+				openapi3.WithStatus(route.okCode, &openapi3.ResponseRef{Value: resp}),
+				openapi3.WithName("default", openapi3.NewResponse().WithDescription("Error").WithJSONSchemaRef(errorSchema)),
+			)
+			item.SetOperation(method, op)
+		}
+	}
+	return doc, nil
+}
+
+// jsonRoute is a route registered with a JSONRouter, used to describe the route in the OpenAPI document
+type jsonRoute struct {
+	// path is the full path of the route, including the prefixes of any subroutes
+	path    string
+	handler *RouteHandler
+	okCode  int
+	// request is the type of the request body, and is nil for untyped routes
+	request reflect.Type
+	// response is the type of the response body, and is nil for untyped routes
+	response reflect.Type
+	// varTypes are the types of the path vars, if known. Vars with an unknown type are described as strings.
+	varTypes map[string]reflect.Type
+}
+
+// jsonRoutes is the list of routes registered with a JSONRouter, which is shared with its subroutes
+type jsonRoutes struct {
+	routes []jsonRoute
+	mux    sync.RWMutex
+}
+
+func (r *jsonRoutes) add(route jsonRoute) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.routes = append(r.routes, route)
+}
+
+func (r *jsonRoutes) list() []jsonRoute {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	return slices.Clone(r.routes)
+}
+
+// openAPIPath converts a route path into an OpenAPI path, stripping any match expressions from the path vars,
+// and returns the path parameters for the path vars
+func openAPIPath(path string, varTypes map[string]reflect.Type) (string, openapi3.Parameters, error) {
+	params := make(openapi3.Parameters, 0)
+	for _, match := range replArgRegex.FindAllStringSubmatch(path, -1) {
+		// Wildcard vars are written as {name...}
+		name := strings.TrimSuffix(match[1], "...")
+		schema := openapi3.NewStringSchema()
+		if t, ok := varTypes[name]; ok {
+			ref, err := generateSchema(t, nil)
+			if err != nil {
+				return "", nil, fmt.Errorf("unable to generate schema for path var '%s': %w", name, err)
+			}
+			schema = ref.Value
+		}
+		if match[2] != "" {
+			schema.Pattern = "^" + match[2] + "$"
+		}
+		params = append(params, &openapi3.ParameterRef{
+			Value: openapi3.NewPathParameter(name).WithSchema(schema),
+		})
+		path = strings.Replace(path, match[0], "{"+name+"}", 1)
+	}
+	path = "/" + strings.TrimLeft(path, "/")
+	for strings.Contains(path, "//") {
+		path = strings.ReplaceAll(path, "//", "/")
+	}
+	return path, params, nil
+}
+
+var (
+	jsonMarshalerType   = reflect.TypeFor[json.Marshaler]()
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+)
+
+// generateSchema generates a JSON schema for t. Schemas for recursive types are added to components.
+func generateSchema(t reflect.Type, components openapi3.Schemas) (*openapi3.SchemaRef, error) {
+	gen := openapi3gen.NewGenerator(
+		openapi3gen.UseAllExportedFields(),
+		openapi3gen.SchemaCustomizer(customizeSchema),
+	)
+	if components == nil {
+		// Without components, keep the values of recursive references so the schema can be used for validation
+		return gen.GenerateSchemaRef(t)
+	}
+	return gen.NewSchemaRefForValue(reflect.New(t).Interface(), components)
+}
+
+// customizeSchema adjusts generated schemas to match how encoding/json encodes values
+func customizeSchema(_ string, t reflect.Type, tag reflect.StructTag, schema *openapi3.Schema) error {
+	// Fields set from path vars by HandleTyped are not part of the body
+	if _, ok := tag.Lookup(VarTag); ok {
+		return &openapi3gen.ExcludeSchemaSentinel{}
+	}
+	// Types with custom JSON encoding can't be described from their fields, so allow any value
+	if t.Kind() != reflect.Interface && (t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonUnmarshalerType)) {
+		if schema.Format != "date-time" {
+			*schema = openapi3.Schema{Nullable: schema.Nullable}
+		}
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Map:
+		// nil slices and maps are encoded as null
+		schema.Nullable = true
+	case reflect.Struct:
+		if schema.Properties == nil {
+			break
+		}
+		walkJSONFields(t, func(name string, field reflect.StructField, omitEmpty bool) {
+			if _, ok := field.Tag.Lookup(VarTag); ok {
+				// The generator doesn't pass the tags of promoted fields to the customizer, so remove them here
+				delete(schema.Properties, name)
+			} else if !omitEmpty {
+				schema.Required = append(schema.Required, name)
+			}
+		})
+		if len(schema.Properties) == 0 {
+			schema.Properties = nil
+			schema.Type = nil
+		}
+	default:
+	}
+	return nil
+}
+
+// walkJSONFields calls fn with the JSON name of each field of the struct t which is encoded by encoding/json,
+// including the promoted fields of embedded structs, and whether the field has the omitempty option
+func walkJSONFields(t reflect.Type, fn func(name string, field reflect.StructField, omitEmpty bool)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				walkJSONFields(ft, fn)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fn(name, field, slices.Contains(strings.Split(opts, ","), "omitempty"))
+	}
+}
+
+// decodeBody validates the JSON in body against schema, and decodes it into a T
+func decodeBody[T any](body io.Reader, schema *openapi3.Schema) (T, error) {
+	var res T
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return res, plugin.WrapError(http.StatusBadRequest, fmt.Errorf("unable to read request body: %w", err))
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		raw = []byte("{}")
+		if schema.Type != nil && !schema.Type.Is(openapi3.TypeObject) {
+			return res, plugin.NewError(http.StatusBadRequest, "request body is required")
+		}
+	}
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return res, plugin.WrapError(http.StatusBadRequest, fmt.Errorf("request body is not valid JSON: %w", err))
+	}
+	if err := schema.VisitJSON(value); err != nil {
+		return res, plugin.WrapError(http.StatusBadRequest, schemaValidationError(err))
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return res, plugin.WrapError(http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+	}
+	return res, nil
+}
+
+// schemaValidationError returns an error with a concise message for a schema validation error,
+// as the default message includes the whole schema and value
+func schemaValidationError(err error) error {
+	var cast *openapi3.SchemaError
+	if !errors.As(err, &cast) {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	if pointer := cast.JSONPointer(); len(pointer) > 0 {
+		return fmt.Errorf("invalid request body: field '%s': %s", strings.Join(pointer, "."), cast.Reason)
+	}
+	return fmt.Errorf("invalid request body: %s", cast.Reason)
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,158 @@
+package router_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana-app-sdk/plugin/router"
+)
+
+type createWidgetRequest struct {
+	Name   string            `json:"name"`
+	Size   int               `json:"size"`
+	Labels map[string]string `json:"labels"`
+	Color  *string           `json:"color,omitempty"`
+}
+
+type widget struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Size int    `json:"size"`
+}
+
+func TestHandleWithBody(t *testing.T) {
+	r := router.NewJSONRouter()
+	router.HandleWithBody(r, "/widgets/{id}", func(_ context.Context, req router.JSONRequest, body createWidgetRequest) (*widget, error) {
+		if body.Name == "empty" {
+			return nil, nil
+		}
+		return &widget{
+			ID:   req.Vars.MustGet("id"),
+			Name: body.Name,
+			Size: body.Size,
+		}, nil
+	}, http.StatusCreated, http.MethodPost)
+
+	t.Run("valid body", func(t *testing.T) {
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:   "/widgets/foo",
+			Method: http.MethodPost,
+			Body:   []byte(`{"name":"bar","size":3,"labels":null}`),
+		})
+		assert.Equal(t, http.StatusCreated, resp.Status)
+		got := widget{}
+		require.NoError(t, json.Unmarshal(resp.Body, &got))
+		assert.Equal(t, widget{ID: "foo", Name: "bar", Size: 3}, got)
+	})
+
+	t.Run("nil response", func(t *testing.T) {
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:   "/widgets/foo",
+			Method: http.MethodPost,
+			Body:   []byte(`{"name":"empty","size":3,"labels":{},"color":"red"}`),
+		})
+		assert.Equal(t, http.StatusNoContent, resp.Status)
+		assert.Empty(t, resp.Body)
+	})
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{{
+		name:    "missing required field",
+		body:    `{"name":"bar","labels":{}}`,
+		wantErr: `invalid request body: field 'size': property "size" is missing`,
+	}, {
+		name:    "wrong type",
+		body:    `{"name":"bar","size":"big","labels":{}}`,
+		wantErr: `invalid request body: field 'size': value must be an integer`,
+	}, {
+		name:    "wrong nested type",
+		body:    `{"name":"bar","size":1,"labels":{"a":1}}`,
+		wantErr: `invalid request body: field 'labels.a': value must be a string`,
+	}, {
+		name:    "invalid JSON",
+		body:    `{"name":`,
+		wantErr: "request body is not valid JSON: unexpected end of JSON input",
+	}, {
+		name:    "empty body",
+		body:    "",
+		wantErr: `invalid request body: field 'name': property "name" is missing`,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := callResource(t, r, &backend.CallResourceRequest{
+				Path:   "/widgets/foo",
+				Method: http.MethodPost,
+				Body:   []byte(test.body),
+			})
+			assert.Equal(t, http.StatusBadRequest, resp.Status)
+			got := router.JSONErrorResponse{}
+			require.NoError(t, json.Unmarshal(resp.Body, &got))
+			assert.Equal(t, router.JSONErrorResponse{Code: http.StatusBadRequest, Error: test.wantErr}, got)
+		})
+	}
+}
+
+func TestJSONRouter_OpenAPI(t *testing.T) {
+	r := router.NewJSONRouter()
+	r.Handle("/health", func(context.Context, router.JSONRequest) (router.JSONResponse, error) {
+		return nil, nil
+	}).Name("health")
+	sub := r.Subroute("/namespaces/{namespace}")
+	router.HandleWithBody(sub, "/widgets/{id:[a-z]+}", func(context.Context, router.JSONRequest, createWidgetRequest) (widget, error) {
+		return widget{}, nil
+	}, http.StatusCreated, http.MethodPost, http.MethodPut).Name("createWidget")
+	sub.HandleWithCode("/widgets/{id:[a-z]+}", func(context.Context, router.JSONRequest) (router.JSONResponse, error) {
+		return nil, nil
+	}, http.StatusNoContent, http.MethodDelete)
+
+	doc, err := r.OpenAPI(openapi3.Info{Title: "widgets", Version: "v1"})
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(context.Background()))
+	assert.ElementsMatch(t, []string{"/health", "/namespaces/{namespace}/widgets/{id}"}, doc.Paths.InMatchingOrder())
+
+	health := doc.Paths.Value("/health").Get
+	require.NotNil(t, health)
+	assert.Equal(t, "health", health.OperationID)
+	assert.Nil(t, health.RequestBody)
+	assert.NotNil(t, health.Responses.Status(http.StatusOK).Value.Content.Get(router.ContentTypeJSON))
+
+	widgets := doc.Paths.Value("/namespaces/{namespace}/widgets/{id}")
+	require.NotNil(t, widgets.Post)
+	require.NotNil(t, widgets.Put)
+	require.NotNil(t, widgets.Delete)
+	assert.Equal(t, "createWidgetPost", widgets.Post.OperationID)
+	assert.Equal(t, "createWidgetPut", widgets.Put.OperationID)
+	require.Len(t, widgets.Post.Parameters, 2)
+	assert.Equal(t, "namespace", widgets.Post.Parameters[0].Value.Name)
+	assert.Equal(t, "id", widgets.Post.Parameters[1].Value.Name)
+	assert.Equal(t, "^[a-z]+$", widgets.Post.Parameters[1].Value.Schema.Value.Pattern)
+
+	reqSchema := widgets.Post.RequestBody.Value.Content.Get(router.ContentTypeJSON).Schema.Value
+	assert.ElementsMatch(t, []string{"name", "size", "labels"}, reqSchema.Required)
+	assert.ElementsMatch(t, []string{"name", "size", "labels", "color"}, keys(reqSchema.Properties))
+	assert.True(t, reqSchema.Properties["labels"].Value.Nullable)
+	respSchema := widgets.Post.Responses.Status(http.StatusCreated).Value.Content.Get(router.ContentTypeJSON).Schema.Value
+	assert.ElementsMatch(t, []string{"id", "name", "size"}, keys(respSchema.Properties))
+	assert.NotNil(t, widgets.Post.Responses.Default())
+
+	assert.Nil(t, widgets.Delete.RequestBody)
+	assert.Empty(t, widgets.Delete.Responses.Status(http.StatusNoContent).Value.Content)
+}
+
+func keys[T any](m map[string]T) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	return res
+}
@@ -78,6 +78,10 @@ type JSONErrorHandler func(err plugin.Error) (int, JSONResponse)
 type JSONRouter struct {
 	*Subrouter
 	errHandler JSONErrorHandler
+	// prefix is the path prefix of the JSONRouter, used when describing its routes
+	prefix string
+	// routes are the routes registered with this JSONRouter and any JSONRouter created from it by subrouting
+	routes *jsonRoutes
 }
 
 // NewJSONRouter returns a new JSONRouter with a logger.
@@ -90,6 +94,7 @@ func NewJSONRouterWithErrorHandler(errHandler JSONErrorHandler) *JSONRouter {
 	return &JSONRouter{
 		Subrouter:  NewRouter().Subrouter(""), // TODO: find a nicer solution to work with routers / subrouters.
 		errHandler: errHandler,
+		routes:     &jsonRoutes{},
 	}
 }
 
@@ -98,6 +103,8 @@ func (j *JSONRouter) Subroute(path string) *JSONRouter {
 	return &JSONRouter{
 		Subrouter:  j.Router.Subrouter(path),
 		errHandler: j.errHandler,
+		prefix:     j.prefix + path,
+		routes:     j.routes,
 	}
 }
 
@@ -107,6 +114,8 @@ func (j *JSONRouter) SubrouteWithErrorHandler(path string, errHandler JSONErrorH
 	return &JSONRouter{
 		Subrouter:  j.Router.Subrouter(path),
 		errHandler: errHandler,
+		prefix:     j.prefix + path,
+		routes:     j.routes,
 	}
 }
 
@@ -139,7 +148,19 @@ func (j *JSONRouter) Handle(path string, handler JSONHandlerFunc, methods ...str
 
 // HandleWithCode works like Handle but allows specifying the response code that's returned for successful requests.
 func (j *JSONRouter) HandleWithCode(path string, handler JSONHandlerFunc, okCode int, methods ...string) *RouteHandler {
-	return j.Router.Handle(path, j.WrapHandlerFunc(handler, okCode), methods...)
+	return j.handle(path, j.WrapHandlerFunc(handler, okCode), jsonRoute{okCode: okCode}, methods...)
+}
+
+// handle registers the handler with the underlying Router, and records the route so it can be described by OpenAPI.
+// Routes which couldn't be registered are not recorded.
+func (j *JSONRouter) handle(path string, handler HandlerFunc, route jsonRoute, methods ...string) *RouteHandler {
+	h := j.Router.Handle(path, handler, methods...)
+	if h != nil && h.node != nil && j.routes != nil {
+		route.path = j.prefix + path
+		route.handler = h
+		j.routes.add(route)
+	}
+	return h
 }
 
 // WrapHandlerFunc wraps a JSONHandlerFunc and returns a regular HandlerFunc.
@@ -0,0 +1,228 @@
+package router
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/grafana/grafana-app-sdk/plugin"
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+// VarTag is the struct tag used by HandleTyped to set a field of the request type to the value of a path var.
+// For example, a field tagged `var:"id"` is set to the value of the "id" var in the route "/widgets/{id}".
+const VarTag = "var"
+
+// TypedHandlerFunc is a JSON request handler which takes a request of type Req, built from the request body and path vars,
+// and returns a response of type Resp or an error. See HandleTyped.
+type TypedHandlerFunc[Req, Resp any] func(ctx context.Context, req Req) (Resp, error)
+
+// HandleTyped attaches a new handler to the route with path and methods methods (GET if no methods are specified),
+// which returns okCode for successful requests.
+//
+// The request body is validated and decoded into a Req in the same way as HandleWithBody,
+// and then each field of Req with a `var` tag (see VarTag) is set to the value of that path var.
+// Fields with a `var` tag are not part of the request body schema, and can be a string, bool, integer or float,
+// or implement encoding.TextUnmarshaler. If a path var can't be converted to the field's type, a 400 error is returned.
+// The handler's response is encoded as JSON, and a nil response results in a 204 No Content response.
+//
+// Errors returned by the handler are mapped to responses in the same way as HandleWithBody.
+//
+// HandleTyped panics if a `var` tag of Req names a var which isn't in the route's path (including the prefix of j),
+// is on a field of an unsupported type, or if the path is invalid. Route conflicts are handled in the same way as Handle.
+func HandleTyped[Req, Resp any](
+	j *JSONRouter, path string, handler TypedHandlerFunc[Req, Resp], okCode int, methods ...string,
+) *RouteHandler {
+	if err := checkVarTypes(varTypes(reflect.TypeFor[Req]()), j.prefix, path); err != nil {
+		panic(err)
+	}
+	return handleWithBody(j, path, func(ctx context.Context, req JSONRequest, body Req) (Resp, error) {
+		if err := setVars(&body, req.Vars); err != nil {
+			var resp Resp
+			return resp, err
+		}
+		return handler(ctx, body)
+	}, okCode, varTypes(reflect.TypeFor[Req]()), methods...)
+}
+
+// typedHandlerError maps an error returned by a typed handler to a plugin.Error.
+// A plugin.Error is returned as-is, resource.APIServerResponseError errors use their status code,
+// and context deadline errors use 504 Gateway Timeout. All other errors are a 500 Internal Server Error.
+func typedHandlerError(err error) error {
+	var perr plugin.Error
+	if errors.As(err, &perr) {
+		return perr
+	}
+	var cast resource.APIServerResponseError
+	if errors.As(err, &cast) && cast.StatusCode() >= http.StatusBadRequest {
+		return plugin.WrapError(cast.StatusCode(), err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return plugin.WrapError(http.StatusGatewayTimeout, err)
+	}
+	return plugin.WrapError(http.StatusInternalServerError, err)
+}
+
+var (
+	textUnmarshalerType   = reflect.TypeFor[encoding.TextUnmarshaler]()
+	errUnsupportedVarType = errors.New("unsupported var field type")
+)
+
+// varTypes returns the types of the fields of the struct t with a `var` tag, keyed by var name
+func varTypes(t reflect.Type) map[string]reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	types := make(map[string]reflect.Type)
+	if t.Kind() != reflect.Struct {
+		return types
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name, ok := field.Tag.Lookup(VarTag); ok && field.IsExported() {
+			types[name] = field.Type
+		} else if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for name, ft := range varTypes(field.Type) {
+				types[name] = ft
+			}
+		}
+	}
+	return types
+}
+
+// checkVarTypes returns an error if any var in types isn't a path var of the route with the prefix and path,
+// or has a type which can't be set from a path var
+func checkVarTypes(types map[string]reflect.Type, prefix string, path string) error {
+	names := make(map[string]struct{})
+	for _, p := range []string{prefix, path} {
+		_, pathNames, err := parsePath(p)
+		if err != nil {
+			return err
+		}
+		for _, name := range pathNames {
+			names[name] = struct{}{}
+		}
+	}
+	for name, t := range types {
+		if _, ok := names[name]; !ok {
+			return fmt.Errorf("var tag '%s' does not match any path var of route '%s'", name, prefix+path)
+		}
+		if !isVarType(t) {
+			return fmt.Errorf("var tag '%s': %w %s", name, errUnsupportedVarType, t)
+		}
+	}
+	return nil
+}
+
+// isVarType returns true if a field of type t can be set from a path var by setVar
+func isVarType(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// setVars sets the fields of the struct which dst points to that have a `var` tag to the value of that var in vars
+func setVars(dst any, vars Vars) error {
+	v := reflect.ValueOf(dst).Elem()
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	return setStructVars(v, vars)
+}
+
+func setStructVars(v reflect.Value, vars Vars) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := field.Tag.Lookup(VarTag)
+		if !ok {
+			// Vars in embedded structs are promoted, as with encoding/json
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err := setStructVars(v.Field(i), vars); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		value, ok := vars[name]
+		if !ok {
+			return fmt.Errorf("field %s has var tag '%s', but the route has no such var", field.Name, name)
+		}
+		if err := setVar(v.Field(i), value); errors.Is(err, errUnsupportedVarType) {
+			return fmt.Errorf("field %s: %w %s", field.Name, err, field.Type)
+		} else if err != nil {
+			return plugin.WrapError(http.StatusBadRequest, fmt.Errorf("invalid value for '%s': %w", name, err))
+		}
+	}
+	return nil
+}
+
+func setVar(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Pointer {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setVar(ptr.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+	if field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return errUnsupportedVarType
+	}
+	return nil
+}
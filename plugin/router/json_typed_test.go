@@ -0,0 +1,167 @@
+package router_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana-app-sdk/plugin"
+	"github.com/grafana/grafana-app-sdk/plugin/router"
+)
+
+type widgetPath struct {
+	Namespace string `var:"namespace"`
+}
+
+type updateWidgetRequest struct {
+	widgetPath
+	ID   int    `var:"id"`
+	Name string `json:"name"`
+	Size *int   `json:"size,omitempty"`
+}
+
+func TestHandleTyped(t *testing.T) {
+	r := router.NewJSONRouter()
+	sub := r.Subroute("/namespaces/{namespace}")
+	router.HandleTyped(sub, "/widgets/{id}", func(_ context.Context, req updateWidgetRequest) (*widget, error) {
+		switch req.Name {
+		case "plugin-error":
+			return nil, plugin.NewError(http.StatusConflict, "conflict")
+		case "api-error":
+			return nil, fmt.Errorf("wrapped: %w", apiError{statusCode: http.StatusNotFound})
+		case "deadline":
+			return nil, context.DeadlineExceeded
+		case "error":
+			return nil, errors.New("something went wrong")
+		default:
+		}
+		size := 0
+		if req.Size != nil {
+			size = *req.Size
+		}
+		return &widget{
+			ID:   fmt.Sprintf("%s/%d", req.Namespace, req.ID),
+			Name: req.Name,
+			Size: size,
+		}, nil
+	}, http.StatusOK, http.MethodPut)
+
+	t.Run("vars and body", func(t *testing.T) {
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:   "/namespaces/default/widgets/42",
+			Method: http.MethodPut,
+			Body:   []byte(`{"name":"foo","size":3}`),
+		})
+		assert.Equal(t, http.StatusOK, resp.Status)
+		got := widget{}
+		require.NoError(t, json.Unmarshal(resp.Body, &got))
+		assert.Equal(t, widget{ID: "default/42", Name: "foo", Size: 3}, got)
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		wantErr    string
+	}{{
+		name:       "invalid var",
+		path:       "/namespaces/default/widgets/foo",
+		body:       `{"name":"foo"}`,
+		wantStatus: http.StatusBadRequest,
+		wantErr:    `invalid value for 'id': strconv.ParseInt: parsing "foo": invalid syntax`,
+	}, {
+		name:       "invalid body",
+		path:       "/namespaces/default/widgets/1",
+		body:       `{"name":1}`,
+		wantStatus: http.StatusBadRequest,
+		wantErr:    `invalid request body: field 'name': value must be a string`,
+	}, {
+		name:       "plugin error",
+		path:       "/namespaces/default/widgets/1",
+		body:       `{"name":"plugin-error"}`,
+		wantStatus: http.StatusConflict,
+		wantErr:    "conflict",
+	}, {
+		name:       "API server error",
+		path:       "/namespaces/default/widgets/1",
+		body:       `{"name":"api-error"}`,
+		wantStatus: http.StatusNotFound,
+		wantErr:    "wrapped: Not Found",
+	}, {
+		name:       "deadline exceeded",
+		path:       "/namespaces/default/widgets/1",
+		body:       `{"name":"deadline"}`,
+		wantStatus: http.StatusGatewayTimeout,
+		wantErr:    "context deadline exceeded",
+	}, {
+		name:       "other error",
+		path:       "/namespaces/default/widgets/1",
+		body:       `{"name":"error"}`,
+		wantStatus: http.StatusInternalServerError,
+		wantErr:    "internal server error",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := callResource(t, r, &backend.CallResourceRequest{
+				Path:   test.path,
+				Method: http.MethodPut,
+				Body:   []byte(test.body),
+			})
+			assert.Equal(t, test.wantStatus, resp.Status)
+			got := router.JSONErrorResponse{}
+			require.NoError(t, json.Unmarshal(resp.Body, &got))
+			assert.Equal(t, router.JSONErrorResponse{Code: test.wantStatus, Error: test.wantErr}, got)
+		})
+	}
+
+	t.Run("OpenAPI", func(t *testing.T) {
+		doc, err := r.OpenAPI(openapi3.Info{Title: "widgets", Version: "v1"})
+		require.NoError(t, err)
+		require.NoError(t, doc.Validate(context.Background()))
+		op := doc.Paths.Value("/namespaces/{namespace}/widgets/{id}").Put
+		require.NotNil(t, op)
+		require.Len(t, op.Parameters, 2)
+		assert.True(t, op.Parameters[0].Value.Schema.Value.Type.Is(openapi3.TypeString))
+		assert.True(t, op.Parameters[1].Value.Schema.Value.Type.Is(openapi3.TypeInteger))
+		body := op.RequestBody.Value.Content.Get(router.ContentTypeJSON).Schema.Value
+		assert.ElementsMatch(t, []string{"name", "size"}, keys(body.Properties))
+		assert.Equal(t, []string{"name"}, body.Required)
+	})
+}
+
+func TestHandleTyped_InvalidVars(t *testing.T) {
+	handler := func(_ context.Context, _ updateWidgetRequest) (*widget, error) {
+		return nil, nil
+	}
+
+	t.Run("var not in path", func(t *testing.T) {
+		assert.Panics(t, func() {
+			router.HandleTyped(router.NewJSONRouter(), "/widgets/{id}", handler, http.StatusOK)
+		})
+	})
+
+	t.Run("var in prefix", func(t *testing.T) {
+		assert.NotPanics(t, func() {
+			router.HandleTyped(router.NewJSONRouter().Subroute("/namespaces/{namespace}"), "/widgets/{id}", handler, http.StatusOK)
+		})
+	})
+
+	t.Run("unsupported var type", func(t *testing.T) {
+		assert.Panics(t, func() {
+			router.HandleTyped(router.NewJSONRouter(), "/widgets/{ids}", func(_ context.Context, _ struct {
+				IDs []string `var:"ids"`
+			}) (*widget, error) {
+				return nil, nil
+			}, http.StatusOK)
+		})
+	})
+}
@@ -2,8 +2,12 @@ package router
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"time"
@@ -11,10 +15,12 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/grafana/grafana-app-sdk/logging"
 	"github.com/grafana/grafana-app-sdk/metrics"
+	"github.com/grafana/grafana-app-sdk/plugin"
 )
 
 // MiddlewareFunc is a function that receives a HandlerFunc and returns another HandlerFunc.
@@ -168,3 +174,107 @@ func NewMetricsMiddleware(cfg metrics.Config, registerer prometheus.Registerer)
 		responseBytes.WithLabelValues(req.Method, routeInfo.Path).Observe(float64(len(resp.Body)))
 	})
 }
+
+// NewRecoveryMiddleware returns a MiddlewareFunc which recovers from panics in downstream handlers.
+// The panic is logged with its stack trace and recorded as an error on the request's span,
+// and a 500 Internal Server Error JSONErrorResponse is sent, unless the handler already sent a response.
+func NewRecoveryMiddleware() MiddlewareFunc {
+	return func(handler HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+			s := &sentTrackingSender{sender: sender}
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				err := fmt.Errorf("panic in handler: %v", r)
+				logging.FromContext(ctx).Error("panic while handling request",
+					"request.http.method", req.Method,
+					"request.http.path", req.Path,
+					"error", err.Error(),
+					"stack", string(debug.Stack()))
+				span := trace.SpanFromContext(ctx)
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				if !s.sent {
+					sendJSONError(ctx, sender, plugin.WrapError(http.StatusInternalServerError, err))
+				}
+			}()
+			handler(ctx, req, s)
+		}
+	}
+}
+
+// NewTimeoutMiddleware returns a MiddlewareFunc which sets a deadline on the context passed to downstream handlers.
+// The deadline is the route's timeout (see RouteHandler.Timeout) if set, or defaultTimeout otherwise.
+// If both are <= 0, no deadline is set.
+// Handlers should stop processing when the context is done. If a handler returns after the deadline without
+// sending a response, a 504 Gateway Timeout JSONErrorResponse is sent.
+func NewTimeoutMiddleware(defaultTimeout time.Duration) MiddlewareFunc {
+	return func(handler HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+			timeout := defaultTimeout
+			if route, ok := ctx.Value(ctxMatchedRouteHandlerKey{}).(*RouteHandler); ok && route.timeout > 0 {
+				timeout = route.timeout
+			}
+			if timeout <= 0 {
+				handler(ctx, req, sender)
+				return
+			}
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			s := &sentTrackingSender{sender: sender}
+			handler(ctx, req, s)
+			if !s.sent && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				sendJSONError(ctx, sender, plugin.NewError(http.StatusGatewayTimeout, "request timed out"))
+			}
+		}
+	}
+}
+
+// NewMaxBodySizeMiddleware returns a MiddlewareFunc which rejects requests with a body larger than the route's
+// maximum body size (see RouteHandler.MaxBodySize) if set, or defaultMaxSize bytes otherwise,
+// by sending a 413 Request Entity Too Large JSONErrorResponse instead of calling the downstream handler.
+// If both are <= 0, the body size isn't limited.
+func NewMaxBodySizeMiddleware(defaultMaxSize int64) MiddlewareFunc {
+	return func(handler HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+			maxSize := defaultMaxSize
+			if route, ok := ctx.Value(ctxMatchedRouteHandlerKey{}).(*RouteHandler); ok && route.maxBodySize > 0 {
+				maxSize = route.maxBodySize
+			}
+			if maxSize > 0 && int64(len(req.Body)) > maxSize {
+				sendJSONError(ctx, sender, plugin.NewError(http.StatusRequestEntityTooLarge,
+					fmt.Sprintf("request body must not be larger than %d bytes", maxSize)))
+				return
+			}
+			handler(ctx, req, sender)
+		}
+	}
+}
+
+// sentTrackingSender is a backend.CallResourceResponseSender which records whether a response has been sent
+type sentTrackingSender struct {
+	sender backend.CallResourceResponseSender
+	sent   bool
+}
+
+func (s *sentTrackingSender) Send(res *backend.CallResourceResponse) error {
+	s.sent = true
+	return s.sender.Send(res)
+}
+
+// sendJSONError sends a JSONErrorResponse for err, with the code of err as the status code
+func sendJSONError(ctx context.Context, sender backend.CallResourceResponseSender, err plugin.Error) {
+	body, _ := json.Marshal(JSONErrorResponse{
+		Code:  err.Code,
+		Error: err.CleanMessage(),
+	})
+	if err := sender.Send(&backend.CallResourceResponse{
+		Status:  err.Code,
+		Headers: map[string][]string{HeaderContentType: {ContentTypeJSON}},
+		Body:    body,
+	}); err != nil {
+		logging.FromContext(ctx).Error("error sending backend response", "error", err)
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana-app-sdk/plugin/router"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -77,3 +79,106 @@ func TestMiddleware_CapturingMiddleware(t *testing.T) {
 	require.NoError(t, err, "no error expected from calling router")
 	require.Equal(t, "12hi!21", string(res.Response.Body))
 }
+
+func TestRecoveryMiddleware(t *testing.T) {
+	r := router.NewRouter()
+	r.Use(router.NewRecoveryMiddleware())
+	r.Handle("/panic", func(ctx context.Context, req *backend.CallResourceRequest, res backend.CallResourceResponseSender) {
+		panic("oops")
+	})
+	r.Handle("/sent", func(ctx context.Context, req *backend.CallResourceRequest, res backend.CallResourceResponseSender) {
+		_ = res.Send(&backend.CallResourceResponse{Status: http.StatusOK})
+		panic("oops")
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		res := &router.CapturingSender{}
+		require.NoError(t, r.CallResource(context.Background(), &backend.CallResourceRequest{Path: "/panic", Method: "GET"}, res))
+		require.NotNil(t, res.Response)
+		assert.Equal(t, http.StatusInternalServerError, res.Response.Status)
+		assert.JSONEq(t, `{"code":500,"error":"internal server error"}`, string(res.Response.Body))
+	})
+
+	t.Run("panic after response sent", func(t *testing.T) {
+		sent := 0
+		require.NoError(t, r.CallResource(context.Background(), &backend.CallResourceRequest{Path: "/sent", Method: "GET"}, fakeSender{
+			sendFunc: func(res *backend.CallResourceResponse) error {
+				sent++
+				assert.Equal(t, http.StatusOK, res.Status)
+				return nil
+			},
+		}))
+		assert.Equal(t, 1, sent)
+	})
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	r := router.NewRouter()
+	r.Use(router.NewTimeoutMiddleware(time.Hour))
+	deadlines := make(chan time.Time, 1)
+	wait := func(ctx context.Context, req *backend.CallResourceRequest, res backend.CallResourceResponseSender) {
+		deadline, _ := ctx.Deadline()
+		deadlines <- deadline
+		<-ctx.Done()
+	}
+	r.Handle("/slow", wait).Timeout(10 * time.Millisecond)
+	r.Handle("/default", func(ctx context.Context, req *backend.CallResourceRequest, res backend.CallResourceResponseSender) {
+		deadline, _ := ctx.Deadline()
+		deadlines <- deadline
+		_ = res.Send(&backend.CallResourceResponse{Status: http.StatusOK})
+	})
+
+	t.Run("route timeout", func(t *testing.T) {
+		res := &router.CapturingSender{}
+		require.NoError(t, r.CallResource(context.Background(), &backend.CallResourceRequest{Path: "/slow", Method: "GET"}, res))
+		assert.WithinDuration(t, time.Now(), <-deadlines, time.Second)
+		require.NotNil(t, res.Response)
+		assert.Equal(t, http.StatusGatewayTimeout, res.Response.Status)
+		assert.JSONEq(t, `{"code":504,"error":"request timed out"}`, string(res.Response.Body))
+	})
+
+	t.Run("default timeout", func(t *testing.T) {
+		res := &router.CapturingSender{}
+		require.NoError(t, r.CallResource(context.Background(), &backend.CallResourceRequest{Path: "/default", Method: "GET"}, res))
+		assert.WithinDuration(t, time.Now().Add(time.Hour), <-deadlines, time.Minute)
+		require.NotNil(t, res.Response)
+		assert.Equal(t, http.StatusOK, res.Response.Status)
+	})
+}
+
+func TestMaxBodySizeMiddleware(t *testing.T) {
+	r := router.NewRouter()
+	r.Use(router.NewMaxBodySizeMiddleware(4))
+	ok := func(ctx context.Context, req *backend.CallResourceRequest, res backend.CallResourceResponseSender) {
+		_ = res.Send(&backend.CallResourceResponse{Status: http.StatusOK})
+	}
+	r.Handle("/small", ok, "POST")
+	r.Handle("/large", ok, "POST").MaxBodySize(8)
+
+	tests := []struct {
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{path: "/small", body: "1234", wantStatus: http.StatusOK},
+		{path: "/small", body: "12345", wantStatus: http.StatusRequestEntityTooLarge,
+			wantBody: `{"code":413,"error":"request body must not be larger than 4 bytes"}`},
+		{path: "/large", body: "12345678", wantStatus: http.StatusOK},
+		{path: "/large", body: "123456789", wantStatus: http.StatusRequestEntityTooLarge,
+			wantBody: `{"code":413,"error":"request body must not be larger than 8 bytes"}`},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s %d bytes", test.path, len(test.body)), func(t *testing.T) {
+			res := &router.CapturingSender{}
+			require.NoError(t, r.CallResource(context.Background(), &backend.CallResourceRequest{
+				Path: test.path, Method: "POST", Body: []byte(test.body),
+			}, res))
+			require.NotNil(t, res.Response)
+			assert.Equal(t, test.wantStatus, res.Response.Status)
+			if test.wantBody != "" {
+				assert.JSONEq(t, test.wantBody, string(res.Response.Body))
+			}
+		})
+	}
+}
@@ -3,13 +3,24 @@ package router
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
 
 	"github.com/grafana/grafana-app-sdk/plugin"
 	"github.com/grafana/grafana-app-sdk/resource"
 )
 
+const (
+	// ContentTypeJSONPatch is the content-type header value for RFC6902 JSON Patch requests.
+	ContentTypeJSONPatch = "application/json-patch+json"
+	// ContentTypeMergePatch is the content-type header value for RFC7386 JSON Merge Patch requests.
+	ContentTypeMergePatch = "application/merge-patch+json"
+)
+
 // Store is the interface for a CRD storage component.
 type Store interface {
 	Add(ctx context.Context, obj resource.Object) (resource.Object, error)
@@ -19,69 +30,139 @@ type Store interface {
 	Delete(ctx context.Context, kind string, identifier resource.Identifier) error
 }
 
+// PatchStore is a Store which supports JSON Patch requests.
+// If the Store used by ResourceGroupRouter implements PatchStore, JSON Patch requests are supported.
+type PatchStore interface {
+	Patch(ctx context.Context, kind string, identifier resource.Identifier, patch resource.PatchRequest,
+		options resource.PatchOptions) (resource.Object, error)
+}
+
+// SubresourceStore is a Store which supports updating subresources.
+// If the Store used by ResourceGroupRouter implements SubresourceStore, subresource PUT requests are supported.
+type SubresourceStore interface {
+	UpdateSubresource(ctx context.Context, kind string, identifier resource.Identifier,
+		subresourceName resource.SubresourceName, obj any) (resource.Object, error)
+}
+
+// ListPageStore is a Store which supports listing a single page of resources.
+// If the Store used by ResourceGroupRouter implements ListPageStore, the limit and continue list query params are supported.
+type ListPageStore interface {
+	ListPage(ctx context.Context, kind string, namespace string, options resource.ListOptions) (resource.ListObject, error)
+}
+
 // ResourceGroupRouter is a Router which exposes generic CRUD routes for every resource contained in a given group.
+// For each kind (and each version of each kind) in the group, it exposes the following routes,
+// where the base route is "{group}/{version}/{plural}":
+//
+// * GET     "{base}"                    - List resources. Supports the labelSelector, fieldSelector, limit, and continue
+// query params, and the allNamespaces query param if AllowAllNamespaces is true
+// * POST    "{base}"                    - Create a resource
+// * GET     "{base}/{name}"             - Get a resource
+// * PUT     "{base}/{name}"             - Update a resource
+// * PATCH   "{base}/{name}"             - Patch a resource with a JSON Patch or JSON Merge Patch (based on the Content-Type)
+// * DELETE  "{base}/{name}"             - Delete a resource
+// * GET     "{base}/{name}/{subresource}" - Get a subresource of a resource
+// * PUT     "{base}/{name}/{subresource}" - Update a subresource of a resource
 type ResourceGroupRouter struct {
 	*JSONRouter
+	// AllowAllNamespaces allows list requests to use the allNamespaces query param to list resources in every namespace,
+	// rather than only the router's namespace. It is false by default, and list requests with allNamespaces=true
+	// are rejected with a 403, as any caller of the routes can list resources in every namespace if it's enabled.
+	AllowAllNamespaces bool
+
 	resourceGroup resource.KindCollection
 	namespace     string
-	store         Store
+	storeFor      func(kind resource.Kind) Store
 }
 
 // NewResourceGroupRouter returns a new ResourceGroupRouter,
 // exposing CRUD routes to manipulate resources in given group.
+// Every version of each kind in the group is served using a client for that version.
 func NewResourceGroupRouter(
 	resourceGroup resource.KindCollection,
 	namespace string,
 	clientGenerator resource.ClientGenerator,
 ) (*ResourceGroupRouter, error) {
-	store := resource.NewStore(clientGenerator, resourceGroup)
+	// A resource.Store tracks a single version of each kind, so use a Store per version
+	stores := make(map[string]*resource.Store)
+	for _, kind := range resourceGroup.Kinds() {
+		store, ok := stores[kind.Version()]
+		if !ok {
+			store = resource.NewStore(clientGenerator)
+			stores[kind.Version()] = store
+		}
+		store.Register(kind)
+	}
 
-	return NewResourceGroupRouterWithStore(resourceGroup, namespace, store)
+	return newResourceGroupRouter(resourceGroup, namespace, func(kind resource.Kind) Store {
+		return stores[kind.Version()]
+	}), nil
 }
 
 // NewResourceGroupRouterWithStore returns a new ResourceGroupRouter with pre-configured Store.
+// The Store is used for every version of each kind in the group, so it must return objects of the version
+// registered for the kind in the store.
 func NewResourceGroupRouterWithStore(
 	resourceGroup resource.KindCollection,
 	namespace string,
 	store Store,
 ) (*ResourceGroupRouter, error) {
+	return newResourceGroupRouter(resourceGroup, namespace, func(resource.Kind) Store {
+		return store
+	}), nil
+}
+
+func newResourceGroupRouter(
+	resourceGroup resource.KindCollection,
+	namespace string,
+	storeFor func(kind resource.Kind) Store,
+) *ResourceGroupRouter {
 	router := &ResourceGroupRouter{
 		JSONRouter:    NewJSONRouter(),
 		resourceGroup: resourceGroup,
-		store:         store,
 		namespace:     namespace,
+		storeFor:      storeFor,
 	}
 
 	for _, schema := range router.resourceGroup.Kinds() {
-		// TODO: all possible versions for each kind should be handled, address this with SchemaGroup in codegen?
 		baseRoute := fmt.Sprintf(
 			"%s/%s/%s",
 			schema.Group(),
 			schema.Version(),
 			schema.Plural(),
 		)
+		store := storeFor(schema)
 
 		router.HandleWithCode(
-			baseRoute, router.createResource(schema), http.StatusAccepted, http.MethodPost,
+			baseRoute, router.createResource(schema, store), http.StatusAccepted, http.MethodPost,
 		)
 		router.Handle(
-			baseRoute, router.listResources(schema), http.MethodGet,
+			baseRoute, router.listResources(schema, store), http.MethodGet,
 		)
 		router.Handle(
-			fmt.Sprintf("%s/{name}", baseRoute), router.getResource(schema), http.MethodGet,
+			fmt.Sprintf("%s/{name}", baseRoute), router.getResource(schema, store), http.MethodGet,
 		)
 		router.HandleWithCode(
-			fmt.Sprintf("%s/{name}", baseRoute), router.updateResource(schema), http.StatusAccepted, http.MethodPut,
+			fmt.Sprintf("%s/{name}", baseRoute), router.updateResource(schema, store), http.StatusAccepted, http.MethodPut,
+		)
+		router.Handle(
+			fmt.Sprintf("%s/{name}", baseRoute), router.patchResource(schema, store), http.MethodPatch,
 		)
 		router.Handle(
-			fmt.Sprintf("%s/{name}", baseRoute), router.deleteResource(schema), http.MethodDelete,
+			fmt.Sprintf("%s/{name}", baseRoute), router.deleteResource(schema, store), http.MethodDelete,
+		)
+		router.Handle(
+			fmt.Sprintf("%s/{name}/{subresource}", baseRoute), router.getSubresource(schema, store), http.MethodGet,
+		)
+		router.HandleWithCode(
+			fmt.Sprintf("%s/{name}/{subresource}", baseRoute), router.updateSubresource(schema, store), http.StatusAccepted, http.MethodPut,
 		)
 	}
 
-	return router, nil
+	return router
 }
 
-func (router *ResourceGroupRouter) createResource(cr resource.Schema) JSONHandlerFunc {
+func (router *ResourceGroupRouter) createResource(cr resource.Schema, store Store) JSONHandlerFunc {
 	return func(ctx context.Context, request JSONRequest) (JSONResponse, error) {
 		toBeInserted := cr.ZeroValue()
 		// TODO: use Unmarshal() method for version stuff
@@ -97,46 +178,88 @@ func (router *ResourceGroupRouter) createResource(cr resource.Schema) JSONHandle
 			Kind:      cr.Kind(),
 		})
 
-		addedResource, err := router.store.Add(ctx, toBeInserted)
+		addedResource, err := store.Add(ctx, toBeInserted)
 		if err != nil {
-			return nil, plugin.WrapError(http.StatusInternalServerError, err)
+			return nil, storeError(err)
 		}
 
 		return addedResource, nil
 	}
 }
 
-func (router *ResourceGroupRouter) listResources(cr resource.Schema) JSONHandlerFunc {
-	return func(ctx context.Context, _ JSONRequest) (JSONResponse, error) {
-		resources, err := router.store.List(ctx, cr.Kind(), resource.StoreListOptions{Namespace: router.namespace}) // TODO: support labels and pagination
+func (router *ResourceGroupRouter) listResources(cr resource.Schema, store Store) JSONHandlerFunc {
+	return func(ctx context.Context, request JSONRequest) (JSONResponse, error) {
+		query := request.URL.Query()
+		namespace := router.namespace
+		if allNamespaces, _ := strconv.ParseBool(query.Get("allNamespaces")); allNamespaces {
+			if !router.AllowAllNamespaces {
+				return nil, plugin.NewError(http.StatusForbidden, "listing resources in all namespaces is not allowed")
+			}
+			namespace = resource.NamespaceAll
+		}
+		options := resource.ListOptions{
+			Continue: query.Get("continue"),
+		}
+		if selector := query.Get("labelSelector"); selector != "" {
+			options.LabelFilters = []string{selector}
+		}
+		if selector := query.Get("fieldSelector"); selector != "" {
+			options.FieldSelectors = []string{selector}
+		}
+		if limit := query.Get("limit"); limit != "" {
+			l, err := strconv.Atoi(limit)
+			if err != nil || l < 0 {
+				return nil, plugin.NewError(http.StatusBadRequest, "limit must be a non-negative integer")
+			}
+			options.Limit = l
+		}
+
+		// Only list a single page if pagination was requested, otherwise return all resources
+		if options.Limit > 0 || options.Continue != "" {
+			pager, ok := store.(ListPageStore)
+			if !ok {
+				return nil, plugin.NewError(http.StatusBadRequest, "pagination is not supported")
+			}
+			resources, err := pager.ListPage(ctx, cr.Kind(), namespace, options)
+			if err != nil {
+				return nil, storeError(err)
+			}
+			return resources, nil
+		}
+
+		resources, err := store.List(ctx, cr.Kind(), resource.StoreListOptions{
+			Namespace:      namespace,
+			Filters:        options.LabelFilters,
+			FieldSelectors: options.FieldSelectors,
+		})
 		if err != nil {
-			return nil, plugin.WrapError(http.StatusInternalServerError, err)
+			return nil, storeError(err)
 		}
 
 		return resources, nil
 	}
 }
 
-func (router *ResourceGroupRouter) getResource(cr resource.Schema) JSONHandlerFunc {
+func (router *ResourceGroupRouter) getResource(cr resource.Schema, store Store) JSONHandlerFunc {
 	return func(ctx context.Context, request JSONRequest) (JSONResponse, error) {
 		name, ok := request.Vars.Get("name")
 		if !ok {
 			return nil, plugin.NewError(http.StatusBadRequest, "must provide resource name")
 		}
 
-		obj, err := router.store.Get(ctx, cr.Kind(), resource.Identifier{
+		obj, err := store.Get(ctx, cr.Kind(), resource.Identifier{
 			Namespace: router.namespace,
 			Name:      name,
 		})
 		if err != nil {
-			return nil, plugin.WrapError(http.StatusInternalServerError, err)
+			return nil, storeError(err)
 		}
 
 		return obj, nil
 	}
 }
 
-func (router *ResourceGroupRouter) updateResource(cr resource.Schema) JSONHandlerFunc {
+func (router *ResourceGroupRouter) updateResource(cr resource.Schema, store Store) JSONHandlerFunc {
 	return func(ctx context.Context, request JSONRequest) (JSONResponse, error) {
 		if _, ok := request.Vars.Get("name"); !ok {
 			return nil, plugin.NewError(http.StatusBadRequest, "must provide resource name")
@@ -155,29 +278,205 @@ func (router *ResourceGroupRouter) updateResource(cr resource.Schema) JSONHandle
 			Kind:      cr.Kind(),
 		})
 
-		updated, err := router.store.Update(ctx, updatedResource)
+		updated, err := store.Update(ctx, updatedResource)
 		if err != nil {
-			return nil, plugin.WrapError(http.StatusInternalServerError, err)
+			return nil, storeError(err)
 		}
 
 		return updated, nil
 	}
 }
 
-func (router *ResourceGroupRouter) deleteResource(cr resource.Schema) JSONHandlerFunc {
+func (router *ResourceGroupRouter) deleteResource(cr resource.Schema, store Store) JSONHandlerFunc {
 	return func(ctx context.Context, request JSONRequest) (JSONResponse, error) {
 		name, ok := request.Vars.Get("name")
 		if !ok {
 			return nil, plugin.NewError(http.StatusBadRequest, "must provide resource name")
 		}
 
-		if err := router.store.Delete(ctx, cr.Kind(), resource.Identifier{
+		if err := store.Delete(ctx, cr.Kind(), resource.Identifier{
 			Namespace: router.namespace,
 			Name:      name,
 		}); err != nil {
-			return nil, plugin.WrapError(http.StatusInternalServerError, err)
+			return nil, storeError(err)
 		}
 
 		return nil, nil
 	}
 }
+
+func (router *ResourceGroupRouter) patchResource(cr resource.Schema, store Store) JSONHandlerFunc {
+	return func(ctx context.Context, request JSONRequest) (JSONResponse, error) {
+		name, ok := request.Vars.Get("name")
+		if !ok {
+			return nil, plugin.NewError(http.StatusBadRequest, "must provide resource name")
+		}
+		identifier := resource.Identifier{
+			Namespace: router.namespace,
+			Name:      name,
+		}
+
+		contentType, _, _ := mime.ParseMediaType(request.Headers.Get(HeaderContentType))
+		switch contentType {
+		case ContentTypeJSONPatch:
+			patcher, ok := store.(PatchStore)
+			if !ok {
+				return nil, plugin.NewError(http.StatusUnsupportedMediaType, "JSON patch is not supported")
+			}
+			patch := resource.PatchRequest{}
+			if err := json.NewDecoder(request.Body).Decode(&patch.Operations); err != nil {
+				return nil, plugin.WrapError(http.StatusBadRequest, err)
+			}
+			patched, err := patcher.Patch(ctx, cr.Kind(), identifier, patch, resource.PatchOptions{})
+			if err != nil {
+				return nil, storeError(err)
+			}
+			return patched, nil
+		case ContentTypeMergePatch:
+			return router.mergePatchResource(ctx, cr, store, identifier, request.Body)
+		default:
+			return nil, plugin.NewError(http.StatusUnsupportedMediaType,
+				fmt.Sprintf("content type must be %s or %s", ContentTypeJSONPatch, ContentTypeMergePatch))
+		}
+	}
+}
+
+// mergePatchResource applies a JSON Merge Patch to the current resource, and updates the resource with the result.
+// The update uses the ResourceVersion of the current resource (unless the patch sets one),
+// so it fails with a conflict if the resource is changed between the get and the update.
+func (router *ResourceGroupRouter) mergePatchResource(
+	ctx context.Context, cr resource.Schema, store Store, identifier resource.Identifier, body io.Reader,
+) (JSONResponse, error) {
+	var patch any
+	if err := json.NewDecoder(body).Decode(&patch); err != nil {
+		return nil, plugin.WrapError(http.StatusBadRequest, err)
+	}
+
+	current, err := store.Get(ctx, cr.Kind(), identifier)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	currentBytes, err := json.Marshal(current)
+	if err != nil {
+		return nil, plugin.WrapError(http.StatusInternalServerError, err)
+	}
+	var original any
+	if err := json.Unmarshal(currentBytes, &original); err != nil {
+		return nil, plugin.WrapError(http.StatusInternalServerError, err)
+	}
+	patchedBytes, err := json.Marshal(mergePatch(original, patch))
+	if err != nil {
+		return nil, plugin.WrapError(http.StatusInternalServerError, err)
+	}
+
+	patched := cr.ZeroValue()
+	if err := json.Unmarshal(patchedBytes, patched); err != nil {
+		return nil, plugin.WrapError(http.StatusBadRequest, err)
+	}
+	// The static metadata of the resource cannot be changed by a patch
+	patched.SetStaticMetadata(resource.StaticMetadata{
+		Name:      identifier.Name,
+		Namespace: identifier.Namespace,
+		Group:     cr.Group(),
+		Version:   cr.Version(),
+		Kind:      cr.Kind(),
+	})
+
+	updated, err := store.Update(ctx, patched)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	return updated, nil
+}
+
+func (router *ResourceGroupRouter) getSubresource(cr resource.Schema, store Store) JSONHandlerFunc {
+	return func(ctx context.Context, request JSONRequest) (JSONResponse, error) {
+		name, ok := request.Vars.Get("name")
+		if !ok {
+			return nil, plugin.NewError(http.StatusBadRequest, "must provide resource name")
+		}
+		subresource, ok := request.Vars.Get("subresource")
+		if !ok {
+			return nil, plugin.NewError(http.StatusBadRequest, "must provide subresource name")
+		}
+
+		obj, err := store.Get(ctx, cr.Kind(), resource.Identifier{
+			Namespace: router.namespace,
+			Name:      name,
+		})
+		if err != nil {
+			return nil, storeError(err)
+		}
+
+		sr, ok := obj.GetSubresource(subresource)
+		if !ok {
+			return nil, plugin.NewError(http.StatusNotFound, fmt.Sprintf("subresource '%s' not found", subresource))
+		}
+
+		return sr, nil
+	}
+}
+
+func (router *ResourceGroupRouter) updateSubresource(cr resource.Schema, store Store) JSONHandlerFunc {
+	return func(ctx context.Context, request JSONRequest) (JSONResponse, error) {
+		name, ok := request.Vars.Get("name")
+		if !ok {
+			return nil, plugin.NewError(http.StatusBadRequest, "must provide resource name")
+		}
+		subresource, ok := request.Vars.Get("subresource")
+		if !ok {
+			return nil, plugin.NewError(http.StatusBadRequest, "must provide subresource name")
+		}
+
+		updater, ok := store.(SubresourceStore)
+		if !ok {
+			return nil, plugin.NewError(http.StatusMethodNotAllowed, "updating subresources is not supported")
+		}
+
+		var sr json.RawMessage
+		if err := json.NewDecoder(request.Body).Decode(&sr); err != nil {
+			return nil, plugin.WrapError(http.StatusBadRequest, err)
+		}
+
+		updated, err := updater.UpdateSubresource(ctx, cr.Kind(), resource.Identifier{
+			Namespace: router.namespace,
+			Name:      name,
+		}, resource.SubresourceName(subresource), sr)
+		if err != nil {
+			return nil, storeError(err)
+		}
+
+		return updated, nil
+	}
+}
+
+// mergePatch applies an RFC7386 JSON Merge Patch to target, both being unmarshaled JSON values
+func mergePatch(target, patch any) any {
+	patchMap, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetMap, ok := target.(map[string]any)
+	if !ok {
+		targetMap = make(map[string]any)
+	}
+	for k, v := range patchMap {
+		if v == nil {
+			delete(targetMap, k)
+		} else {
+			targetMap[k] = mergePatch(targetMap[k], v)
+		}
+	}
+	return targetMap
+}
+
+// storeError wraps an error returned by a Store, using the status code of the error if it is an API server error,
+// and 500 otherwise.
+func storeError(err error) error {
+	var cast resource.APIServerResponseError
+	if errors.As(err, &cast) && cast.StatusCode() >= http.StatusBadRequest {
+		return plugin.WrapError(cast.StatusCode(), err)
+	}
+	return plugin.WrapError(http.StatusInternalServerError, err)
+}
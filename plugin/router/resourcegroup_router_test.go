@@ -452,3 +452,353 @@ func TestResourceGroupRouter_Delete(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+type fakeExtendedStore struct {
+	fakeStore
+	patchFunc             func(ctx context.Context, kind string, identifier resource.Identifier, patch resource.PatchRequest) (resource.Object, error)
+	updateSubresourceFunc func(ctx context.Context, kind string, identifier resource.Identifier, subresource resource.SubresourceName, obj any) (resource.Object, error)
+	listPageFunc          func(ctx context.Context, kind string, namespace string, options resource.ListOptions) (resource.ListObject, error)
+}
+
+func (s fakeExtendedStore) Patch(ctx context.Context, kind string, identifier resource.Identifier, patch resource.PatchRequest, _ resource.PatchOptions) (resource.Object, error) {
+	return s.patchFunc(ctx, kind, identifier, patch)
+}
+
+func (s fakeExtendedStore) UpdateSubresource(ctx context.Context, kind string, identifier resource.Identifier, subresource resource.SubresourceName, obj any) (resource.Object, error) {
+	return s.updateSubresourceFunc(ctx, kind, identifier, subresource, obj)
+}
+
+func (s fakeExtendedStore) ListPage(ctx context.Context, kind string, namespace string, options resource.ListOptions) (resource.ListObject, error) {
+	return s.listPageFunc(ctx, kind, namespace, options)
+}
+
+type apiError struct {
+	statusCode int
+}
+
+func (e apiError) Error() string {
+	return http.StatusText(e.statusCode)
+}
+
+func (e apiError) StatusCode() int {
+	return e.statusCode
+}
+
+// callResource calls the router with the request, and returns the response sent by the router
+func callResource(t *testing.T, r backend.CallResourceHandler, req *backend.CallResourceRequest) *backend.CallResourceResponse {
+	var resp *backend.CallResourceResponse
+	require.NoError(t, r.CallResource(context.Background(), req, fakeSender{
+		sendFunc: func(response *backend.CallResourceResponse) error {
+			resp = response
+			return nil
+		},
+	}))
+	require.NotNil(t, resp)
+	return resp
+}
+
+func getTestObject(name string) *Test {
+	test := testResource.ZeroValue().(*Test)
+	test.ObjectMeta.Name = name
+	test.ObjectMeta.Namespace = metav1.NamespaceDefault
+	test.ObjectMeta.ResourceVersion = "1"
+	test.Spec.SomeInfo = "some_info"
+	test.Status.Status = "ok"
+	return test
+}
+
+func TestResourceGroupRouter_ListOptions(t *testing.T) {
+	t.Run("selectors and all namespaces", func(t *testing.T) {
+		r, err := router.NewResourceGroupRouterWithStore(testResourceGroup, metav1.NamespaceDefault, fakeStore{
+			listFunc: func(ctx context.Context, kind string, options resource.StoreListOptions) (resource.ListObject, error) {
+				assert.Equal(t, resource.NamespaceAll, options.Namespace)
+				assert.Equal(t, []string{"a=b,c in (d,e)"}, options.Filters)
+				assert.Equal(t, []string{"spec.foo=bar"}, options.FieldSelectors)
+				return &resource.UntypedList{}, nil
+			},
+		})
+		require.NoError(t, err)
+		r.AllowAllNamespaces = true
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:   "test.resource/v1/tests",
+			URL:    "test.resource/v1/tests?labelSelector=a%3Db%2Cc+in+%28d%2Ce%29&fieldSelector=spec.foo%3Dbar&allNamespaces=true",
+			Method: http.MethodGet,
+		})
+		assert.Equal(t, http.StatusOK, resp.Status)
+	})
+
+	t.Run("all namespaces not allowed", func(t *testing.T) {
+		r, err := router.NewResourceGroupRouterWithStore(testResourceGroup, metav1.NamespaceDefault, fakeStore{
+			listFunc: func(ctx context.Context, kind string, options resource.StoreListOptions) (resource.ListObject, error) {
+				t.Fatal("list should not be called")
+				return nil, nil
+			},
+		})
+		require.NoError(t, err)
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:   "test.resource/v1/tests",
+			URL:    "test.resource/v1/tests?allNamespaces=true",
+			Method: http.MethodGet,
+		})
+		assert.Equal(t, http.StatusForbidden, resp.Status)
+	})
+
+	t.Run("pagination not supported", func(t *testing.T) {
+		r, err := router.NewResourceGroupRouterWithStore(testResourceGroup, metav1.NamespaceDefault, fakeStore{})
+		require.NoError(t, err)
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:   "test.resource/v1/tests",
+			URL:    "test.resource/v1/tests?limit=10",
+			Method: http.MethodGet,
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		r, err := router.NewResourceGroupRouterWithStore(testResourceGroup, metav1.NamespaceDefault, fakeExtendedStore{})
+		require.NoError(t, err)
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:   "test.resource/v1/tests",
+			URL:    "test.resource/v1/tests?limit=foo",
+			Method: http.MethodGet,
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.Status)
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		r, err := router.NewResourceGroupRouterWithStore(testResourceGroup, metav1.NamespaceDefault, fakeExtendedStore{
+			listPageFunc: func(ctx context.Context, kind string, namespace string, options resource.ListOptions) (resource.ListObject, error) {
+				assert.Equal(t, "Test", kind)
+				assert.Equal(t, metav1.NamespaceDefault, namespace)
+				assert.Equal(t, 10, options.Limit)
+				assert.Equal(t, "abc", options.Continue)
+				return &resource.UntypedList{ListMeta: metav1.ListMeta{Continue: "def"}}, nil
+			},
+		})
+		require.NoError(t, err)
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:   "test.resource/v1/tests",
+			URL:    "test.resource/v1/tests?limit=10&continue=abc",
+			Method: http.MethodGet,
+		})
+		assert.Equal(t, http.StatusOK, resp.Status)
+		list := resource.UntypedList{}
+		require.NoError(t, json.Unmarshal(resp.Body, &list))
+		assert.Equal(t, "def", list.GetContinue())
+	})
+}
+
+func TestResourceGroupRouter_Patch(t *testing.T) {
+	t.Run("unsupported content type", func(t *testing.T) {
+		r, err := router.NewResourceGroupRouterWithStore(testResourceGroup, metav1.NamespaceDefault, fakeExtendedStore{})
+		require.NoError(t, err)
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:    "test.resource/v1/tests/some_test",
+			Method:  http.MethodPatch,
+			Headers: map[string][]string{"Content-Type": {"application/json"}},
+			Body:    []byte(`{}`),
+		})
+		assert.Equal(t, http.StatusUnsupportedMediaType, resp.Status)
+	})
+
+	t.Run("JSON patch not supported by store", func(t *testing.T) {
+		r, err := router.NewResourceGroupRouterWithStore(testResourceGroup, metav1.NamespaceDefault, fakeStore{})
+		require.NoError(t, err)
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:    "test.resource/v1/tests/some_test",
+			Method:  http.MethodPatch,
+			Headers: map[string][]string{"Content-Type": {router.ContentTypeJSONPatch}},
+			Body:    []byte(`[]`),
+		})
+		assert.Equal(t, http.StatusUnsupportedMediaType, resp.Status)
+	})
+
+	t.Run("JSON patch", func(t *testing.T) {
+		r, err := router.NewResourceGroupRouterWithStore(testResourceGroup, metav1.NamespaceDefault, fakeExtendedStore{
+			patchFunc: func(ctx context.Context, kind string, identifier resource.Identifier, patch resource.PatchRequest) (resource.Object, error) {
+				assert.Equal(t, "Test", kind)
+				assert.Equal(t, resource.Identifier{Namespace: metav1.NamespaceDefault, Name: "some_test"}, identifier)
+				assert.Equal(t, []resource.PatchOperation{{Path: "/spec/some_info", Operation: resource.PatchOpReplace, Value: "patched"}}, patch.Operations)
+				obj := getTestObject(identifier.Name)
+				obj.Spec.SomeInfo = "patched"
+				return obj, nil
+			},
+		})
+		require.NoError(t, err)
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:    "test.resource/v1/tests/some_test",
+			Method:  http.MethodPatch,
+			Headers: map[string][]string{"Content-Type": {router.ContentTypeJSONPatch}},
+			Body:    []byte(`[{"op":"replace","path":"/spec/some_info","value":"patched"}]`),
+		})
+		assert.Equal(t, http.StatusOK, resp.Status)
+		patched := Test{}
+		require.NoError(t, json.Unmarshal(resp.Body, &patched))
+		assert.Equal(t, "patched", patched.Spec.SomeInfo)
+	})
+
+	t.Run("merge patch", func(t *testing.T) {
+		r, err := router.NewResourceGroupRouterWithStore(testResourceGroup, metav1.NamespaceDefault, fakeStore{
+			getFunc: func(ctx context.Context, kind string, identifier resource.Identifier) (resource.Object, error) {
+				obj := getTestObject(identifier.Name)
+				obj.SetLabels(map[string]string{"a": "b", "c": "d"})
+				return obj, nil
+			},
+			updateFunc: func(ctx context.Context, obj resource.Object) (resource.Object, error) {
+				test := obj.(*Test)
+				assert.Equal(t, "patched", test.Spec.SomeInfo)
+				assert.Equal(t, "ok", test.Status.Status)
+				assert.Equal(t, map[string]string{"a": "e"}, test.GetLabels())
+				assert.Equal(t, "1", test.GetResourceVersion())
+				assert.Equal(t, "some_test", test.GetName())
+				assert.Equal(t, metav1.NamespaceDefault, test.GetNamespace())
+				return test, nil
+			},
+		})
+		require.NoError(t, err)
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:    "test.resource/v1/tests/some_test",
+			Method:  http.MethodPatch,
+			Headers: map[string][]string{"Content-Type": {router.ContentTypeMergePatch}},
+			Body:    []byte(`{"metadata":{"name":"other","labels":{"a":"e","c":null}},"spec":{"some_info":"patched"}}`),
+		})
+		assert.Equal(t, http.StatusOK, resp.Status)
+	})
+
+	t.Run("merge patch conflict", func(t *testing.T) {
+		r, err := router.NewResourceGroupRouterWithStore(testResourceGroup, metav1.NamespaceDefault, fakeStore{
+			getFunc: func(ctx context.Context, kind string, identifier resource.Identifier) (resource.Object, error) {
+				return getTestObject(identifier.Name), nil
+			},
+			updateFunc: func(ctx context.Context, obj resource.Object) (resource.Object, error) {
+				return nil, apiError{statusCode: http.StatusConflict}
+			},
+		})
+		require.NoError(t, err)
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:    "test.resource/v1/tests/some_test",
+			Method:  http.MethodPatch,
+			Headers: map[string][]string{"Content-Type": {router.ContentTypeMergePatch}},
+			Body:    []byte(`{"spec":{"some_info":"patched"}}`),
+		})
+		assert.Equal(t, http.StatusConflict, resp.Status)
+	})
+}
+
+func TestResourceGroupRouter_Subresources(t *testing.T) {
+	store := fakeExtendedStore{
+		fakeStore: fakeStore{
+			getFunc: func(ctx context.Context, kind string, identifier resource.Identifier) (resource.Object, error) {
+				if identifier.Name != "some_test" {
+					return nil, apiError{statusCode: http.StatusNotFound}
+				}
+				return getTestObject(identifier.Name), nil
+			},
+		},
+		updateSubresourceFunc: func(ctx context.Context, kind string, identifier resource.Identifier, subresource resource.SubresourceName, obj any) (resource.Object, error) {
+			assert.Equal(t, "Test", kind)
+			assert.Equal(t, "some_test", identifier.Name)
+			assert.Equal(t, resource.SubresourceStatus, subresource)
+			raw, err := json.Marshal(obj)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"status":"updated"}`, string(raw))
+			updated := getTestObject(identifier.Name)
+			updated.Status.Status = "updated"
+			return updated, nil
+		},
+	}
+	r, err := router.NewResourceGroupRouterWithStore(testResourceGroup, metav1.NamespaceDefault, store)
+	require.NoError(t, err)
+
+	t.Run("get", func(t *testing.T) {
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:   "test.resource/v1/tests/some_test/status",
+			Method: http.MethodGet,
+		})
+		assert.Equal(t, http.StatusOK, resp.Status)
+		assert.JSONEq(t, `{"status":"ok"}`, string(resp.Body))
+	})
+
+	t.Run("get unknown subresource", func(t *testing.T) {
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:   "test.resource/v1/tests/some_test/foo",
+			Method: http.MethodGet,
+		})
+		assert.Equal(t, http.StatusNotFound, resp.Status)
+	})
+
+	t.Run("get missing resource", func(t *testing.T) {
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:   "test.resource/v1/tests/other_test/status",
+			Method: http.MethodGet,
+		})
+		assert.Equal(t, http.StatusNotFound, resp.Status)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:   "test.resource/v1/tests/some_test/status",
+			Method: http.MethodPut,
+			Body:   []byte(`{"status":"updated"}`),
+		})
+		assert.Equal(t, http.StatusAccepted, resp.Status)
+		updated := Test{}
+		require.NoError(t, json.Unmarshal(resp.Body, &updated))
+		assert.Equal(t, "updated", updated.Status.Status)
+	})
+
+	t.Run("update not supported by store", func(t *testing.T) {
+		r, err := router.NewResourceGroupRouterWithStore(testResourceGroup, metav1.NamespaceDefault, fakeStore{})
+		require.NoError(t, err)
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:   "test.resource/v1/tests/some_test/status",
+			Method: http.MethodPut,
+			Body:   []byte(`{"status":"updated"}`),
+		})
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.Status)
+	})
+}
+
+// versionClient is a resource.Client which returns objects of its kind's version from Get
+type versionClient struct {
+	resource.Client
+	kind resource.Kind
+}
+
+func (c *versionClient) Get(_ context.Context, identifier resource.Identifier) (resource.Object, error) {
+	obj := c.kind.ZeroValue()
+	obj.SetStaticMetadata(resource.StaticMetadata{
+		Group:     c.kind.Group(),
+		Version:   c.kind.Version(),
+		Kind:      c.kind.Kind(),
+		Namespace: identifier.Namespace,
+		Name:      identifier.Name,
+	})
+	return obj, nil
+}
+
+type versionClientGenerator struct{}
+
+func (versionClientGenerator) ClientFor(kind resource.Kind) (resource.Client, error) {
+	return &versionClient{kind: kind}, nil
+}
+
+func TestResourceGroupRouter_MultipleVersions(t *testing.T) {
+	v2 := resource.Kind{
+		Schema: resource.NewSimpleSchema("test.resource", "v2", &Test{}, &resource.UntypedList{}, resource.WithKind("Test")),
+		Codecs: map[resource.KindEncoding]resource.Codec{resource.KindEncodingJSON: resource.NewJSONCodec()},
+	}
+	r, err := router.NewResourceGroupRouter(&rg{[]resource.Kind{testResource, v2}}, metav1.NamespaceDefault, versionClientGenerator{})
+	require.NoError(t, err)
+
+	for _, version := range []string{"v1", "v2"} {
+		resp := callResource(t, r, &backend.CallResourceRequest{
+			Path:   "test.resource/" + version + "/tests/some_test",
+			Method: http.MethodGet,
+		})
+		require.Equal(t, http.StatusOK, resp.Status)
+		obj := Test{}
+		require.NoError(t, json.Unmarshal(resp.Body, &obj))
+		assert.Equal(t, "test.resource/"+version, obj.APIVersion)
+	}
+}
@@ -7,9 +7,13 @@ import (
 	"net/http"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/grafana-app-sdk/logging"
 )
 
 var replArgRegex = regexp.MustCompile(`\{([^\}^\:]+):?([^\}^\:]*)\}`)
@@ -26,12 +30,27 @@ var DefaultNotFoundHandler HandlerFunc = func(
 	})
 }
 
+// DefaultMethodNotAllowedHandler is the handler that is used for handling requests when the path matches
+// one or more routes, but none of them handle the request's method. It responds with a 405 Method Not Allowed
+// and an Allow header listing the methods the routes do handle (see AllowedMethodsFromContext).
+// This can be overridden in the Router.
+var DefaultMethodNotAllowedHandler HandlerFunc = func(
+	ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender,
+) {
+	_ = sender.Send(&backend.CallResourceResponse{
+		Status:  http.StatusMethodNotAllowed,
+		Headers: map[string][]string{"Allow": {strings.Join(AllowedMethodsFromContext(ctx), ", ")}},
+		Body:    []byte(fmt.Sprintf("method %s not allowed for path %s", req.Method, req.Path)),
+	})
+}
+
 // NewRouter returns a new Router
 func NewRouter() *Router {
 	return &Router{
-		NotFoundHandler: DefaultNotFoundHandler,
-		subrouters:      make([]*Subrouter, 0),
-		routes:          make([]*RouteHandler, 0),
+		NotFoundHandler:         DefaultNotFoundHandler,
+		MethodNotAllowedHandler: DefaultMethodNotAllowedHandler,
+		tree:                    newRouteNode(),
+		routes:                  make([]*RouteHandler, 0),
 	}
 }
 
@@ -46,14 +65,22 @@ type HandlerFunc func(context.Context, *backend.CallResourceRequest, backend.Cal
 // Router is a simple request router specific to the grafana plugin SDK backend.CallResourceRequest HTTP calls.
 // It allows the user to treat the grafana plugin backend as a traditional HTTP server,
 // registering routes and using path parameters as normal.
+//
+// Routes are matched segment by segment using a tree, so matching time doesn't grow with the number of routes.
+// In each segment, static segments take precedence over segments with path vars, which take precedence over wildcards,
+// and subrouters take precedence over the routes of their parent router.
 type Router struct {
 	// Handler called when there's no route match.
 	NotFoundHandler HandlerFunc
 
-	// Nested routers with a matching path.
-	subrouters []*Subrouter
+	// Handler called when the path matches a route, but not the method.
+	// If nil, NotFoundHandler is used instead.
+	MethodNotAllowedHandler HandlerFunc
+
+	// tree is the tree of routes and subrouters to match requests against.
+	tree *routeNode
 
-	// Routes to be matched.
+	// Routes registered with this router, in registration order.
 	routes []*RouteHandler
 
 	// Slice of middlewares to be called after a match is found.
@@ -64,91 +91,90 @@ type Router struct {
 // meant for being registered a with Subrouter() on either a Router or Subrouter.
 type Subrouter struct {
 	Router
-	matcher      *regexp.Regexp
 	pathArgNames []string
 	path         string
 }
 
+// Path returns the path prefix of the Subrouter, as provided when creating it
 func (r *Subrouter) Path() string {
 	return r.path
 }
 
 // Subrouter creates and returns a Subrouter for the given path prefix.
 // All handlers registered with the Subrouter will have the prefix added implicitly.
+// The prefix may contain path vars, but not a wildcard.
+// If the prefix is invalid, the error is logged and the returned Subrouter is not attached to r,
+// so its routes are never matched. Use AddSubrouter to handle the error instead.
 func (r *Router) Subrouter(path string) *Subrouter {
-	pathArgNames := make([]string, 0)
-	// Look for path replacement vars ({...})
-	matches := replArgRegex.FindAllStringSubmatch(path, -1)
-	if len(matches) > 0 {
-		// Check each match, replace it with regex, and add the match to our list of pathArgNames
-		for _, match := range matches {
-			if len(match) != 3 {
-				continue
-			}
-			// Element 0 is the whole matched string which needs replacing in the path
-			// Element 1 is the variable name
-			// Element 2 is the match expression (may be empty, in which case it should be `([^\/]+)`)
-			repl := `([^\/]+)`
-			if match[2] != "" {
-				repl = fmt.Sprintf("(%s)", match[2])
-			}
-			path = strings.ReplaceAll(path, match[0], repl)
-			pathArgNames = append(pathArgNames, match[1])
-		}
+	sr, err := r.AddSubrouter(path)
+	if err != nil {
+		logging.DefaultLogger.Error("invalid subrouter path, subrouter will not be used", "path", path, "error", err)
+		return r.newSubrouter(path, nil)
 	}
-	regex, err := regexp.Compile(fmt.Sprintf(`^%s`, path))
+	return sr
+}
+
+// AddSubrouter creates and returns a Subrouter for the given path prefix, like Subrouter,
+// but returns an error if the prefix is invalid.
+func (r *Router) AddSubrouter(path string) (*Subrouter, error) {
+	segments, pathArgNames, err := parsePath(path)
 	if err != nil {
-		// TODO
-		return nil
+		return nil, err
 	}
+	sr := r.newSubrouter(path, pathArgNames)
+	if err := r.tree.addSubrouter(segments, sr); err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
 
-	sr := &Subrouter{
+func (r *Router) newSubrouter(path string, pathArgNames []string) *Subrouter {
+	return &Subrouter{
 		Router: Router{
-			NotFoundHandler: r.NotFoundHandler,
-			subrouters:      make([]*Subrouter, 0),
-			routes:          make([]*RouteHandler, 0),
+			NotFoundHandler:         r.NotFoundHandler,
+			MethodNotAllowedHandler: r.MethodNotAllowedHandler,
+			tree:                    newRouteNode(),
+			routes:                  make([]*RouteHandler, 0),
 		},
 		pathArgNames: pathArgNames,
-		matcher:      regex,
 		path:         path,
 	}
-
-	r.subrouters = append(r.subrouters, sr)
-	return sr
 }
 
 // Handle registers a handler to a given path and method(s). If no method(s) are specified, GET is implicitly used.
+//
+// Path vars are written as {name}, which matches any non-empty path segment, or {name:regex}, which matches
+// a path segment (or part of one) matching regex. The last segment of the path may be a wildcard, written as {name...},
+// which matches the remainder of the path (including slashes).
+//
+// If the path is invalid, Handle logs the error and returns nil.
+// If a route with the same path (ignoring var names) already handles one of the methods, Handle logs the conflict
+// and keeps the existing route, returning a RouteHandler which is never matched.
+// Use AddRoute to handle these errors instead.
 func (r *Router) Handle(path string, handler HandlerFunc, methods ...string) *RouteHandler {
-	providedPath := path
-	// Normalize empty path to root.
-	if path == "" {
-		path = "/"
+	h, err := r.addRoute(path, handler, methods...)
+	if err != nil {
+		logging.DefaultLogger.Error("unable to add route", "path", path, "error", err)
 	}
+	return h
+}
 
-	pathArgNames := make([]string, 0)
-	// Look for path replacement vars ({...})
-	matches := replArgRegex.FindAllStringSubmatch(path, -1)
-	if len(matches) > 0 {
-		// Check each match, replace it with regex, and add the match to our list of pathArgNames
-		for _, match := range matches {
-			if len(match) != 3 {
-				continue
-			}
-			// Element 0 is the whole matched string which needs replacing in the path
-			// Element 1 is the variable name
-			// Element 2 is the match expression (may be empty, in which case it should be `([^\/]+)`)
-			repl := `([^\/]+)`
-			if match[2] != "" {
-				repl = fmt.Sprintf("(%s)", match[2])
-			}
-			path = strings.ReplaceAll(path, match[0], repl)
-			pathArgNames = append(pathArgNames, match[1])
-		}
+// AddRoute registers a handler to a given path and method(s), like Handle, but returns an error if the path is invalid,
+// or if a route with the same path (ignoring var names) already handles one of the methods (see ErrRouteConflict).
+func (r *Router) AddRoute(path string, handler HandlerFunc, methods ...string) (*RouteHandler, error) {
+	h, err := r.addRoute(path, handler, methods...)
+	if err != nil {
+		return nil, err
 	}
-	regex, err := regexp.Compile(fmt.Sprintf(`^%s$`, path))
+	return h, nil
+}
+
+// addRoute registers the handler. If the route conflicts with an existing route, it returns the error
+// along with the unregistered RouteHandler.
+func (r *Router) addRoute(path string, handler HandlerFunc, methods ...string) (*RouteHandler, error) {
+	segments, pathArgNames, err := parsePath(path)
 	if err != nil {
-		// TODO
-		return nil
+		return nil, err
 	}
 
 	// Methods
@@ -161,16 +187,22 @@ func (r *Router) Handle(path string, handler HandlerFunc, methods ...string) *Ro
 	}
 
 	h := &RouteHandler{
-		matcher:      regex,
 		handleFunc:   handler,
 		pathArgNames: pathArgNames,
 		methods:      m,
-		path:         providedPath,
+		path:         path,
+	}
+	if h.path == "" {
+		// Normalize empty path to root.
+		h.path = "/"
 	}
 
+	if err := r.tree.addRoute(segments, h); err != nil {
+		return h, err
+	}
 	r.routes = append(r.routes, h)
 
-	return h
+	return h, nil
 }
 
 // RouteByName gets a RouteHandler by its name, if assigned.
@@ -184,69 +216,120 @@ func (r *Router) RouteByName(name string) *RouteHandler {
 	return nil
 }
 
+// routeMatch is the state of matching a request against a Router's tree
+type routeMatch struct {
+	ctx              context.Context
+	method           string
+	matchedPath      string
+	applyMiddlewares []middleware
+	// handler is the matched handler (with middlewares applied), and ctx is its context, if a match is found
+	handler HandlerFunc
+	// allowed are the methods of routes which match the path, but not the method
+	allowed map[string]struct{}
+}
+
+// getHandler returns the handler for the path and method, and the context to call it with.
+// If no route matches, it returns a nil handler, and the methods of any routes which match the path but not the method.
+//
 //nolint:lll
-func (r *Router) getHandler(ctx context.Context, path string, method string, matchedPath string, applyMiddlewares ...middleware) (context.Context, HandlerFunc) {
+func (r *Router) getHandler(ctx context.Context, path string, method string, matchedPath string, applyMiddlewares ...middleware) (context.Context, HandlerFunc, map[string]struct{}) {
+	m := &routeMatch{
+		ctx:              ctx,
+		method:           method,
+		matchedPath:      matchedPath,
+		applyMiddlewares: applyMiddlewares,
+		allowed:          make(map[string]struct{}),
+	}
+	if r.match(m, r.tree, splitRequestPath(path), nil) {
+		return m.ctx, m.handler, nil
+	}
+	return ctx, nil, m.allowed
+}
+
+// match matches the remaining segments against the node n, backtracking to lower precedence children
+// if a child doesn't lead to a match. values are the values of the path vars matched so far.
+func (r *Router) match(m *routeMatch, n *routeNode, segments []string, values []string) bool {
 	// Check subrouters
-	for _, h := range r.subrouters {
-		if matches := h.matcher.FindStringSubmatch(path); len(matches) > 0 {
-			for i := 1; i < len(matches); i++ {
-				if i > len(h.pathArgNames) {
-					break
-				}
-
-				ctx = CtxWithVar(ctx, h.pathArgNames[i-1], matches[i])
-			}
+	for _, sr := range n.subrouters {
+		ctx := m.ctx
+		for i, name := range sr.pathArgNames {
+			ctx = CtxWithVar(ctx, name, values[i])
+		}
+		// Matched path
+		mPath := sr.path
+		if m.matchedPath != "" {
+			mPath = filepath.Join(m.matchedPath, mPath)
+		}
+		subCtx, handler, allowed := sr.getHandler(ctx, "/"+strings.Join(segments, "/"), m.method, mPath,
+			append(slices.Clone(m.applyMiddlewares), r.middlewares...)...)
+		if handler != nil {
+			m.ctx, m.handler = subCtx, handler
+			return true
+		}
+		for method := range allowed {
+			m.allowed[method] = struct{}{}
+		}
+	}
 
-			// Matched path
-			mPath := h.path
-			if matchedPath != "" {
-				mPath = filepath.Join(matchedPath, mPath)
-			}
-			return h.getHandler(ctx, path[len(matches[0]):], method, mPath, append(applyMiddlewares, r.middlewares...)...)
+	if len(segments) == 0 {
+		return r.matchRoutes(m, n.routes, values)
+	}
+	if child, ok := n.static[segments[0]]; ok && r.match(m, child, segments[1:], values) {
+		return true
+	}
+	for _, p := range n.patterns {
+		if matches := p.matcher.FindStringSubmatch(segments[0]); matches != nil &&
+			r.match(m, p.node, segments[1:], append(slices.Clone(values), matches[1:]...)) {
+			return true
 		}
 	}
+	if n.wildcard != nil {
+		return r.matchRoutes(m, n.wildcard.routes, append(slices.Clone(values), strings.Join(segments, "/")))
+	}
+	return false
+}
 
-	// Look for a matching handler
-	for _, routeHandler := range r.routes {
-		if _, ok := routeHandler.methods[method]; !ok {
+// matchRoutes finds the route which handles the request method in routes, and sets the handler and context of m
+func (r *Router) matchRoutes(m *routeMatch, routes []*RouteHandler, values []string) bool {
+	for _, routeHandler := range routes {
+		if _, ok := routeHandler.methods[m.method]; !ok {
+			for method := range routeHandler.methods {
+				m.allowed[method] = struct{}{}
+			}
 			continue
 		}
 
-		if matches := routeHandler.matcher.FindStringSubmatch(path); len(matches) > 0 {
-			for i := 1; i < len(matches); i++ {
-				if i > len(routeHandler.pathArgNames) {
-					break
-				}
-
-				ctx = CtxWithVar(ctx, routeHandler.pathArgNames[i-1], matches[i])
-			}
+		ctx := m.ctx
+		for i, name := range routeHandler.pathArgNames {
+			ctx = CtxWithVar(ctx, name, values[i])
+		}
 
-			// handler found, apply middleware chain
-			var handler HandlerFunc = routeHandler.handleFunc
-			// middlewares attached to this router first
-			for i := len(r.middlewares) - 1; i >= 0; i-- {
-				handler = r.middlewares[i].Middleware(handler)
-			}
-			// middlewares from parent routers next
-			for i := len(applyMiddlewares) - 1; i >= 0; i-- {
-				handler = applyMiddlewares[i].Middleware(handler)
-			}
+		// handler found, enforce the route's requirements, then apply middleware chain
+		handler := authorizedHandler(routeHandler, routeHandler.handleFunc)
+		// middlewares attached to this router first
+		for i := len(r.middlewares) - 1; i >= 0; i-- {
+			handler = r.middlewares[i].Middleware(handler)
+		}
+		// middlewares from parent routers next
+		for i := len(m.applyMiddlewares) - 1; i >= 0; i-- {
+			handler = m.applyMiddlewares[i].Middleware(handler)
+		}
 
-			// Add the matched route info to the context
-			mPath := routeHandler.path
-			if matchedPath != "" {
-				mPath = filepath.Join(matchedPath, mPath)
-			}
-			ctx = context.WithValue(ctx, ctxMatchedRouteKey{}, RouteInfo{
-				Name:   routeHandler.name,
-				Path:   mPath,
-				Method: method,
-			})
-			return ctx, handler
+		// Add the matched route info to the context
+		mPath := routeHandler.path
+		if m.matchedPath != "" {
+			mPath = filepath.Join(m.matchedPath, mPath)
 		}
+		ctx = context.WithValue(ctx, ctxMatchedRouteKey{}, RouteInfo{
+			Name:   routeHandler.name,
+			Path:   mPath,
+			Method: m.method,
+		})
+		ctx = context.WithValue(ctx, ctxMatchedRouteHandlerKey{}, routeHandler)
+		m.ctx, m.handler = ctx, handler
+		return true
 	}
-
-	return ctx, nil
+	return false
 }
 
 // CallResource implements backend.CallResourceHandler, allowing the Router to route resource API requests
@@ -254,19 +337,29 @@ func (r *Router) CallResource(
 	ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender,
 ) error {
 	// Get the appropriate handler (if one exists)
-	ctx, handler := r.getHandler(ctx, req.Path, strings.ToUpper(req.Method), "")
+	ctx, handler, allowed := r.getHandler(ctx, req.Path, strings.ToUpper(req.Method), "")
 	if handler == nil {
-		if r.NotFoundHandler == nil {
+		name := "NotFoundHandler"
+		handler = r.NotFoundHandler
+		if len(allowed) > 0 && r.MethodNotAllowedHandler != nil {
+			name = "MethodNotAllowedHandler"
+			handler = r.MethodNotAllowedHandler
+			methods := make([]string, 0, len(allowed))
+			for method := range allowed {
+				methods = append(methods, method)
+			}
+			slices.Sort(methods)
+			ctx = context.WithValue(ctx, ctxAllowedMethodsKey{}, methods)
+		}
+		if handler == nil {
 			return errors.New("no handler found for the request")
 		}
 
-		// Return not found
-		handler = r.NotFoundHandler
 		for i := len(r.middlewares) - 1; i >= 0; i-- {
 			handler = r.middlewares[i].Middleware(handler)
 		}
 		ctx = context.WithValue(ctx, ctxMatchedRouteKey{}, RouteInfo{
-			Name:   "NotFoundHandler",
+			Name:   name,
 			Path:   req.Path,
 			Method: req.Method,
 		})
@@ -276,6 +369,15 @@ func (r *Router) CallResource(
 	return nil
 }
 
+type ctxAllowedMethodsKey struct{}
+
+// AllowedMethodsFromContext returns the methods allowed for the request's path, sorted alphabetically,
+// when called from a Router's MethodNotAllowedHandler.
+func AllowedMethodsFromContext(ctx context.Context) []string {
+	methods, _ := ctx.Value(ctxAllowedMethodsKey{}).([]string)
+	return methods
+}
+
 // ListenAndServe hooks into the backend of the plugin SDK to handle and serve resource API requests
 // nolint: staticcheck
 // TODO: migration to backend.Manage requires plugin ID
@@ -287,11 +389,9 @@ func (r *Router) ListenAndServe() error {
 
 // RouteHandler is a Handler function assigned to a route
 type RouteHandler struct {
-	// matcher is the regexp matcher for the route, built from the path
-	matcher *regexp.Regexp
 	// name is a user-provided name for the route, may be empty
 	name string
-	// path is the user-provided path when registering the route, used to build the matcher expression
+	// path is the user-provided path when registering the route, used to build the route tree
 	path string
 	// handleFunc is the function called to handle the route
 	handleFunc func(ctx context.Context, req *backend.CallResourceRequest, res backend.CallResourceResponseSender)
@@ -299,18 +399,51 @@ type RouteHandler struct {
 	pathArgNames []string
 	// methods is the list of HTTP methods that this RouteHandler should handle
 	methods map[string]struct{}
+	// requiredRole is the minimum Grafana role a user must have to call the route
+	requiredRole string
+	// accessChecks are additional checks a user must pass to call the route
+	accessChecks []AccessCheckFunc
+	// timeout overrides the timeout of NewTimeoutMiddleware for the route, if > 0
+	timeout time.Duration
+	// maxBodySize overrides the maximum request body size of NewMaxBodySizeMiddleware for the route, if > 0
+	maxBodySize int64
+	// node is the node of the route tree the route is registered at, nil if the route isn't registered
+	node *routeNode
 }
 
-// Methods sets the methods the handler function will be called for
+// Methods sets the methods the handler function will be called for.
+// If another route with the same path (ignoring var names) already handles one of the methods,
+// the conflict is logged and the route's methods are left unchanged.
 func (h *RouteHandler) Methods(methods []string) *RouteHandler {
 	m := make(map[string]struct{})
 	for _, method := range methods {
 		m[strings.ToUpper(method)] = struct{}{}
 	}
+	if h.node != nil {
+		if err := h.node.checkConflicts(h, m); err != nil {
+			logging.DefaultLogger.Error("unable to set route methods", "path", h.path, "error", err)
+			return h
+		}
+	}
 	h.methods = m
 	return h
 }
 
+// Timeout sets the timeout for requests to the route, overriding the default timeout of NewTimeoutMiddleware.
+// It has no effect unless the middleware returned by NewTimeoutMiddleware is used by the router (or a parent router).
+func (h *RouteHandler) Timeout(timeout time.Duration) *RouteHandler {
+	h.timeout = timeout
+	return h
+}
+
+// MaxBodySize sets the maximum size in bytes of request bodies for the route,
+// overriding the default maximum of NewMaxBodySizeMiddleware.
+// It has no effect unless the middleware returned by NewMaxBodySizeMiddleware is used by the router (or a parent router).
+func (h *RouteHandler) MaxBodySize(size int64) *RouteHandler {
+	h.maxBodySize = size
+	return h
+}
+
 // Name sets the name of the RouteHandler.
 // Names should be unique for retrieval purposes, but uniqueness is not enforced.
 func (h *RouteHandler) Name(name string) *RouteHandler {
@@ -320,6 +453,8 @@ func (h *RouteHandler) Name(name string) *RouteHandler {
 
 type ctxMatchedRouteKey struct{}
 
+type ctxMatchedRouteHandlerKey struct{}
+
 // RouteInfo stores information about a matched route
 type RouteInfo struct {
 	// Name is the user-provided name on the route, if a name was provided
@@ -8,6 +8,7 @@ import (
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRouter_CallResource(t *testing.T) {
@@ -127,3 +128,159 @@ func TestRouter_RouteByName(t *testing.T) {
 		assert.Equal(t, handler, h)
 	})
 }
+
+func TestRouter_Matching(t *testing.T) {
+	called := ""
+	var vars Vars
+	handler := func(name string) HandlerFunc {
+		return func(ctx context.Context, request *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+			called = name
+			vars = VarsFromCtx(ctx)
+			if sender != nil {
+				_ = sender.Send(&backend.CallResourceResponse{Status: http.StatusOK})
+			}
+		}
+	}
+	r := NewRouter()
+	r.Handle("/files/{path...}", handler("files"))
+	r.Handle("/files/{name}.json", handler("json"))
+	r.Handle("/a/b/c", handler("abc"))
+	r.Handle("/a/{x}/d", handler("axd"))
+	r.Handle("/items/{id:[0-9]+}", handler("item-get"))
+	r.Handle("/items/{num:[0-9]+}", handler("item-put"), http.MethodPut)
+	r.Handle("/", handler("root"))
+	r.NotFoundHandler = handler("not-found")
+
+	tests := []struct {
+		path       string
+		method     string
+		wantCalled string
+		wantVars   Vars
+	}{
+		{path: "/", method: http.MethodGet, wantCalled: "root", wantVars: Vars{}},
+		{path: "/files/a/b/c.txt", method: http.MethodGet, wantCalled: "files", wantVars: Vars{"path": "a/b/c.txt"}},
+		{path: "/files/", method: http.MethodGet, wantCalled: "files", wantVars: Vars{"path": ""}},
+		{path: "/files/foo.json", method: http.MethodGet, wantCalled: "json", wantVars: Vars{"name": "foo"}},
+		{path: "/files", method: http.MethodGet, wantCalled: "not-found", wantVars: Vars{}},
+		// Static "b" matches first, but "/a/b/d" only matches with the path var, so matching backtracks
+		{path: "/a/b/c", method: http.MethodGet, wantCalled: "abc", wantVars: Vars{}},
+		{path: "/a/b/d", method: http.MethodGet, wantCalled: "axd", wantVars: Vars{"x": "b"}},
+		// Routes which only differ by var name share a node, and have their own var names
+		{path: "/items/12", method: http.MethodGet, wantCalled: "item-get", wantVars: Vars{"id": "12"}},
+		{path: "/items/12", method: http.MethodPut, wantCalled: "item-put", wantVars: Vars{"num": "12"}},
+		{path: "/items/foo", method: http.MethodGet, wantCalled: "not-found", wantVars: Vars{}},
+	}
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			called = ""
+			assert.NoError(t, r.CallResource(context.Background(), &backend.CallResourceRequest{
+				Path:   test.path,
+				Method: test.method,
+			}, nil))
+			assert.Equal(t, test.wantCalled, called)
+			assert.Equal(t, test.wantVars, vars)
+		})
+	}
+
+	t.Run("method not allowed", func(t *testing.T) {
+		res := &CapturingSender{}
+		assert.NoError(t, r.CallResource(context.Background(), &backend.CallResourceRequest{
+			Path:   "/items/12",
+			Method: http.MethodDelete,
+		}, res))
+		assert.Equal(t, http.StatusMethodNotAllowed, res.Response.Status)
+		assert.Equal(t, []string{"GET, PUT"}, res.Response.Headers["Allow"])
+	})
+
+	t.Run("method not allowed in subrouter", func(t *testing.T) {
+		r := NewRouter()
+		r.Subrouter("/sub/{id}").Handle("/foo", handler("sub"), http.MethodPost)
+		res := &CapturingSender{}
+		assert.NoError(t, r.CallResource(context.Background(), &backend.CallResourceRequest{
+			Path:   "/sub/1/foo",
+			Method: http.MethodGet,
+		}, res))
+		assert.Equal(t, http.StatusMethodNotAllowed, res.Response.Status)
+		assert.Equal(t, []string{"POST"}, res.Response.Headers["Allow"])
+	})
+}
+
+func TestRouter_AddRoute(t *testing.T) {
+	noop := func(context.Context, *backend.CallResourceRequest, backend.CallResourceResponseSender) {}
+	r := NewRouter()
+	_, err := r.AddRoute("/foo/{id}", noop, http.MethodGet, http.MethodPost)
+	assert.NoError(t, err)
+
+	t.Run("conflict", func(t *testing.T) {
+		_, err := r.AddRoute("/foo/{name}", noop, http.MethodPost)
+		assert.ErrorIs(t, err, ErrRouteConflict)
+	})
+
+	t.Run("handle conflict keeps first route", func(t *testing.T) {
+		r := NewRouter()
+		r.Handle("/bar/{id}", func(_ context.Context, _ *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+			_ = sender.Send(&backend.CallResourceResponse{Status: http.StatusOK})
+		})
+		var h *RouteHandler
+		assert.NotPanics(t, func() {
+			h = r.Handle("/bar/{other}", func(_ context.Context, _ *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+				_ = sender.Send(&backend.CallResourceResponse{Status: http.StatusTeapot})
+			}).Name("other")
+		})
+		require.NotNil(t, h)
+		assert.Nil(t, r.RouteByName("other"))
+		var status int
+		err := r.CallResource(context.Background(), &backend.CallResourceRequest{Path: "/bar/1", Method: http.MethodGet},
+			backend.CallResourceResponseSenderFunc(func(res *backend.CallResourceResponse) error {
+				status = res.Status
+				return nil
+			}))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("handle invalid path", func(t *testing.T) {
+		assert.NotPanics(t, func() {
+			assert.Nil(t, r.Handle("/foo/{id", noop))
+		})
+	})
+
+	t.Run("methods conflict", func(t *testing.T) {
+		h, err := r.AddRoute("/foo/{name}", noop, http.MethodPut)
+		require.NoError(t, err)
+		h.Methods([]string{http.MethodPut, http.MethodGet})
+		assert.Equal(t, map[string]struct{}{http.MethodPut: {}}, h.methods)
+		h.Methods([]string{http.MethodPut, http.MethodPatch})
+		assert.Equal(t, map[string]struct{}{http.MethodPut: {}, http.MethodPatch: {}}, h.methods)
+	})
+
+	t.Run("same path, different methods", func(t *testing.T) {
+		_, err := r.AddRoute("/foo/{name}", noop, http.MethodDelete)
+		assert.NoError(t, err)
+	})
+
+	invalid := []string{
+		"/foo/{id:[0-9+}",
+		"/foo/{id",
+		"/foo/id}",
+		"/foo/{id:([0-9]+)}",
+		"/foo/{id}/{id}",
+		"/foo/{path...}/bar",
+		"/foo/bar{path...}",
+		"/foo/{path:.+}",
+		"/foo/{path:[a-z/]+}",
+		"/foo/{path:[^.]+}",
+		"/foo/{path:a|b/c}",
+	}
+	for _, path := range invalid {
+		t.Run(path, func(t *testing.T) {
+			_, err := r.AddRoute(path, noop)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("subrouter with wildcard", func(t *testing.T) {
+		_, err := r.AddSubrouter("/foo/{path...}")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,281 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+)
+
+// routeNode is a node in a Router's route tree. Each level of the tree matches one segment of the path.
+// Segments are matched in order of precedence: static segments first, then segments with path vars
+// (in the order they were registered), then wildcards.
+type routeNode struct {
+	// static are the children for segments without path vars, keyed by the segment
+	static map[string]*routeNode
+	// patterns are the children for segments with path vars, in registration order
+	patterns []*patternNode
+	// wildcard is the child for a wildcard var, which matches the remainder of the path
+	wildcard *routeNode
+	// routes are the routes which end at this node
+	routes []*RouteHandler
+	// subrouters are the subrouters with a path prefix ending at this node
+	subrouters []*Subrouter
+}
+
+// patternNode is a child of a routeNode for a segment with path vars
+type patternNode struct {
+	// template is the segment with var names removed, so segments which only differ by var names share a node
+	template string
+	matcher  *regexp.Regexp
+	node     *routeNode
+}
+
+func newRouteNode() *routeNode {
+	return &routeNode{
+		static: make(map[string]*routeNode),
+	}
+}
+
+// pathSegment is a parsed segment of a route path
+type pathSegment struct {
+	// value is the literal segment for static segments, or the template for segments with path vars
+	value    string
+	matcher  *regexp.Regexp
+	wildcard bool
+}
+
+func (s pathSegment) static() bool {
+	return s.matcher == nil && !s.wildcard
+}
+
+// parsePath parses a route path into its segments and the names of its path vars, in order.
+// Path vars are written as {name}, which matches any non-empty segment, or {name:regex}, which matches a segment
+// (or part of a segment) which matches regex. A regex which can match a slash is an error, as vars only match
+// within a single segment. The last segment may be a wildcard, written as {name...}, which matches the remainder
+// of the path.
+func parsePath(path string) ([]pathSegment, []string, error) {
+	rawSegments, err := splitRoutePath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	segments := make([]pathSegment, 0, len(rawSegments))
+	names := make([]string, 0)
+	for i, raw := range rawSegments {
+		matches := replArgRegex.FindAllStringSubmatchIndex(raw, -1)
+		if len(matches) == 0 {
+			if strings.ContainsAny(raw, "{}") {
+				return nil, nil, fmt.Errorf("invalid path var in segment '%s' of path '%s'", raw, path)
+			}
+			segments = append(segments, pathSegment{value: raw})
+			continue
+		}
+		// Wildcard segment
+		if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(raw) && matches[0][5] == matches[0][4] &&
+			strings.HasSuffix(raw[matches[0][2]:matches[0][3]], "...") {
+			if i != len(rawSegments)-1 {
+				return nil, nil, fmt.Errorf("wildcard var '%s' must be the last segment of path '%s'", raw, path)
+			}
+			names = append(names, strings.TrimSuffix(raw[matches[0][2]:matches[0][3]], "..."))
+			segments = append(segments, pathSegment{value: "{...}", wildcard: true})
+			continue
+		}
+		// Segment with one or more vars, which may also contain literal text
+		expr := strings.Builder{}
+		template := strings.Builder{}
+		last := 0
+		for _, match := range matches {
+			literal := raw[last:match[0]]
+			if strings.ContainsAny(literal, "{}") {
+				return nil, nil, fmt.Errorf("invalid path var in segment '%s' of path '%s'", raw, path)
+			}
+			expr.WriteString(regexp.QuoteMeta(literal))
+			template.WriteString(literal)
+			name := raw[match[2]:match[3]]
+			if strings.HasSuffix(name, "...") {
+				return nil, nil, fmt.Errorf("wildcard var '%s' must be a whole segment of path '%s'", name, path)
+			}
+			names = append(names, name)
+			pattern := raw[match[4]:match[5]]
+			if pattern == "" {
+				expr.WriteString(`([^/]+)`)
+				template.WriteString("{}")
+			} else {
+				if matchesSlash(pattern) {
+					return nil, nil, fmt.Errorf("path var pattern '%s' in path '%s' can match '/', but path vars only match "+
+						"a single segment; use a wildcard var {%s...} to match multiple segments", pattern, path, name)
+				}
+				expr.WriteString("(" + pattern + ")")
+				template.WriteString("{:" + pattern + "}")
+			}
+			last = match[1]
+		}
+		if literal := raw[last:]; strings.ContainsAny(literal, "{}") {
+			return nil, nil, fmt.Errorf("invalid path var in segment '%s' of path '%s'", raw, path)
+		}
+		expr.WriteString(regexp.QuoteMeta(raw[last:]))
+		template.WriteString(raw[last:])
+		matcher, err := regexp.Compile("^" + expr.String() + "$")
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid path var pattern in segment '%s' of path '%s': %w", raw, path, err)
+		}
+		if matcher.NumSubexp() != len(matches) {
+			return nil, nil, fmt.Errorf("path var patterns in segment '%s' of path '%s' must not contain capture groups, "+
+				"use non-capturing groups (?:...) instead", raw, path)
+		}
+		segments = append(segments, pathSegment{value: template.String(), matcher: matcher})
+	}
+	for i, name := range names {
+		if slices.Contains(names[:i], name) {
+			return nil, nil, fmt.Errorf("path var '%s' appears more than once in path '%s'", name, path)
+		}
+	}
+	return segments, names, nil
+}
+
+// matchesSlash returns true if the regex pattern can match a slash. Invalid patterns return false,
+// and are reported when the segment's matcher is compiled.
+func matchesSlash(pattern string) bool {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return false
+	}
+	return regexpMatchesSlash(re)
+}
+
+func regexpMatchesSlash(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return true
+	case syntax.OpLiteral:
+		return slices.Contains(re.Rune, '/')
+	case syntax.OpCharClass:
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			if re.Rune[i] <= '/' && '/' <= re.Rune[i+1] {
+				return true
+			}
+		}
+		return false
+	default:
+		return slices.ContainsFunc(re.Sub, regexpMatchesSlash)
+	}
+}
+
+// splitRoutePath splits a route path into segments on each slash which isn't within a path var's braces.
+// A single leading slash is ignored, so "/foo/bar" and "foo/bar" are both ["foo", "bar"], and "/" and "" are empty.
+func splitRoutePath(path string) ([]string, error) {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return []string{}, nil
+	}
+	segments := make([]string, 0)
+	depth := 0
+	start := 0
+	for i, c := range path {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced braces in path '%s'", path)
+			}
+		case '/':
+			if depth == 0 {
+				segments = append(segments, path[start:i])
+				start = i + 1
+			}
+		default:
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced braces in path '%s'", path)
+	}
+	return append(segments, path[start:]), nil
+}
+
+// splitRequestPath splits a request path into segments, in the same way as splitRoutePath
+func splitRequestPath(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return []string{}
+	}
+	return strings.Split(path, "/")
+}
+
+// node returns the node for the segments, creating any nodes which don't exist
+func (n *routeNode) node(segments []pathSegment) *routeNode {
+	current := n
+	for _, segment := range segments {
+		switch {
+		case segment.wildcard:
+			if current.wildcard == nil {
+				current.wildcard = newRouteNode()
+			}
+			current = current.wildcard
+		case segment.static():
+			child, ok := current.static[segment.value]
+			if !ok {
+				child = newRouteNode()
+				current.static[segment.value] = child
+			}
+			current = child
+		default:
+			idx := slices.IndexFunc(current.patterns, func(p *patternNode) bool {
+				return p.template == segment.value
+			})
+			if idx < 0 {
+				current.patterns = append(current.patterns, &patternNode{
+					template: segment.value,
+					matcher:  segment.matcher,
+					node:     newRouteNode(),
+				})
+				idx = len(current.patterns) - 1
+			}
+			current = current.patterns[idx].node
+		}
+	}
+	return current
+}
+
+// ErrRouteConflict is returned when a route is registered for the same path and method as an existing route
+var ErrRouteConflict = errors.New("route conflicts with an existing route")
+
+// addRoute adds the route to the tree, returning an error if a route with the same path (ignoring var names)
+// already handles any of the route's methods
+func (n *routeNode) addRoute(segments []pathSegment, route *RouteHandler) error {
+	node := n.node(segments)
+	if err := node.checkConflicts(route, route.methods); err != nil {
+		return err
+	}
+	node.routes = append(node.routes, route)
+	route.node = node
+	return nil
+}
+
+// checkConflicts returns an error if a route at the node other than route already handles any of methods
+func (n *routeNode) checkConflicts(route *RouteHandler, methods map[string]struct{}) error {
+	for _, existing := range n.routes {
+		if existing == route {
+			continue
+		}
+		for method := range methods {
+			if _, ok := existing.methods[method]; ok {
+				return fmt.Errorf("%w: %s %s conflicts with %s", ErrRouteConflict, method, route.path, existing.path)
+			}
+		}
+	}
+	return nil
+}
+
+// addSubrouter adds the subrouter to the tree at the node for its path prefix
+func (n *routeNode) addSubrouter(segments []pathSegment, subrouter *Subrouter) error {
+	if len(segments) > 0 && segments[len(segments)-1].wildcard {
+		return fmt.Errorf("subrouter path '%s' must not contain a wildcard", subrouter.path)
+	}
+	node := n.node(segments)
+	node.subrouters = append(node.subrouters, subrouter)
+	return nil
+}
@@ -0,0 +1,258 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/grafana-app-sdk/logging"
+	"github.com/grafana/grafana-app-sdk/plugin"
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+const (
+	// ContentTypeNDJSON is the content-type header value for newline-delimited JSON content.
+	ContentTypeNDJSON = "application/x-ndjson"
+	// ContentTypeEventStream is the content-type header value for server-sent events.
+	ContentTypeEventStream = "text/event-stream"
+)
+
+// watchEventTypeError is the event type of watch events which carry an error
+const watchEventTypeError = "ERROR"
+
+// WatchClient describes a client which can make watch requests, such as resource.Client.
+type WatchClient interface {
+	Watch(ctx context.Context, namespace string, options resource.WatchOptions) (resource.WatchResponse, error)
+}
+
+// WatchHandlerOptions are the options for a watch handler created by NewWatchHandler.
+type WatchHandlerOptions struct {
+	// Namespace is the namespace to watch. It must be set unless AllNamespaces is true.
+	Namespace string
+	// AllNamespaces watches all namespaces, and requires Namespace to be empty.
+	// If Namespace is empty and AllNamespaces is false, every request to the watch handler returns a 400 error.
+	AllNamespaces bool
+	// HeartbeatInterval is the interval at which an empty message (which clients should ignore) is sent,
+	// to keep the connection open through proxies. If HeartbeatInterval is <= 0, no heartbeats are sent.
+	HeartbeatInterval time.Duration
+}
+
+// WatchEvent is an event sent by a watch handler.
+type WatchEvent struct {
+	// Type is the type of the event, such as ADDED, MODIFIED, DELETED, or ERROR
+	Type string `json:"type"`
+	// Object is the resource the event is for. It is nil for ERROR events.
+	Object resource.Object `json:"object,omitempty"`
+	// Error is the error for ERROR events.
+	Error *JSONErrorResponse `json:"error,omitempty"`
+}
+
+// NewWatchHandler returns a HandlerFunc which makes a watch request with the WatchClient and streams the watch events
+// to the caller until the request context is canceled or the watch ends.
+//
+// Events are streamed as server-sent events if the request's Accept header includes text/event-stream,
+// with the event name being the event type, the event ID being the resource version of the object, and the data
+// being the JSON-encoded WatchEvent. Otherwise, each event is sent as a line of JSON (content type application/x-ndjson).
+//
+// The watch starts from the resourceVersion query param if provided, or from the Last-Event-ID header
+// (sent by server-sent event clients when reconnecting) otherwise. The labelSelector and fieldSelector query params
+// filter the watched resources. If the resource version is too old, an ERROR event with code 410 is sent,
+// and the caller must list the resources again to get a new resource version.
+//
+// If options doesn't set exactly one of Namespace and AllNamespaces, the error is logged,
+// and every request to the handler returns a 400 error.
+func NewWatchHandler(client WatchClient, options WatchHandlerOptions) HandlerFunc {
+	optionsErr := options.validate()
+	if optionsErr != nil {
+		logging.DefaultLogger.Error("invalid watch handler options", "error", optionsErr)
+	}
+	return func(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) {
+		if optionsErr != nil {
+			sendJSONError(ctx, sender, plugin.WrapError(http.StatusBadRequest, optionsErr))
+			return
+		}
+		query := url.Values{}
+		if u, err := url.Parse(req.URL); err == nil {
+			query = u.Query()
+		}
+		headers := http.Header(req.Headers)
+		watchOptions := resource.WatchOptions{
+			ResourceVersion:     query.Get("resourceVersion"),
+			AllowWatchBookmarks: true,
+			Reconnect:           true,
+		}
+		if watchOptions.ResourceVersion == "" {
+			watchOptions.ResourceVersion = headers.Get("Last-Event-ID")
+		}
+		if selector := query.Get("labelSelector"); selector != "" {
+			watchOptions.LabelFilters = []string{selector}
+		}
+		if selector := query.Get("fieldSelector"); selector != "" {
+			watchOptions.FieldSelectors = []string{selector}
+		}
+
+		watch, err := client.Watch(ctx, options.Namespace, watchOptions)
+		if err != nil {
+			sendWatchError(ctx, sender, err)
+			return
+		}
+		defer watch.Stop()
+
+		w := &watchStreamWriter{
+			sender:      sender,
+			eventStream: strings.Contains(headers.Get("Accept"), ContentTypeEventStream),
+		}
+		if err := w.start(); err != nil {
+			logging.FromContext(ctx).Error("error sending watch response", "error", err)
+			return
+		}
+
+		var heartbeat <-chan time.Time
+		if options.HeartbeatInterval > 0 {
+			ticker := time.NewTicker(options.HeartbeatInterval)
+			defer ticker.Stop()
+			heartbeat = ticker.C
+		}
+		events := watch.WatchEvents()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-heartbeat:
+				err = w.heartbeat()
+			case evt, ok := <-events:
+				if !ok {
+					return
+				}
+				err = w.write(watchEvent(evt))
+			}
+			if err != nil {
+				logging.FromContext(ctx).Error("error sending watch event", "error", err)
+				return
+			}
+		}
+	}
+}
+
+// validate returns an error unless exactly one of Namespace and AllNamespaces is set
+func (o WatchHandlerOptions) validate() error {
+	if o.Namespace == "" && !o.AllNamespaces {
+		return errors.New("no namespace to watch: set Namespace, or AllNamespaces to watch all namespaces")
+	}
+	if o.Namespace != "" && o.AllNamespaces {
+		return fmt.Errorf("cannot set both Namespace '%s' and AllNamespaces", o.Namespace)
+	}
+	return nil
+}
+
+// HandleWatch registers a watch handler (see NewWatchHandler) for the path, using the GET method.
+func (r *Router) HandleWatch(path string, client WatchClient, options WatchHandlerOptions) *RouteHandler {
+	return r.Handle(path, NewWatchHandler(client, options), http.MethodGet)
+}
+
+func watchEvent(evt resource.WatchEvent) WatchEvent {
+	if evt.EventType != watchEventTypeError {
+		return WatchEvent{
+			Type:   evt.EventType,
+			Object: evt.Object,
+		}
+	}
+	err := evt.Error
+	if err == nil {
+		err = errors.New("unknown watch error")
+	}
+	code := http.StatusInternalServerError
+	var cast resource.APIServerResponseError
+	if errors.As(err, &cast) && cast.StatusCode() != 0 {
+		code = cast.StatusCode()
+	}
+	return WatchEvent{
+		Type: watchEventTypeError,
+		Error: &JSONErrorResponse{
+			Code:  code,
+			Error: err.Error(),
+		},
+	}
+}
+
+func sendWatchError(ctx context.Context, sender backend.CallResourceResponseSender, err error) {
+	code := http.StatusInternalServerError
+	var cast resource.APIServerResponseError
+	if errors.As(err, &cast) && cast.StatusCode() >= http.StatusBadRequest {
+		code = cast.StatusCode()
+	}
+	logging.FromContext(ctx).Error("error starting watch", "error", err.Error())
+	uerr := plugin.WrapError(code, err)
+	body, _ := json.Marshal(JSONErrorResponse{
+		Code:  code,
+		Error: uerr.CleanMessage(),
+	})
+	if err := sender.Send(&backend.CallResourceResponse{
+		Status:  code,
+		Headers: map[string][]string{HeaderContentType: {ContentTypeJSON}},
+		Body:    body,
+	}); err != nil {
+		logging.FromContext(ctx).Error("error sending backend response", "error", err)
+	}
+}
+
+// watchStreamWriter writes watch events to a CallResourceResponseSender. The first response sent contains the status
+// and headers, and each subsequent response is a chunk of the body.
+type watchStreamWriter struct {
+	sender      backend.CallResourceResponseSender
+	eventStream bool
+}
+
+func (w *watchStreamWriter) start() error {
+	contentType := ContentTypeNDJSON
+	if w.eventStream {
+		contentType = ContentTypeEventStream
+	}
+	return w.sender.Send(&backend.CallResourceResponse{
+		Status: http.StatusOK,
+		Headers: map[string][]string{
+			HeaderContentType: {contentType},
+			"Cache-Control":   {"no-cache"},
+		},
+	})
+}
+
+func (w *watchStreamWriter) write(evt WatchEvent) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	buf := bytes.Buffer{}
+	if w.eventStream {
+		fmt.Fprintf(&buf, "event: %s\n", evt.Type)
+		if evt.Object != nil && evt.Object.GetResourceVersion() != "" {
+			fmt.Fprintf(&buf, "id: %s\n", evt.Object.GetResourceVersion())
+		}
+		fmt.Fprintf(&buf, "data: %s\n\n", data)
+	} else {
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return w.sender.Send(&backend.CallResourceResponse{
+		Body: buf.Bytes(),
+	})
+}
+
+func (w *watchStreamWriter) heartbeat() error {
+	body := []byte("\n")
+	if w.eventStream {
+		// Lines starting with a colon are comments, which are ignored by server-sent event clients
+		body = []byte(":\n\n")
+	}
+	return w.sender.Send(&backend.CallResourceResponse{
+		Body: body,
+	})
+}
@@ -0,0 +1,196 @@
+package router_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana-app-sdk/plugin/router"
+	"github.com/grafana/grafana-app-sdk/resource"
+)
+
+type fakeWatchClient struct {
+	watchFunc func(ctx context.Context, namespace string, options resource.WatchOptions) (resource.WatchResponse, error)
+}
+
+func (c *fakeWatchClient) Watch(ctx context.Context, namespace string, options resource.WatchOptions) (resource.WatchResponse, error) {
+	return c.watchFunc(ctx, namespace, options)
+}
+
+type fakeWatchResponse struct {
+	ch      chan resource.WatchEvent
+	stopped bool
+}
+
+func (w *fakeWatchResponse) Stop() {
+	w.stopped = true
+}
+
+func (w *fakeWatchResponse) WatchEvents() <-chan resource.WatchEvent {
+	return w.ch
+}
+
+type collectingSender struct {
+	mux       sync.Mutex
+	responses []*backend.CallResourceResponse
+}
+
+func (s *collectingSender) Send(r *backend.CallResourceResponse) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.responses = append(s.responses, r)
+	return nil
+}
+
+func (s *collectingSender) body() string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	sb := strings.Builder{}
+	for _, r := range s.responses[1:] {
+		sb.Write(r.Body)
+	}
+	return sb.String()
+}
+
+func watchTestObject(name, rv string) resource.Object {
+	obj := getTestObject(name)
+	obj.SetResourceVersion(rv)
+	return obj
+}
+
+func TestNewWatchHandler(t *testing.T) {
+	t.Run("JSON lines", func(t *testing.T) {
+		watch := &fakeWatchResponse{ch: make(chan resource.WatchEvent, 3)}
+		watch.ch <- resource.WatchEvent{EventType: "ADDED", Object: watchTestObject("a", "2")}
+		watch.ch <- resource.WatchEvent{EventType: "ERROR", Error: apiError{statusCode: http.StatusGone}}
+		close(watch.ch)
+		handler := router.NewWatchHandler(&fakeWatchClient{
+			watchFunc: func(ctx context.Context, namespace string, options resource.WatchOptions) (resource.WatchResponse, error) {
+				assert.Equal(t, "ns", namespace)
+				assert.Equal(t, "1", options.ResourceVersion)
+				assert.Equal(t, []string{"a=b"}, options.LabelFilters)
+				assert.Equal(t, []string{"spec.foo=bar"}, options.FieldSelectors)
+				assert.True(t, options.Reconnect)
+				return watch, nil
+			},
+		}, router.WatchHandlerOptions{Namespace: "ns"})
+
+		sender := &collectingSender{}
+		handler(context.Background(), &backend.CallResourceRequest{
+			URL: "watch?resourceVersion=1&labelSelector=a%3Db&fieldSelector=spec.foo%3Dbar",
+		}, sender)
+
+		require.Len(t, sender.responses, 3)
+		assert.Equal(t, http.StatusOK, sender.responses[0].Status)
+		assert.Equal(t, []string{router.ContentTypeNDJSON}, sender.responses[0].Headers[router.HeaderContentType])
+		lines := strings.Split(strings.TrimSpace(sender.body()), "\n")
+		require.Len(t, lines, 2)
+		added := struct {
+			Type   string `json:"type"`
+			Object Test   `json:"object"`
+		}{}
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &added))
+		assert.Equal(t, "ADDED", added.Type)
+		assert.Equal(t, "a", added.Object.GetName())
+		assert.Equal(t, "2", added.Object.GetResourceVersion())
+		assert.JSONEq(t, `{"type":"ERROR","error":{"code":410,"error":"Gone"}}`, lines[1])
+		assert.True(t, watch.stopped)
+	})
+
+	t.Run("server-sent events", func(t *testing.T) {
+		watch := &fakeWatchResponse{ch: make(chan resource.WatchEvent, 1)}
+		watch.ch <- resource.WatchEvent{EventType: "MODIFIED", Object: watchTestObject("a", "6")}
+		close(watch.ch)
+		handler := router.NewWatchHandler(&fakeWatchClient{
+			watchFunc: func(ctx context.Context, namespace string, options resource.WatchOptions) (resource.WatchResponse, error) {
+				assert.Empty(t, namespace)
+				assert.Equal(t, "5", options.ResourceVersion)
+				return watch, nil
+			},
+		}, router.WatchHandlerOptions{AllNamespaces: true})
+
+		sender := &collectingSender{}
+		handler(context.Background(), &backend.CallResourceRequest{
+			URL: "watch",
+			Headers: map[string][]string{
+				"Accept":        {router.ContentTypeEventStream},
+				"Last-Event-Id": {"5"},
+			},
+		}, sender)
+
+		require.Len(t, sender.responses, 2)
+		assert.Equal(t, []string{router.ContentTypeEventStream}, sender.responses[0].Headers[router.HeaderContentType])
+		body := sender.body()
+		assert.True(t, strings.HasPrefix(body, "event: MODIFIED\nid: 6\ndata: {"), body)
+		assert.True(t, strings.HasSuffix(body, "}\n\n"), body)
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		watch := &fakeWatchResponse{ch: make(chan resource.WatchEvent)}
+		handler := router.NewWatchHandler(&fakeWatchClient{
+			watchFunc: func(ctx context.Context, namespace string, options resource.WatchOptions) (resource.WatchResponse, error) {
+				return watch, nil
+			},
+		}, router.WatchHandlerOptions{Namespace: "ns", HeartbeatInterval: 10 * time.Millisecond})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		sender := &collectingSender{}
+		done := make(chan struct{})
+		go func() {
+			handler(ctx, &backend.CallResourceRequest{URL: "watch"}, sender)
+			close(done)
+		}()
+		assert.Eventually(t, func() bool {
+			sender.mux.Lock()
+			defer sender.mux.Unlock()
+			// Headers and at least one heartbeat
+			return len(sender.responses) >= 2
+		}, time.Second, 5*time.Millisecond)
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("handler did not return after context was canceled")
+		}
+		assert.True(t, watch.stopped)
+		assert.Equal(t, "\n", string(sender.responses[1].Body))
+	})
+
+	t.Run("watch error", func(t *testing.T) {
+		r := router.NewRouter()
+		r.HandleWatch("watch", &fakeWatchClient{
+			watchFunc: func(ctx context.Context, namespace string, options resource.WatchOptions) (resource.WatchResponse, error) {
+				return nil, apiError{statusCode: http.StatusForbidden}
+			},
+		}, router.WatchHandlerOptions{Namespace: "ns"})
+		resp := callResource(t, r, &backend.CallResourceRequest{Path: "watch", Method: http.MethodGet})
+		assert.Equal(t, http.StatusForbidden, resp.Status)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		for name, options := range map[string]router.WatchHandlerOptions{
+			"no namespace":                     {},
+			"namespace and all namespaces set": {Namespace: "ns", AllNamespaces: true},
+		} {
+			t.Run(name, func(t *testing.T) {
+				r := router.NewRouter()
+				r.HandleWatch("watch", &fakeWatchClient{
+					watchFunc: func(ctx context.Context, namespace string, options resource.WatchOptions) (resource.WatchResponse, error) {
+						t.Fatal("watch should not be called")
+						return nil, nil
+					},
+				}, options)
+				resp := callResource(t, r, &backend.CallResourceRequest{Path: "watch", Method: http.MethodGet})
+				assert.Equal(t, http.StatusBadRequest, resp.Status)
+			})
+		}
+	})
+}
@@ -1,6 +1,11 @@
 package resource
 
-import "context"
+import (
+	"context"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 type AdmissionAction string
 
@@ -50,10 +55,82 @@ type AdmissionError interface {
 	Reason() string
 }
 
+// DetailedAdmissionError is an optional extension of AdmissionError which supplies additional information
+// to return with an admission rejection, such as field-level causes, warnings, and audit annotations.
+type DetailedAdmissionError interface {
+	AdmissionError
+	// Causes should return a list of field-level causes for the rejection, if any
+	Causes() []metav1.StatusCause
+	// Warnings should return a list of warning messages to return to the user alongside the rejection, if any
+	Warnings() []string
+	// AuditAnnotations should return a map of annotations to add to the audit event for the request, if any
+	AuditAnnotations() map[string]string
+}
+
+type admissionDetailsKey struct{}
+
+// admissionDetails collects the warnings and audit annotations added to a context with AddAdmissionWarnings
+// and AddAdmissionAuditAnnotations
+type admissionDetails struct {
+	mux              sync.Mutex
+	warnings         []string
+	auditAnnotations map[string]string
+}
+
+// WithAdmissionDetails returns a copy of ctx which collects warnings and audit annotations added to it with
+// AddAdmissionWarnings and AddAdmissionAuditAnnotations, and a function which returns the collected values.
+// It is used by webhook servers to return warnings and audit annotations with the response to an admission request,
+// including when the request is allowed.
+func WithAdmissionDetails(ctx context.Context) (context.Context, func() ([]string, map[string]string)) {
+	details := &admissionDetails{}
+	return context.WithValue(ctx, admissionDetailsKey{}, details), func() ([]string, map[string]string) {
+		details.mux.Lock()
+		defer details.mux.Unlock()
+		return details.warnings, details.auditAnnotations
+	}
+}
+
+// AddAdmissionWarnings adds warning messages to return to the user with the response to the admission request
+// being handled with ctx, whether or not the request is allowed.
+// It has no effect if ctx wasn't created by WithAdmissionDetails.
+func AddAdmissionWarnings(ctx context.Context, warnings ...string) {
+	details, ok := ctx.Value(admissionDetailsKey{}).(*admissionDetails)
+	if !ok {
+		return
+	}
+	details.mux.Lock()
+	defer details.mux.Unlock()
+	details.warnings = append(details.warnings, warnings...)
+}
+
+// AddAdmissionAuditAnnotations adds annotations to the audit event for the admission request being handled with ctx,
+// whether or not the request is allowed. Keys must be valid label-style keys,
+// and will be prefixed by the API server with the webhook's name.
+// It has no effect if ctx wasn't created by WithAdmissionDetails.
+func AddAdmissionAuditAnnotations(ctx context.Context, annotations map[string]string) {
+	details, ok := ctx.Value(admissionDetailsKey{}).(*admissionDetails)
+	if !ok {
+		return
+	}
+	details.mux.Lock()
+	defer details.mux.Unlock()
+	if details.auditAnnotations == nil {
+		details.auditAnnotations = make(map[string]string, len(annotations))
+	}
+	for k, v := range annotations {
+		details.auditAnnotations[k] = v
+	}
+}
+
 // MutatingResponse is the mutation to perform on a request
 type MutatingResponse struct {
 	// UpdatedObject is an updated version of the object which was passed to the MutatingAdmissionController.
 	UpdatedObject Object
+	// Warnings is an optional list of warning messages to return to the user who made the request.
+	Warnings []string
+	// AuditAnnotations is an optional map of annotations to add to the audit event for the request.
+	// Keys must be valid label-style keys, and will be prefixed by the API server with the webhook's name.
+	AuditAnnotations map[string]string
 }
 
 // ValidatingAdmissionController is an interface that describes any object which should validate admission of
@@ -62,6 +139,8 @@ type ValidatingAdmissionController interface {
 	// Validate consumes an AdmissionRequest, then returns an error if the request should be denied.
 	// The returned error SHOULD satisfy the AdmissionError interface, but callers will fallback
 	// to using only the information in a simple error if not.
+	// Warnings and audit annotations can be returned with an allowed request by adding them to ctx
+	// with AddAdmissionWarnings and AddAdmissionAuditAnnotations.
 	Validate(ctx context.Context, request *AdmissionRequest) error
 }
 
@@ -132,6 +132,14 @@ type WatchOptions struct {
 	LabelFilters []string
 	// FieldSelectors are a set of field selector strings applied to watched resources
 	FieldSelectors []string
+	// AllowWatchBookmarks requests that the server send periodic BOOKMARK events,
+	// which carry only the current resource version of the watched collection.
+	AllowWatchBookmarks bool
+	// Reconnect, if true, makes the watch resilient to the underlying watch request ending (by server timeout or error).
+	// The implementation tracks the last seen resource version (including from bookmarks) and transparently re-establishes
+	// the watch from that version. If the resource version has expired, an ERROR event is sent with an Error indicating
+	// that the consumer must re-list, and the watch is stopped.
+	Reconnect bool
 }
 
 // WatchResponse is an interface describing the response to a Client.Watch call
@@ -148,6 +156,8 @@ type WatchEvent struct {
 	EventType string
 	// Object is the affected object
 	Object Object
+	// Error is the error returned by the server for an ERROR event. It is nil for all other event types.
+	Error error
 }
 
 // Client is any object which interfaces with schema Objects.
@@ -0,0 +1,89 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultListIteratorPageSize is the page size used by list iterators when no page size (or Limit) is provided.
+const DefaultListIteratorPageSize = 500
+
+// ErrListInconsistent is returned (wrapped) by a list iterator when the list can no longer be continued consistently,
+// either because the continue token has expired (the server returned a 410 Gone) or because a page was returned
+// from a different resource version than the first page. Items already yielded may be stale; to get a consistent view,
+// the list must be restarted.
+var ErrListInconsistent = errors.New("list is no longer consistent and must be restarted")
+
+// ListIterator returns an iterator over all resources returned by client.List, which requests one page at a time
+// using options.Limit as the page size (or DefaultListIteratorPageSize if options.Limit is <= 0).
+// Only one page of resources is held in memory at a time, and breaking out of the iteration stops any further pages
+// from being requested.
+//
+// If an error is encountered, it is yielded with a nil Object, and iteration stops. If a subsequent page cannot be
+// listed consistently with the first page, the error wraps ErrListInconsistent.
+func ListIterator(ctx context.Context, client Client, namespace string, options ListOptions) iter.Seq2[Object, error] {
+	return listIterator(ctx, options, func(ctx context.Context, opts ListOptions) ([]Object, metav1.ListInterface, error) {
+		resp, err := client.List(ctx, namespace, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return resp.GetItems(), resp, nil
+	})
+}
+
+// listIterator implements the paging logic for list iterators. listPage is called for each page with the options
+// for that page, and returns the items in the page, and the list metadata of the page.
+func listIterator[T any](
+	ctx context.Context, options ListOptions, listPage func(context.Context, ListOptions) ([]T, metav1.ListInterface, error),
+) iter.Seq2[T, error] {
+	if options.Limit <= 0 {
+		options.Limit = DefaultListIteratorPageSize
+	}
+	return func(yield func(T, error) bool) {
+		// Copy the options so that the iterator can be re-used
+		options := options
+		var zero T
+		resourceVersion := ""
+		for page := 0; ; page++ {
+			if page > 0 {
+				// Stop between pages if the context is done, rather than relying on the client to check
+				if err := ctx.Err(); err != nil {
+					yield(zero, err)
+					return
+				}
+			}
+			items, md, err := listPage(ctx, options)
+			if err != nil {
+				var cast APIServerResponseError
+				if page > 0 && errors.As(err, &cast) && cast.StatusCode() == http.StatusGone {
+					err = fmt.Errorf("%w: %w", ErrListInconsistent, err)
+				}
+				yield(zero, err)
+				return
+			}
+			if page == 0 {
+				resourceVersion = md.GetResourceVersion()
+			} else if resourceVersion != "" && md.GetResourceVersion() != "" && md.GetResourceVersion() != resourceVersion {
+				yield(zero, fmt.Errorf("%w: page resource version '%s' does not match list resource version '%s'",
+					ErrListInconsistent, md.GetResourceVersion(), resourceVersion))
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if md.GetContinue() == "" {
+				return
+			}
+			options.Continue = md.GetContinue()
+			// The continue token encodes the resource version of the first page
+			options.ResourceVersion = ""
+		}
+	}
+}
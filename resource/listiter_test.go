@@ -0,0 +1,166 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestListIterator(t *testing.T) {
+	client := &mockClient{}
+	ctx := context.TODO()
+	ns := "ns"
+	pages := map[string]*UntypedList{
+		"": {
+			ListMeta: metav1.ListMeta{Continue: "1", ResourceVersion: "10"},
+			Items:    []Object{&UntypedObject{ObjectMeta: metav1.ObjectMeta{Name: "a"}}, &UntypedObject{ObjectMeta: metav1.ObjectMeta{Name: "b"}}},
+		},
+		"1": {
+			ListMeta: metav1.ListMeta{Continue: "2", ResourceVersion: "10"},
+			Items:    []Object{&UntypedObject{ObjectMeta: metav1.ObjectMeta{Name: "c"}}, &UntypedObject{ObjectMeta: metav1.ObjectMeta{Name: "d"}}},
+		},
+		"2": {
+			ListMeta: metav1.ListMeta{ResourceVersion: "10"},
+			Items:    []Object{&UntypedObject{ObjectMeta: metav1.ObjectMeta{Name: "e"}}},
+		},
+	}
+	names := func(seq func(func(Object, error) bool)) ([]string, error) {
+		ret := make([]string, 0)
+		for obj, err := range seq {
+			if err != nil {
+				return ret, err
+			}
+			ret = append(ret, obj.GetName())
+		}
+		return ret, nil
+	}
+
+	t.Run("all pages", func(t *testing.T) {
+		requested := make([]string, 0)
+		client.ListFunc = func(c context.Context, namespace string, options ListOptions) (ListObject, error) {
+			assert.Equal(t, ns, namespace)
+			assert.Equal(t, 2, options.Limit)
+			assert.Equal(t, []string{"foo=bar"}, options.LabelFilters)
+			if options.Continue != "" {
+				assert.Equal(t, "", options.ResourceVersion)
+			} else {
+				assert.Equal(t, "5", options.ResourceVersion)
+			}
+			requested = append(requested, options.Continue)
+			return pages[options.Continue], nil
+		}
+		iter := ListIterator(ctx, client, ns, ListOptions{Limit: 2, LabelFilters: []string{"foo=bar"}, ResourceVersion: "5"})
+		ret, err := names(iter)
+		require.Nil(t, err)
+		assert.Equal(t, []string{"a", "b", "c", "d", "e"}, ret)
+		assert.Equal(t, []string{"", "1", "2"}, requested)
+		// The iterator can be re-used, and starts from the first page again
+		ret, err = names(iter)
+		require.Nil(t, err)
+		assert.Equal(t, []string{"a", "b", "c", "d", "e"}, ret)
+	})
+
+	t.Run("default page size", func(t *testing.T) {
+		client.ListFunc = func(c context.Context, namespace string, options ListOptions) (ListObject, error) {
+			assert.Equal(t, DefaultListIteratorPageSize, options.Limit)
+			return pages["2"], nil
+		}
+		ret, err := names(ListIterator(ctx, client, ns, ListOptions{}))
+		require.Nil(t, err)
+		assert.Equal(t, []string{"e"}, ret)
+	})
+
+	t.Run("early termination", func(t *testing.T) {
+		requests := 0
+		client.ListFunc = func(c context.Context, namespace string, options ListOptions) (ListObject, error) {
+			requests++
+			return pages[options.Continue], nil
+		}
+		for obj, err := range ListIterator(ctx, client, ns, ListOptions{Limit: 2}) {
+			require.Nil(t, err)
+			if obj.GetName() == "b" {
+				break
+			}
+		}
+		assert.Equal(t, 1, requests)
+	})
+
+	t.Run("list error", func(t *testing.T) {
+		cerr := fmt.Errorf("I AM ERROR")
+		client.ListFunc = func(c context.Context, namespace string, options ListOptions) (ListObject, error) {
+			if options.Continue == "1" {
+				return nil, cerr
+			}
+			return pages[options.Continue], nil
+		}
+		ret, err := names(ListIterator(ctx, client, ns, ListOptions{Limit: 2}))
+		assert.Equal(t, cerr, err)
+		assert.Equal(t, []string{"a", "b"}, ret)
+	})
+
+	t.Run("expired continue", func(t *testing.T) {
+		cerr := &testAPIError{err: fmt.Errorf("continue token expired"), statusCode: http.StatusGone}
+		client.ListFunc = func(c context.Context, namespace string, options ListOptions) (ListObject, error) {
+			if options.Continue == "1" {
+				return nil, cerr
+			}
+			return pages[options.Continue], nil
+		}
+		_, err := names(ListIterator(ctx, client, ns, ListOptions{Limit: 2}))
+		assert.ErrorIs(t, err, ErrListInconsistent)
+		var apiErr APIServerResponseError
+		require.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, http.StatusGone, apiErr.StatusCode())
+	})
+
+	t.Run("expired continue, wrapped", func(t *testing.T) {
+		cerr := fmt.Errorf("list failed: %w", &testAPIError{err: fmt.Errorf("continue token expired"), statusCode: http.StatusGone})
+		client.ListFunc = func(c context.Context, namespace string, options ListOptions) (ListObject, error) {
+			if options.Continue == "1" {
+				return nil, cerr
+			}
+			return pages[options.Continue], nil
+		}
+		_, err := names(ListIterator(ctx, client, ns, ListOptions{Limit: 2}))
+		assert.ErrorIs(t, err, ErrListInconsistent)
+		assert.ErrorIs(t, err, cerr)
+	})
+
+	t.Run("resource version changed", func(t *testing.T) {
+		client.ListFunc = func(c context.Context, namespace string, options ListOptions) (ListObject, error) {
+			if options.Continue == "1" {
+				return &UntypedList{ListMeta: metav1.ListMeta{ResourceVersion: "11"}}, nil
+			}
+			return pages[options.Continue], nil
+		}
+		ret, err := names(ListIterator(ctx, client, ns, ListOptions{Limit: 2}))
+		assert.ErrorIs(t, err, ErrListInconsistent)
+		assert.Equal(t, []string{"a", "b"}, ret)
+	})
+
+	t.Run("context canceled between pages", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		client.ListFunc = func(c context.Context, namespace string, options ListOptions) (ListObject, error) {
+			return pages[options.Continue], nil
+		}
+		ret := make([]string, 0)
+		var iterErr error
+		for obj, err := range ListIterator(cctx, client, ns, ListOptions{Limit: 2}) {
+			if err != nil {
+				iterErr = err
+				break
+			}
+			ret = append(ret, obj.GetName())
+			cancel()
+		}
+		assert.ErrorIs(t, iterErr, context.Canceled)
+		assert.Equal(t, []string{"a", "b"}, ret)
+	})
+}
@@ -0,0 +1,50 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// DefaultConflictRetryBackoff is the backoff used by the UpdateWithRetry methods of Store, TypedStore, and SimpleStore.
+// It makes up to five attempts, roughly 10ms apart.
+var DefaultConflictRetryBackoff = wait.Backoff{
+	Steps:    5,
+	Duration: 10 * time.Millisecond,
+	Factor:   1.0,
+	Jitter:   0.1,
+}
+
+// IsConflictError returns true if the error is, or wraps, an APIServerResponseError with a 409 Conflict status code,
+// which is returned by the API server when an update is made with an out-of-date ResourceVersion.
+func IsConflictError(err error) bool {
+	var cast APIServerResponseError
+	return errors.As(err, &cast) && cast.StatusCode() == http.StatusConflict
+}
+
+// RetryOnConflict calls fn, and calls it again (waiting according to backoff between attempts) each time it returns
+// an error for which IsConflictError is true. It returns when fn succeeds or returns a non-conflict error,
+// backoff.Steps attempts have been made, or ctx is done.
+// If all attempts fail, the error from the last attempt is returned. If ctx is done, ctx.Err() is returned.
+//
+// fn should re-fetch the object it updates on each call, so that each attempt uses the latest ResourceVersion.
+//
+//nolint:gocritic
+func RetryOnConflict(ctx context.Context, backoff wait.Backoff, fn func(ctx context.Context) error) error {
+	for {
+		err := fn(ctx)
+		if err == nil || !IsConflictError(err) || backoff.Steps <= 1 {
+			return err
+		}
+		timer := time.NewTimer(backoff.Step())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
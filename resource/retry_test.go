@@ -0,0 +1,157 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestIsConflictError(t *testing.T) {
+	assert.False(t, IsConflictError(nil))
+	assert.False(t, IsConflictError(fmt.Errorf("foo")))
+	assert.False(t, IsConflictError(&testAPIError{err: fmt.Errorf("foo"), statusCode: http.StatusNotFound}))
+	assert.True(t, IsConflictError(&testAPIError{err: fmt.Errorf("foo"), statusCode: http.StatusConflict}))
+	assert.True(t, IsConflictError(fmt.Errorf("wrapped: %w", &testAPIError{err: fmt.Errorf("foo"), statusCode: http.StatusConflict})))
+}
+
+func TestRetryOnConflict(t *testing.T) {
+	conflict := &testAPIError{err: fmt.Errorf("conflict"), statusCode: http.StatusConflict}
+	backoff := wait.Backoff{Steps: 3, Duration: time.Millisecond, Factor: 1}
+
+	t.Run("success after conflicts", func(t *testing.T) {
+		calls := 0
+		err := RetryOnConflict(context.Background(), backoff, func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return conflict
+			}
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		calls := 0
+		err := RetryOnConflict(context.Background(), backoff, func(ctx context.Context) error {
+			calls++
+			return conflict
+		})
+		assert.Equal(t, conflict, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("non-conflict error", func(t *testing.T) {
+		cerr := fmt.Errorf("I AM ERROR")
+		calls := 0
+		err := RetryOnConflict(context.Background(), backoff, func(ctx context.Context) error {
+			calls++
+			return cerr
+		})
+		assert.Equal(t, cerr, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		err := RetryOnConflict(ctx, wait.Backoff{Steps: 3, Duration: time.Minute}, func(ctx context.Context) error {
+			cancel()
+			return conflict
+		})
+		assert.Equal(t, context.Canceled, err)
+	})
+}
+
+// conflictingClient sets up client to return an object whose ResourceVersion is incremented on each Get,
+// and to return a conflict for the first conflicts updates. It returns a pointer to the ResourceVersions used for each update.
+func conflictingClient(client *mockClient, conflicts int, obj func(rv string) Object) *[]string {
+	gets := 0
+	updates := make([]string, 0)
+	client.GetFunc = func(ctx context.Context, identifier Identifier) (Object, error) {
+		gets++
+		return obj(strconv.Itoa(gets)), nil
+	}
+	client.UpdateFunc = func(ctx context.Context, identifier Identifier, o Object, options UpdateOptions) (Object, error) {
+		updates = append(updates, options.ResourceVersion)
+		if len(updates) <= conflicts {
+			return nil, &testAPIError{err: fmt.Errorf("conflict"), statusCode: http.StatusConflict}
+		}
+		return o, nil
+	}
+	return &updates
+}
+
+func TestTypedStore_UpdateWithRetry(t *testing.T) {
+	store, client := getTypedStoreTestSetup()
+	id := Identifier{Namespace: "ns", Name: "test"}
+	updates := conflictingClient(client, 2, func(rv string) Object {
+		return &TypedSpecStatusObject[string, string]{ObjectMeta: metav1.ObjectMeta{ResourceVersion: rv}, Spec: "old"}
+	})
+
+	ret, err := store.UpdateWithRetry(context.Background(), id, func(obj *TypedSpecStatusObject[string, string]) (*TypedSpecStatusObject[string, string], error) {
+		obj.Spec = "new"
+		return obj, nil
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "new", ret.Spec)
+	// Each update uses the ResourceVersion of a freshly-fetched object
+	assert.Equal(t, []string{"1", "2", "3"}, *updates)
+
+	t.Run("mutate error", func(t *testing.T) {
+		cerr := fmt.Errorf("I AM ERROR")
+		_, err := store.UpdateWithRetry(context.Background(), id, func(obj *TypedSpecStatusObject[string, string]) (*TypedSpecStatusObject[string, string], error) {
+			return nil, cerr
+		})
+		assert.Equal(t, cerr, err)
+	})
+}
+
+func TestStore_UpdateWithRetry(t *testing.T) {
+	client := &mockClient{}
+	store := NewStore(&mockClientGenerator{
+		ClientForFunc: func(kind Kind) (Client, error) {
+			return client, nil
+		},
+	})
+	kind := Kind{NewSimpleSchema("g1", "v1", &TypedSpecObject[any]{}, &TypedList[*TypedSpecObject[string]]{}, WithKind("test")), map[KindEncoding]Codec{KindEncodingJSON: &JSONCodec{}}}
+	store.Register(kind)
+	id := Identifier{Namespace: "ns", Name: "test"}
+	updates := conflictingClient(client, 1, func(rv string) Object {
+		obj := &UntypedObject{ObjectMeta: metav1.ObjectMeta{Namespace: id.Namespace, Name: id.Name, ResourceVersion: rv}}
+		obj.SetGroupVersionKind(kind.GroupVersionKind())
+		return obj
+	})
+
+	ret, err := store.UpdateWithRetry(context.Background(), kind.Kind(), id, func(obj Object) (Object, error) {
+		obj.SetLabels(map[string]string{"foo": "bar"})
+		return obj, nil
+	})
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{"foo": "bar"}, ret.GetLabels())
+	assert.Equal(t, []string{"1", "2"}, *updates)
+}
+
+func TestSimpleStore_UpdateWithRetry(t *testing.T) {
+	store, client := getSimpleStoreTestSetup()
+	id := Identifier{Namespace: "ns", Name: "test"}
+	updates := conflictingClient(client, 1, func(rv string) Object {
+		return &TypedObject[string, MapSubresourceCatalog]{ObjectMeta: metav1.ObjectMeta{ResourceVersion: rv}, Spec: "old"}
+	})
+
+	ret, err := store.UpdateWithRetry(context.Background(), id, func(spec string) (string, error) {
+		assert.Equal(t, "old", spec)
+		return "new", nil
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "new", ret.Spec)
+	// SimpleStore.Update also gets the object, but each update must use the ResourceVersion of the object which was mutated
+	assert.Equal(t, []string{"1", "3"}, *updates)
+}
@@ -168,6 +168,31 @@ func (s *SimpleStore[T]) Update(ctx context.Context, identifier Identifier, obj
 	return s.cast(ret)
 }
 
+// UpdateWithRetry gets the object with the provided identifier, calls mutate with its spec, and updates the object
+// with the spec returned by mutate. The update is made with the ResourceVersion of the fetched object,
+// and if it fails due to a conflict, the process is retried according to DefaultConflictRetryBackoff.
+// If mutate returns an error, UpdateWithRetry returns that error without retrying.
+func (s *SimpleStore[T]) UpdateWithRetry(ctx context.Context, identifier Identifier, mutate func(T) (T, error),
+	opts ...ObjectMetadataOption) (*TypedObject[T, MapSubresourceCatalog], error) {
+	var ret *TypedObject[T, MapSubresourceCatalog]
+	err := RetryOnConflict(ctx, DefaultConflictRetryBackoff, func(ctx context.Context) error {
+		current, err := s.Get(ctx, identifier)
+		if err != nil {
+			return err
+		}
+		updated, err := mutate(current.Spec)
+		if err != nil {
+			return err
+		}
+		ret, err = s.Update(ctx, identifier, updated, append([]ObjectMetadataOption{WithResourceVersion(current.GetResourceVersion())}, opts...)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
 // UpdateSubresource updates a named subresource. Type compatibility is not checked for subresources.
 // If the WithResourceVersion option is used, the update will fail if the object's ResourceVersion in the store
 // doesn't match the one provided in WithResourceVersion.
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"iter"
 	"net/http"
 	"time"
 )
@@ -147,6 +148,32 @@ func (s *Store) Update(ctx context.Context, obj Object) (Object, error) {
 	})
 }
 
+// UpdateWithRetry gets the object of the provided kind and identifier, calls mutate with it, and updates the object
+// with the one returned by mutate. If the update fails due to a ResourceVersion conflict, the process is retried
+// (with a freshly-fetched object) according to DefaultConflictRetryBackoff.
+// If mutate returns an error, UpdateWithRetry returns that error without retrying.
+func (s *Store) UpdateWithRetry(
+	ctx context.Context, kind string, identifier Identifier, mutate func(Object) (Object, error),
+) (Object, error) {
+	var ret Object
+	err := RetryOnConflict(ctx, DefaultConflictRetryBackoff, func(ctx context.Context) error {
+		current, err := s.Get(ctx, kind, identifier)
+		if err != nil {
+			return err
+		}
+		updated, err := mutate(current)
+		if err != nil {
+			return err
+		}
+		ret, err = s.Update(ctx, updated)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
 // UpdateSubresource updates a subresource of an object.
 // The provided obj parameter should be the subresource object, not the entire object.
 // No checks are made that the provided object matches the subresource's definition.
@@ -227,6 +254,19 @@ func (s *Store) Upsert(ctx context.Context, obj Object) (Object, error) {
 	}, obj, CreateOptions{})
 }
 
+// Patch performs a JSON Patch on the resource with the given Identifier and kind.
+// It returns the patched Object from the storage system.
+func (s *Store) Patch(
+	ctx context.Context, kind string, identifier Identifier, patch PatchRequest, options PatchOptions,
+) (Object, error) {
+	client, err := s.getClient(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Patch(ctx, identifier, patch, options)
+}
+
 // Delete deletes a resource with the given Identifier and kind.
 func (s *Store) Delete(ctx context.Context, kind string, identifier Identifier) error {
 	client, err := s.getClient(kind)
@@ -286,6 +326,24 @@ func (s *Store) List(ctx context.Context, kind string, options StoreListOptions)
 	return resp, nil
 }
 
+// ListIter returns an iterator over all resources of the provided kind, using the Namespace and Filters provided in options.
+// Unlike List, pages of options.PerPage resources are requested as the iteration progresses, so only one page
+// is held in memory at a time. If options.PerPage is <= 0, DefaultListIteratorPageSize is used.
+// If an error is encountered, it is yielded with a nil Object, and iteration stops.
+func (s *Store) ListIter(ctx context.Context, kind string, options StoreListOptions) iter.Seq2[Object, error] {
+	client, err := s.getClient(kind)
+	if err != nil {
+		return func(yield func(Object, error) bool) {
+			yield(nil, err)
+		}
+	}
+	return ListIterator(ctx, client, options.Namespace, ListOptions{
+		Limit:          options.PerPage,
+		LabelFilters:   options.Filters,
+		FieldSelectors: options.FieldSelectors,
+	})
+}
+
 // ListPage lists a single page of resources, with no auto-paging logic like List.
 // This is semantically identical to calling Client(kind).List(ctx, namespace, options)
 func (s *Store) ListPage(ctx context.Context, kind string, namespace string, options ListOptions) (ListObject, error) {
@@ -178,6 +178,47 @@ func TestStore_List(t *testing.T) {
 	})
 }
 
+func TestStore_ListIter(t *testing.T) {
+	client := &mockClient{}
+	generator := &mockClientGenerator{}
+	store := NewStore(generator)
+	kind := Kind{NewSimpleSchema("g1", "v1", &TypedSpecObject[any]{}, &TypedList[*TypedSpecObject[string]]{}, WithKind("test")), map[KindEncoding]Codec{KindEncodingJSON: &JSONCodec{}}}
+	store.Register(kind)
+	ctx := context.TODO()
+
+	t.Run("unregistered Schema", func(t *testing.T) {
+		for obj, err := range store.ListIter(ctx, kind.Kind()+"no", StoreListOptions{}) {
+			assert.Nil(t, obj)
+			assert.Equal(t, fmt.Errorf("resource kind '%sno' is not registered in store", kind.Kind()), err)
+		}
+	})
+
+	t.Run("list, two pages", func(t *testing.T) {
+		ns := "foo"
+		generator.ClientForFunc = func(kind Kind) (Client, error) {
+			return client, nil
+		}
+		client.ListFunc = func(c context.Context, namespace string, options ListOptions) (ListObject, error) {
+			assert.Equal(t, ns, namespace)
+			assert.Equal(t, 1, options.Limit)
+			assert.Equal(t, []string{"a"}, options.FieldSelectors)
+			if options.Continue == "continue" {
+				return &UntypedList{Items: []Object{&UntypedObject{ObjectMeta: metav1.ObjectMeta{Name: "bar"}}}}, nil
+			}
+			return &UntypedList{
+				ListMeta: metav1.ListMeta{Continue: "continue"},
+				Items:    []Object{&UntypedObject{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}},
+			}, nil
+		}
+		names := make([]string, 0)
+		for obj, err := range store.ListIter(ctx, kind.Kind(), StoreListOptions{Namespace: ns, PerPage: 1, FieldSelectors: []string{"a"}}) {
+			require.Nil(t, err)
+			names = append(names, obj.GetName())
+		}
+		assert.Equal(t, []string{"foo", "bar"}, names)
+	})
+}
+
 func TestStore_Get(t *testing.T) {
 	client := &mockClient{}
 	generator := &mockClientGenerator{}
@@ -732,6 +773,50 @@ func TestStore_Upsert(t *testing.T) {
 	})
 }
 
+func TestStore_Patch(t *testing.T) {
+	client := &mockClient{}
+	generator := &mockClientGenerator{}
+	store := NewStore(generator)
+	kind := Kind{NewSimpleSchema("g1", "v1", &TypedSpecObject[any]{}, &TypedList[*TypedSpecObject[string]]{}, WithKind("kind")), map[KindEncoding]Codec{KindEncodingJSON: &JSONCodec{}}}
+	store.Register(kind)
+	ctx := context.TODO()
+	id := Identifier{Namespace: "foo", Name: "bar"}
+	patch := PatchRequest{Operations: []PatchOperation{{Path: "/spec", Operation: PatchOpReplace, Value: "foo"}}}
+
+	t.Run("unregistered Schema", func(t *testing.T) {
+		_, err := store.Patch(ctx, kind.Kind()+"no", id, patch, PatchOptions{})
+		assert.Equal(t, fmt.Errorf("resource kind '%sno' is not registered in store", kind.Kind()), err)
+	})
+
+	t.Run("client error", func(t *testing.T) {
+		cerr := fmt.Errorf("JE SUIS ERROR")
+		client.PatchFunc = func(c context.Context, identifier Identifier, p PatchRequest, options PatchOptions) (Object, error) {
+			return nil, cerr
+		}
+		generator.ClientForFunc = func(kind Kind) (Client, error) {
+			return client, nil
+		}
+		_, err := store.Patch(ctx, kind.Kind(), id, patch, PatchOptions{})
+		assert.Equal(t, cerr, err)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		ret := &TypedSpecObject[any]{Spec: "foo"}
+		client.PatchFunc = func(c context.Context, identifier Identifier, p PatchRequest, options PatchOptions) (Object, error) {
+			assert.Equal(t, id, identifier)
+			assert.Equal(t, patch, p)
+			assert.True(t, options.DryRun)
+			return ret, nil
+		}
+		generator.ClientForFunc = func(kind Kind) (Client, error) {
+			return client, nil
+		}
+		obj, err := store.Patch(ctx, kind.Kind(), id, patch, PatchOptions{DryRun: true})
+		assert.Nil(t, err)
+		assert.Equal(t, ret, obj)
+	})
+}
+
 func TestStore_Delete(t *testing.T) {
 	client := &mockClient{}
 	generator := &mockClientGenerator{}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"iter"
 	"net/http"
 	"reflect"
 	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var ErrMissingResourceVersion = errors.New("object is missing a ResourceVersion")
@@ -108,6 +111,31 @@ func (t *TypedStore[T]) Update(ctx context.Context, identifier Identifier, obj T
 	return t.cast(ret)
 }
 
+// UpdateWithRetry gets the object with the provided identifier, calls mutate with it, and updates the object
+// with the one returned by mutate. If the update fails due to a ResourceVersion conflict, the process is retried
+// (with a freshly-fetched object) according to DefaultConflictRetryBackoff.
+// If mutate returns an error, UpdateWithRetry returns that error without retrying.
+func (t *TypedStore[T]) UpdateWithRetry(ctx context.Context, identifier Identifier, mutate func(T) (T, error)) (T, error) {
+	var ret T
+	err := RetryOnConflict(ctx, DefaultConflictRetryBackoff, func(ctx context.Context) error {
+		current, err := t.Get(ctx, identifier)
+		if err != nil {
+			return err
+		}
+		updated, err := mutate(current)
+		if err != nil {
+			return err
+		}
+		ret, err = t.Update(ctx, identifier, updated)
+		return err
+	})
+	if err != nil {
+		var n T
+		return n, err
+	}
+	return ret, nil
+}
+
 // Upsert updates an existing resource or creates a new one if none exists, and returns the new version.
 // Keep in mind that an Upsert will completely overwrite the object,
 // so nil or missing values will be removed, not ignored.
@@ -208,6 +236,24 @@ func (t *TypedStore[T]) List(ctx context.Context, options StoreListOptions) (*Ty
 	return resp, nil
 }
 
+// ListIter returns an iterator over all resources, using the Namespace and Filters provided in options.
+// Unlike List, pages of options.PerPage resources are requested as the iteration progresses, so only one page
+// is held in memory at a time. If options.PerPage is <= 0, DefaultListIteratorPageSize is used.
+// If an error is encountered, it is yielded with the zero value of T, and iteration stops.
+func (t *TypedStore[T]) ListIter(ctx context.Context, options StoreListOptions) iter.Seq2[T, error] {
+	return listIterator(ctx, ListOptions{
+		Limit:          options.PerPage,
+		LabelFilters:   options.Filters,
+		FieldSelectors: options.FieldSelectors,
+	}, func(ctx context.Context, opts ListOptions) ([]T, metav1.ListInterface, error) {
+		resp, err := t.ListPage(ctx, options.Namespace, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return resp.Items, resp, nil
+	})
+}
+
 // ListPage lists a single page of resources, with no auto-paging logic like List.
 // This is semantically identical to calling Client().ListInto(ctx, namespace, options, &TypedList[T])
 func (t *TypedStore[T]) ListPage(ctx context.Context, namespace string, options ListOptions) (*TypedList[T], error) {
@@ -507,6 +507,44 @@ func TestTypedStore_List(t *testing.T) {
 	})
 }
 
+func TestTypedStore_ListIter(t *testing.T) {
+	store, client := getTypedStoreTestSetup()
+	ctx := context.TODO()
+	ns := "ns"
+
+	t.Run("error", func(t *testing.T) {
+		cerr := fmt.Errorf("I AM ERROR")
+		client.ListIntoFunc = func(ctx context.Context, namespace string, options ListOptions, into ListObject) error {
+			return cerr
+		}
+		for obj, err := range store.ListIter(ctx, StoreListOptions{Namespace: ns}) {
+			assert.Nil(t, obj)
+			assert.Equal(t, cerr, err)
+		}
+	})
+
+	t.Run("success, with two pages", func(t *testing.T) {
+		client.ListIntoFunc = func(c context.Context, namespace string, options ListOptions, into ListObject) error {
+			assert.Equal(t, ns, namespace)
+			assert.Equal(t, 1, options.Limit)
+			assert.Equal(t, []string{"a"}, options.LabelFilters)
+			if options.Continue == "" {
+				into.SetItems([]Object{&TypedSpecStatusObject[string, string]{Spec: "a"}})
+				into.SetContinue("continue")
+			} else {
+				into.SetItems([]Object{&TypedSpecStatusObject[string, string]{Spec: "b"}})
+			}
+			return nil
+		}
+		specs := make([]string, 0)
+		for obj, err := range store.ListIter(ctx, StoreListOptions{Namespace: ns, PerPage: 1, Filters: []string{"a"}}) {
+			assert.Nil(t, err)
+			specs = append(specs, obj.Spec)
+		}
+		assert.Equal(t, []string{"a", "b"}, specs)
+	})
+}
+
 func getTypedStoreTestSetup() (*TypedStore[*TypedSpecStatusObject[string, string]], *mockClient) {
 	client := &mockClient{}
 	generator := &mockClientGenerator{
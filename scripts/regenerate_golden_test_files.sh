@@ -13,12 +13,14 @@ go run ./cmd/grafana-app-sdk/*.go generate -s="${rootdir}/codegen/cuekind/testin
   --defpath="${testdir}/crd" \
   -t="${testdir}/typescript/versioned" \
   --grouping=group \
+  --genclients \
   --manifest="customManifest"
 go run ./cmd/grafana-app-sdk/*.go generate -s="${rootdir}/codegen/cuekind/testing" \
   -g="${testdir}/go/groupbygroup" \
   --defpath="${testdir}/crd" \
   -t="${testdir}/typescript/versioned" \
   --grouping=group \
+  --genclients \
   --manifest="testManifest"
 go run ./cmd/grafana-app-sdk/*.go generate -s="${rootdir}/codegen/cuekind/testing" \
   -g="${testdir}/go/groupbygroup" \
@@ -44,6 +46,7 @@ go run ./cmd/grafana-app-sdk/*.go generate -s="${rootdir}/codegen/cuekind/testin
   --defencoding="none" \
   -t="${testdir}/typescript/versioned" \
   --grouping=kind \
+  --genclients \
   --manifest="customManifest"
 
 # Rename files to append .txt
@@ -87,6 +87,7 @@ func NewOperator(cfg OperatorConfig) (*Operator, error) {
 			ValidatingControllers:       cfg.Webhooks.Validators,
 			MutatingControllers:         cfg.Webhooks.Mutators,
 			KindConverters:              cfg.Webhooks.Converters,
+			MetricsConfig:               metrics.DefaultConfig(cfg.Metrics.Namespace),
 		})
 		if err != nil {
 			return nil, err
@@ -118,6 +119,12 @@ func NewOperator(cfg OperatorConfig) (*Operator, error) {
 		if err != nil {
 			return nil, err
 		}
+		if ws != nil {
+			err = me.RegisterCollectors(ws.PrometheusCollectors()...)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 	if cfg.Tracing.Enabled {
 		err := SetTraceProvider(cfg.Tracing.OpenTelemetryConfig)